@@ -0,0 +1,215 @@
+package main
+
+import (
+	"auth_service/internal/config"
+	"auth_service/internal/grpc"
+	"auth_service/internal/grpc/auth"
+	"auth_service/internal/grpc/authn"
+	"auth_service/internal/storage/postgres"
+	"auth_service/lib/slogpretty"
+	"auth_service/lib/utils"
+	"auth_service/migrations"
+	"context"
+	"flag"
+	"fmt"
+	"lib/jwt"
+	"lib/migrator"
+	"lib/tokens"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+)
+
+const (
+	envLocal = "local"
+	envDev   = "dev"
+	envProd  = "prod"
+)
+
+func main() {
+	cfg := config.MustLoad()
+	log := setupLogger(cfg.Env)
+	log.Info("Config read!")
+
+	// `--config=... migrate up|down|status` manages schema of storage instead of running service
+	if flag.Arg(0) == "migrate" {
+		migrate(cfg, log, flag.Arg(1))
+		return
+	}
+
+	if len(cfg.ServiceCredentials) == 0 {
+		panic("service_credentials must be set, auth service accepts calls of services only")
+	}
+
+	grpcSrv := grpc.CreateGrpcServer(log, authn.AuthFunc(log, cfg.ServiceCredentials))
+
+	storage := mustSetupStorage(cfg, log)
+
+	log.Info("Storage created!")
+
+	keyring := mustLoadKeyring(cfg)
+	tokenManager := tokens.New(storage, nil, keyring, cfg.TokenTTL, cfg.RefreshTokenTTL)
+
+	auth.Register(grpcSrv, log, tokenManager)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+	if err != nil {
+		log.Error("cant listen (tcp)", utils.WrapErr(err))
+	}
+
+	log.Info(fmt.Sprintf("Server started on port %d", cfg.GRPC.Port))
+
+	go func() {
+		if err := grpcSrv.Serve(l); err != nil {
+			log.Error("cant serve server", utils.WrapErr(err))
+		}
+	}()
+
+	// Graceful shutdown
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+
+	<-stop
+
+	grpcSrv.GracefulStop()
+
+	log.Info("Server stopped...")
+}
+
+// mustSetupStorage returns postgres storage, its database must have schema of this binary
+func mustSetupStorage(cfg *config.Config, log *slog.Logger) *postgres.Storage {
+	db, m := mustOpenStorage(cfg)
+
+	ctx := context.Background()
+	if cfg.MigrateOnStart {
+		versions, err := m.Up(ctx)
+		if err != nil {
+			panic("cant apply migrations " + err.Error())
+		}
+		log.Info(fmt.Sprintf("Migrations applied: %d", len(versions)))
+	}
+
+	if err := m.Check(ctx); err != nil {
+		panic("wrong storage schema: " + err.Error())
+	}
+
+	return postgres.New(db)
+}
+
+// mustOpenStorage opens database of storage with migrator of its schema
+func mustOpenStorage(cfg *config.Config) (*sqlx.DB, *migrator.Migrator) {
+	db := postgres.MustOpenPostgresDB(cfg.PostgresStorageURI)
+
+	m, err := migrator.New(db.DB, goose.DialectPostgres, migrations.Postgres)
+	if err != nil {
+		panic("cant setup migrator " + err.Error())
+	}
+
+	return db, m
+}
+
+// migrate runs `migrate up|down|status` subcommand
+func migrate(cfg *config.Config, log *slog.Logger, cmd string) {
+	_, m := mustOpenStorage(cfg)
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		versions, err := m.Up(ctx)
+		if err != nil {
+			log.Error("cant apply migrations", utils.WrapErr(err))
+			os.Exit(1)
+		}
+		log.Info(fmt.Sprintf("Migrations applied: %d, schema version: %d", len(versions), m.Latest()))
+	case "down":
+		version, err := m.Down(ctx)
+		if err != nil {
+			log.Error("cant roll back migration", utils.WrapErr(err))
+			os.Exit(1)
+		}
+		log.Info(fmt.Sprintf("Migration rolled back: %d", version))
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			log.Error("cant get migrations status", utils.WrapErr(err))
+			os.Exit(1)
+		}
+		for _, s := range status {
+			state := string(s.State)
+			if s.State == goose.StateApplied {
+				state = "applied at " + s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Printf("%d %s %s\n", s.Source.Version, path.Base(s.Source.Path), state)
+		}
+	default:
+		log.Error("usage: migrate up|down|status")
+		os.Exit(2)
+	}
+}
+
+// mustLoadKeyring loads signing keys from config, token secret is added as HS256 key
+func mustLoadKeyring(cfg *config.Config) *jwt.Keyring {
+	var keys []jwt.Key
+
+	for _, k := range cfg.SigningKeys {
+		key, err := jwt.LoadKey(k.ID, k.Path, k.NotBefore, k.NotAfter)
+		if err != nil {
+			panic("cant load signing key: " + err.Error())
+		}
+		keys = append(keys, key)
+	}
+
+	if cfg.TokenSecret != "" {
+		keys = append(keys, jwt.NewHMACKey(cfg.TokenSecret))
+	}
+
+	if len(keys) == 0 {
+		panic("token_secret or signing_keys must be set")
+	}
+
+	keyring, err := jwt.NewKeyring(keys...)
+	if err != nil {
+		panic("cant create keyring: " + err.Error())
+	}
+
+	return keyring
+}
+
+func setupLogger(env string) *slog.Logger {
+	var log *slog.Logger
+
+	switch env {
+	case envLocal:
+		log = setupPrettySlog()
+	case envDev:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	case envProd:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		)
+	}
+
+	return log
+}
+
+func setupPrettySlog() *slog.Logger {
+	opts := slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		},
+	}
+
+	handler := opts.NewPrettyHandler(os.Stdout)
+
+	return slog.New(handler)
+}
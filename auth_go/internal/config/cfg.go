@@ -0,0 +1,81 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
+	"os"
+	"time"
+)
+
+type Config struct {
+	Env                string        `yaml:"env" env-default:"local"`
+	PostgresStorageURI string        `yaml:"postgres_storage_uri" env-required:"true"`
+	MigrateOnStart     bool          `yaml:"migrate_on_start"` // apply migrations before schema check
+	GRPC               GRPCConfig    `yaml:"grpc"`
+	TokenSecret        string        `yaml:"token_secret"` // HS256 secret, used only if no signing_keys are active, such tokens can be verified only by this service
+	TokenTTL           time.Duration `yaml:"token_ttl" env-default:"24h"`
+	RefreshTokenTTL    time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	SigningKeys        []SigningKey  `yaml:"signing_keys"`
+	// ServiceCredentials are credentials of services allowed to call auth service by their names,
+	// callers pass `authorization: Service <credential>` metadata
+	ServiceCredentials map[string]string `yaml:"service_credentials"`
+}
+
+// SigningKey is PEM encoded RSA or Ed25519 private key used to sign tokens.
+// Key signs tokens between not_before and not_after, but verifies them and is
+// published in JWKS while it is in config. To rotate keys add new key with not_before
+// in future and set not_after of the old one, remove old key after token_ttl passed.
+type SigningKey struct {
+	ID        string    `yaml:"kid"`
+	Path      string    `yaml:"path"`
+	NotBefore time.Time `yaml:"not_before"`
+	NotAfter  time.Time `yaml:"not_after"`
+}
+
+type GRPCConfig struct {
+	Port    int           `yaml:"port"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func MustLoad() *Config {
+	configPath := fetchConfigPath()
+	if configPath == "" {
+		panic("config path is empty")
+	}
+
+	return MustLoadPath(configPath)
+}
+
+func MustLoadPath(configPath string) *Config {
+	// check if file exists
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		panic("config file does not exist: " + configPath)
+	}
+
+	var cfg Config
+
+	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
+		panic("cannot read config: " + err.Error())
+	}
+
+	return &cfg
+}
+
+// fetchConfigPath fetches config path from command line flag or environment variable.
+// Priority: flag > env > default.
+// Default value is empty string.
+func fetchConfigPath() string {
+	var res string
+
+	flag.StringVar(&res, "config", "", "path to config file")
+	flag.Parse()
+
+	if res == "" {
+		fmt.Printf("Getting config, path: %s \n", os.Getenv("CFG_PATH"))
+
+		res = os.Getenv("CFG_PATH")
+	}
+
+	return res
+}
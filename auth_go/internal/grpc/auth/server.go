@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"auth_service/lib/utils"
+	pb "auth_service/pb/authservice"
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lib/jwt"
+	"lib/tokens"
+	"log/slog"
+	"time"
+)
+
+type serverAPI struct {
+	pb.UnimplementedAuthServiceServer
+	l      *slog.Logger
+	tokens *tokens.Manager
+}
+
+func Register(grpcServer *grpc.Server, logger *slog.Logger, tokens *tokens.Manager) {
+	pb.RegisterAuthServiceServer(grpcServer, &serverAPI{
+		l:      logger,
+		tokens: tokens,
+	})
+}
+
+func (s *serverAPI) Authenticate(
+	ctx context.Context,
+	in *pb.AuthRequest,
+) (*pb.AuthResponse, error) {
+	const op = "internal/grpc/auth/server/Authenticate()"
+	log := s.l.With(slog.String("op", op))
+
+	// tokens are always issued for app, so they can't be accepted by any app
+	if in.GetUserId() == "" || in.GetAppId() == 0 {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	pair, err := s.tokens.Issue(ctx, tokens.Subject{
+		UserID: in.UserId,
+		Email:  in.Email,
+		App: tokens.App{
+			ID:       in.AppId,
+			Name:     in.Audience,
+			TokenTTL: time.Duration(in.TokenTtl) * time.Second,
+		},
+		SessionID: in.SessionId,
+	})
+	if err != nil {
+		log.Error("cant issue tokens", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.AuthResponse{
+		Token:        pair.Token,
+		RefreshToken: pair.RefreshToken,
+		Claims:       ClaimsToPb(pair.Claims),
+	}, nil
+}
+
+func (s *serverAPI) Validate(
+	ctx context.Context,
+	in *pb.ValidateRequest,
+) (*pb.ValidateResponse, error) {
+	const op = "internal/grpc/auth/server/Validate()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token)
+	if err != nil {
+		st := pb.TokenStatus_TOKEN_STATUS_UNSPECIFIED
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			st = pb.TokenStatus_TOKEN_STATUS_EXPIRED
+		case errors.Is(err, tokens.ErrTokenRevoked):
+			st = pb.TokenStatus_TOKEN_STATUS_REVOKED
+		case errors.Is(err, jwt.ErrInvalidSignature):
+			st = pb.TokenStatus_TOKEN_STATUS_INVALID_SIGNATURE
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			st = pb.TokenStatus_TOKEN_STATUS_MALFORMED
+		default:
+			log.Error("cant validate token", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		log.Debug("invalid token", utils.WrapErr(err))
+
+		return &pb.ValidateResponse{Status: st}, nil
+	}
+
+	return &pb.ValidateResponse{
+		Status: pb.TokenStatus_TOKEN_STATUS_VALID,
+		Claims: ClaimsToPb(c),
+	}, nil
+}
+
+func (s *serverAPI) Refresh(
+	ctx context.Context,
+	in *pb.RefreshRequest,
+) (*pb.RefreshResponse, error) {
+	const op = "internal/grpc/auth/server/Refresh()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetRefreshToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	pair, err := s.tokens.Refresh(ctx, in.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrRefreshTokenExpired):
+			log.Debug("refresh token expired")
+			return &pb.RefreshResponse{Status: pb.RefreshStatus_REFRESH_STATUS_EXPIRED}, nil
+		case errors.Is(err, tokens.ErrRefreshTokenInvalid):
+			log.Warn("invalid refresh token")
+			return &pb.RefreshResponse{Status: pb.RefreshStatus_REFRESH_STATUS_INVALID}, nil
+		default:
+			log.Error("cant refresh tokens", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	return &pb.RefreshResponse{
+		Status:       pb.RefreshStatus_REFRESH_STATUS_REFRESHED,
+		Token:        pair.Token,
+		RefreshToken: pair.RefreshToken,
+		Claims:       ClaimsToPb(pair.Claims),
+	}, nil
+}
+
+func (s *serverAPI) GetFamily(
+	ctx context.Context,
+	in *pb.GetFamilyRequest,
+) (*pb.GetFamilyResponse, error) {
+	const op = "internal/grpc/auth/server/GetFamily()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetRefreshToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	family, err := s.tokens.Family(ctx, in.RefreshToken)
+	if err != nil {
+		if errors.Is(err, tokens.ErrRefreshTokenInvalid) {
+			log.Warn("invalid refresh token")
+			return &pb.GetFamilyResponse{}, nil
+		}
+		log.Error("error in Family", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.GetFamilyResponse{FamilyId: family}, nil
+}
+
+func (s *serverAPI) Revoke(
+	ctx context.Context,
+	in *pb.RevokeRequest,
+) (*pb.RevokeResponse, error) {
+	const op = "internal/grpc/auth/server/Revoke()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetUserId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c := jwt.Claims{
+		UserID:    in.UserId,
+		JTI:       in.Jti,
+		ExpiresAt: time.Unix(in.ExpiresAt, 0),
+	}
+
+	if err := s.tokens.Revoke(ctx, c, in.RefreshToken); err != nil {
+		log.Error("error in Revoke", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.RevokeResponse{}, nil
+}
+
+func (s *serverAPI) RevokeAll(
+	ctx context.Context,
+	in *pb.RevokeAllRequest,
+) (*pb.RevokeAllResponse, error) {
+	const op = "internal/grpc/auth/server/RevokeAll()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetUserId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if err := s.tokens.RevokeAll(ctx, in.UserId); err != nil {
+		log.Error("error in RevokeAll", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("all tokens revoked", slog.String("user_id", in.UserId))
+
+	return &pb.RevokeAllResponse{}, nil
+}
+
+func (s *serverAPI) GetJWKS(
+	ctx context.Context,
+	in *pb.GetJWKSRequest,
+) (*pb.GetJWKSResponse, error) {
+	const op = "internal/grpc/auth/server/GetJWKS()"
+	log := s.l.With(slog.String("op", op))
+
+	jwks, err := s.tokens.JWKS(ctx)
+	if err != nil {
+		log.Error("error in JWKS", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	var keys []*pb.JWK
+
+	for _, k := range jwks {
+		keys = append(keys, &pb.JWK{
+			Kty: k.Kty,
+			Kid: k.Kid,
+			Alg: k.Alg,
+			Use: k.Use,
+			N:   k.N,
+			E:   k.E,
+			Crv: k.Crv,
+			X:   k.X,
+		})
+	}
+
+	return &pb.GetJWKSResponse{Keys: keys}, nil
+}
+
+func ClaimsToPb(c jwt.Claims) *pb.Claims {
+	return &pb.Claims{
+		UserId:    c.UserID,
+		Email:     c.Email,
+		Jti:       c.JTI,
+		AppId:     c.AppID,
+		Audience:  c.Audience,
+		SessionId: c.SessionID,
+		IssuedAt:  c.IssuedAt.Unix(),
+		ExpiresAt: c.ExpiresAt.Unix(),
+	}
+}
@@ -0,0 +1,65 @@
+package authn
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type ctxKey struct{}
+
+// AuthFunc returns auth.AuthFunc for auth interceptors, only services may call auth service.
+// Caller passes `authorization: Service <credential>` metadata with credential of one of services,
+// which are credentials by names of services. Name of the service is put into context.
+func AuthFunc(log *slog.Logger, services map[string]string) auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		var v string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if vals := md.Get("authorization"); len(vals) > 0 {
+				v = vals[0]
+			}
+		}
+
+		scheme, credential, _ := strings.Cut(v, " ")
+		if credential == "" {
+			return nil, status.Error(codes.Unauthenticated, "you need to pass service credential")
+		}
+
+		if !strings.EqualFold(scheme, "service") {
+			return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+		}
+
+		name, ok := serviceName(services, credential)
+		if !ok {
+			log.Warn("invalid service credential")
+			return nil, status.Error(codes.Unauthenticated, "invalid service credential")
+		}
+
+		return context.WithValue(ctx, ctxKey{}, name), nil
+	}
+}
+
+// ServiceFromContext returns name of service put by auth interceptor
+func ServiceFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(ctxKey{}).(string)
+	return name, ok
+}
+
+// serviceName returns name of service with credential, all credentials
+// are compared in constant time, so timing doesn't tell which one is close
+func serviceName(services map[string]string, credential string) (string, bool) {
+	var res string
+	for name, c := range services {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(credential)) == 1 {
+			res = name
+		}
+	}
+
+	return res, res != ""
+}
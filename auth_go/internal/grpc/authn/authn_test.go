@@ -0,0 +1,77 @@
+package authn
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthFunc(t *testing.T) {
+	authFunc := AuthFunc(slog.New(slog.NewTextHandler(io.Discard, nil)), map[string]string{
+		"users": "users-credential",
+		"other": "other-credential",
+	})
+
+	tests := []struct {
+		name          string
+		authorization []string
+		wantService   string
+		wantCode      codes.Code
+	}{
+		{
+			name:     "missing header",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:          "empty credential",
+			authorization: []string{"Service "},
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "user token",
+			authorization: []string{"Bearer users-credential"},
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "unknown credential",
+			authorization: []string{"Service users-credential-2"},
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "known credential",
+			authorization: []string{"Service users-credential"},
+			wantService:   "users",
+		},
+		{
+			name:          "scheme is case insensitive",
+			authorization: []string{"service other-credential"},
+			wantService:   "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.MD{"authorization": tt.authorization})
+			}
+
+			ctx, err := authFunc(ctx)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("AuthFunc() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			if got, _ := ServiceFromContext(ctx); got != tt.wantService {
+				t.Errorf("ServiceFromContext() = %v, want %v", got, tt.wantService)
+			}
+		})
+	}
+}
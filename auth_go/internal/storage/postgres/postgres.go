@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+type Storage struct {
+	db *sqlx.DB
+}
+
+func New(db *sqlx.DB) *Storage {
+	return &Storage{
+		db: db,
+	}
+}
+
+// MustOpenPostgresDB is helper func to get db, will throw panic when error
+func MustOpenPostgresDB(dbURI string) *sqlx.DB {
+	db, err := sqlx.Open("postgres", dbURI)
+	if err != nil {
+		panic("cant open db" + err.Error())
+	}
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		panic("cant ping db " + err.Error())
+	}
+
+	return db
+}
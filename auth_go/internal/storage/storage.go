@@ -0,0 +1,12 @@
+package storage
+
+import (
+	"errors"
+	"lib/tokens"
+)
+
+var (
+	ErrEmptyFields = errors.New("empty fields")
+	ErrNotFound    = tokens.ErrNotFound
+	ErrAlreadyUsed = tokens.ErrAlreadyUsed
+)
@@ -0,0 +1,10 @@
+// Package migrations embeds migrations of postgres storage into binary,
+// they are applied by migrate subcommand of the service
+package migrations
+
+import "embed"
+
+// Postgres are migrations of postgres storage
+//
+//go:embed *.sql
+var Postgres embed.FS
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"files/internal/clients/auth"
+	"files/internal/clients/permissions"
 	"files/internal/config"
 	"files/internal/grpc"
+	"files/internal/grpc/authn"
 	"files/internal/grpc/files"
 	"files/internal/storage/firebase_file_storage"
 	"files/lib/slogpretty"
@@ -44,9 +47,20 @@ func main() {
 		panic(err)
 	}
 
-	grpcSrv := grpc.CreateGrpcServer(log)
+	permissionsClient, err := permissions.New(cfg.Services.Users, cfg.Services.Credential, cfg.Services.Timeout)
+	if err != nil {
+		panic(err)
+	}
+
+	authClient, err := auth.New(cfg.Services.Users, cfg.Auth.AppID, cfg.Services.Timeout, cfg.Auth.JWKSURL)
+	if err != nil {
+		panic(err)
+	}
+
+	grpcSrv := grpc.CreateGrpcServer(log, authn.AuthFunc(log, authClient, cfg.Auth.ServiceCredentials))
 	storage := firebase_file_storage.New(bucket)
-	files.Register(grpcSrv, storage, log)
+
+	files.Register(grpcSrv, storage, log, permissionsClient)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
 	if err != nil {
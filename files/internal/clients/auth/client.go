@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	pb "files/pb/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"lib/jwt"
+	"time"
+)
+
+// Client verifies tokens of users in user service
+type Client struct {
+	api     pb.AuthClient
+	appID   int32
+	timeout time.Duration
+	keys    *jwt.KeySet
+}
+
+// New returns client accepting tokens of appID, tokens of other apps are rejected.
+// If jwksURL isn't empty, auth tokens signed with keys published there are verified offline,
+// so they are accepted until they expire even if revoked.
+func New(addr string, appID int32, timeout time.Duration, jwksURL string) (*Client, error) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys *jwt.KeySet
+	if jwksURL != "" {
+		keys = jwt.NewKeySet(jwt.FetchURL(jwksURL))
+	}
+
+	return &Client{
+		api:     pb.NewAuthClient(conn),
+		appID:   appID,
+		timeout: timeout,
+		keys:    keys,
+	}, nil
+}
+
+// GetID returns owner of auth token, personal access token or OAuth access token.
+// scopes are nil for auth tokens, they aren't limited.
+func (c *Client) GetID(ctx context.Context, token string) (userID string, scopes []string, err error) {
+	if c.keys != nil {
+		claims, err := c.keys.Verify(token)
+		switch {
+		case err == nil && claims.AppID == c.appID:
+			return claims.UserID, nil, nil
+		case err == nil:
+			return "", nil, status.Error(codes.Unauthenticated, "token issued for another app")
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return "", nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		// other tokens are verified by user service
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := c.api.GetID(ctx, &pb.GetIDRequest{
+		Token: token,
+		AppId: c.appID,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !res.GetAccessToken() {
+		return res.GetUserId(), nil, nil
+	}
+
+	// access token without scopes can't do anything, it must not look like auth token
+	scopes = res.GetScopes()
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return res.GetUserId(), scopes, nil
+}
@@ -0,0 +1,58 @@
+package permissions
+
+import (
+	"context"
+	pb "files/pb/permissions"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"time"
+)
+
+// Client checks user permissions in user service
+type Client struct {
+	api     pb.PermissionsClient
+	timeout time.Duration
+}
+
+// New returns client authenticated by credential of files service in user service
+func New(addr, credential string, timeout time.Duration) (*Client, error) {
+	conn, err := grpc.Dial(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(serviceCredential(credential)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		api:     pb.NewPermissionsClient(conn),
+		timeout: timeout,
+	}, nil
+}
+
+func (c *Client) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := c.api.CheckPermission(ctx, &pb.CheckPermissionRequest{
+		UserId:     userID,
+		Permission: permission,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return res.GetAllowed(), nil
+}
+
+// serviceCredential puts `authorization: Service <credential>` metadata into every call
+type serviceCredential string
+
+func (c serviceCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Service " + string(c)}, nil
+}
+
+// RequireTransportSecurity is false, services talk over insecure connections in local network
+func (c serviceCredential) RequireTransportSecurity() bool {
+	return false
+}
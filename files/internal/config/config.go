@@ -15,10 +15,33 @@ type Config struct {
 	GRPC           GRPCConfig `yaml:"grpc" env-required:"true"`
 	StorageBucket  string     `yaml:"storage_bucket" env-required:"true"`
 	DatabaseURL    string     `yaml:"database_url" env-required:"true"`
+	Services       Services   `yaml:"services" env-required:"true"`
+	Auth           Auth       `yaml:"auth"`
 	StorageOptions option.ClientOption
 	StorageCfg     *firebase.Config
 }
 
+// Services are addresses of other services
+type Services struct {
+	Users string `yaml:"users" env-required:"true"`
+	// Credential is service credential of this service in user service config
+	Credential string        `yaml:"credential"`
+	Timeout    time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+// Auth tells which callers are accepted, every call needs bearer token of user or credential of service
+type Auth struct {
+	// AppID is app of user tokens forwarded by gateway, tokens of other apps are rejected
+	AppID int32 `yaml:"app_id" env-default:"1"`
+	// ServiceCredentials are credentials of services by their names,
+	// services are trusted to act for user given in request
+	ServiceCredentials map[string]string `yaml:"service_credentials"`
+	// JWKSURL is URL of public keys of user service, e.g. /.well-known/jwks.json of gateway.
+	// If it's set, auth tokens are verified offline, revoked tokens and tokens of disabled users
+	// are accepted until they expire then. Other tokens are verified by user service anyway.
+	JWKSURL string `yaml:"jwks_url"`
+}
+
 type GRPCConfig struct {
 	Port    int           `yaml:"port" env-required:"true"`
 	Timeout time.Duration `yaml:"timeout" env-required:"true"`
@@ -0,0 +1,112 @@
+package authn
+
+import (
+	"context"
+	"crypto/subtle"
+	"files/lib/utils"
+	"log/slog"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type ctxKey struct{}
+
+// Principal is authenticated caller, either user or service
+type Principal struct {
+	// UserID is owner of bearer token, it's empty for services
+	UserID string
+	// Service is name of service which passed service credential
+	Service string
+	// Scopes limit personal access tokens and OAuth tokens, nil means no limits
+	Scopes []string
+}
+
+// HasScope tells if principal may do what scope allows
+func (p Principal) HasScope(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Tokens verifies bearer tokens of users, it's implemented by user service client
+type Tokens interface {
+	GetID(ctx context.Context, token string) (userID string, scopes []string, err error)
+}
+
+// AuthFunc returns auth.AuthFunc for auth interceptors, it puts Principal of the call into context.
+// Caller passes `authorization: Bearer <token>` metadata with token of user, gateway forwards it,
+// or `authorization: Service <credential>` with credential of one of services, which are
+// credentials by names of services.
+func AuthFunc(log *slog.Logger, tokens Tokens, services map[string]string) auth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		var v string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if vals := md.Get("authorization"); len(vals) > 0 {
+				v = vals[0]
+			}
+		}
+
+		scheme, credential, _ := strings.Cut(v, " ")
+		if credential == "" {
+			return nil, status.Error(codes.Unauthenticated, "you need to pass token")
+		}
+
+		var p Principal
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			userID, scopes, err := tokens.GetID(ctx, credential)
+			if err != nil {
+				// any problem of the token is told to caller, failures of user service aren't
+				switch status.Code(err) {
+				case codes.Unauthenticated, codes.InvalidArgument, codes.NotFound, codes.PermissionDenied:
+					return nil, status.Error(codes.Unauthenticated, "invalid token")
+				default:
+					log.Error("cant verify token", utils.WrapErr(err))
+					return nil, status.Error(codes.Internal, "internal error")
+				}
+			}
+			p = Principal{UserID: userID, Scopes: scopes}
+		case "service":
+			name, ok := serviceName(services, credential)
+			if !ok {
+				return nil, status.Error(codes.Unauthenticated, "invalid service credential")
+			}
+			p = Principal{Service: name}
+		default:
+			return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+		}
+
+		return context.WithValue(ctx, ctxKey{}, p), nil
+	}
+}
+
+// FromContext returns principal put by auth interceptor, false if call isn't authenticated
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// serviceName returns name of service with credential, all credentials
+// are compared in constant time, so timing doesn't tell which one is close
+func serviceName(services map[string]string, credential string) (string, bool) {
+	var res string
+	for name, c := range services {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(credential)) == 1 {
+			res = name
+		}
+	}
+
+	return res, res != ""
+}
@@ -0,0 +1,113 @@
+package authn
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// fakeTokens accepts tokens by map, other tokens are rejected like user service does
+type fakeTokens struct {
+	users  map[string]string
+	scopes map[string][]string
+	err    error
+}
+
+func (f fakeTokens) GetID(ctx context.Context, token string) (string, []string, error) {
+	if f.err != nil {
+		return "", nil, f.err
+	}
+
+	userID, ok := f.users[token]
+	if !ok {
+		return "", nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return userID, f.scopes[token], nil
+}
+
+func withAuthorization(v string) context.Context {
+	if v == "" {
+		return context.Background()
+	}
+
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", v))
+}
+
+func TestAuthFunc(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tokens := fakeTokens{
+		users:  map[string]string{"user-token": "user-id", "pat": "user-id"},
+		scopes: map[string][]string{"pat": {"files:read"}},
+	}
+	services := map[string]string{"gateway": "gateway-credential"}
+
+	tests := []struct {
+		name          string
+		authorization string
+		tokens        Tokens
+		want          Principal
+		wantCode      codes.Code
+	}{
+		{name: "missing header", wantCode: codes.Unauthenticated},
+		{name: "bearer without token", authorization: "Bearer", wantCode: codes.Unauthenticated},
+		{name: "bearer with empty token", authorization: "Bearer ", wantCode: codes.Unauthenticated},
+		{name: "token without scheme", authorization: "user-token", wantCode: codes.Unauthenticated},
+		{name: "unsupported scheme", authorization: "Basic user-token", wantCode: codes.Unauthenticated},
+		{name: "token rejected by user service", authorization: "Bearer unknown", wantCode: codes.Unauthenticated},
+		{
+			name:          "user service is down",
+			authorization: "Bearer user-token",
+			tokens:        fakeTokens{err: status.Error(codes.Unavailable, "connection refused")},
+			wantCode:      codes.Internal,
+		},
+		{name: "unknown service credential", authorization: "Service other-credential", wantCode: codes.Unauthenticated},
+		{name: "service credential of user token", authorization: "Service user-token", wantCode: codes.Unauthenticated},
+		{name: "user token", authorization: "Bearer user-token", want: Principal{UserID: "user-id"}},
+		{name: "scheme is case insensitive", authorization: "bearer user-token", want: Principal{UserID: "user-id"}},
+		{name: "access token", authorization: "Bearer pat", want: Principal{UserID: "user-id", Scopes: []string{"files:read"}}},
+		{name: "service", authorization: "Service gateway-credential", want: Principal{Service: "gateway"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tk := tt.tokens
+			if tk == nil {
+				tk = tokens
+			}
+
+			ctx, err := AuthFunc(log, tk, services)(withAuthorization(tt.authorization))
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("AuthFunc() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			got, ok := FromContext(ctx)
+			if !ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromContext() = %+v, %v, want %+v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipal_HasScope(t *testing.T) {
+	if !(Principal{UserID: "user-id"}).HasScope("files:write") {
+		t.Error("HasScope() of auth token = false, want true")
+	}
+	if (Principal{UserID: "user-id", Scopes: []string{}}).HasScope("files:read") {
+		t.Error("HasScope() of access token without scopes = true")
+	}
+
+	p := Principal{UserID: "user-id", Scopes: []string{"files:read"}}
+	if !p.HasScope("files:read") || p.HasScope("files:write") {
+		t.Errorf("HasScope() of %v is wrong", p.Scopes)
+	}
+}
@@ -2,9 +2,10 @@ package files
 
 import (
 	"context"
-	"errors"
 	"files/internal/domain/models"
-	"files/internal/storage"
+	"files/internal/grpc/authn"
+	"files/internal/grpc/grpcerr"
+	"files/lib/utils"
 	pb "files/pb/files"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -15,26 +16,43 @@ import (
 
 type serverAPI struct {
 	pb.UnimplementedFilesServer
-	storage Storage
-	l       *slog.Logger
+	storage     Storage
+	l           *slog.Logger
+	permissions Permissions
 }
 
 type Storage interface {
 	UploadFile(ctx context.Context, f models.File) (models.File, error)
 	GetFileById(ctx context.Context, id string) (models.File, error)
-	GetFilesByName(ctx context.Context, name string, limit int) ([]models.File, error)
+	// GetFilesByName returns files with name of userID, files of all users if userID is empty
+	GetFilesByName(ctx context.Context, name, userID string, limit int) ([]models.File, error)
 	GetFilesByUser(ctx context.Context, userId string, limit int) ([]models.File, error)
 }
 
-func Register(grpcServer *grpc.Server, storage Storage, logger *slog.Logger) {
+// Permissions checks permissions of users, it's implemented by user service client
+type Permissions interface {
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
+// permissionReadAny allows to read files of other users
+const permissionReadAny = "files:read:any"
+
+// scopeFilesWrite is scope personal access token or OAuth token needs to upload files
+const scopeFilesWrite = "files:write"
+
+// scopeFilesRead is scope personal access token or OAuth token needs to read files
+const scopeFilesRead = "files:read"
+
+func Register(grpcServer *grpc.Server, storage Storage, logger *slog.Logger, permissions Permissions) {
 	pb.RegisterFilesServer(grpcServer, &serverAPI{
-		storage: storage,
-		l:       logger,
+		storage:     storage,
+		l:           logger,
+		permissions: permissions,
 	})
 }
 
 func (s *serverAPI) UploadFile(ctx context.Context, in *pb.UploadFileRequest) (*pb.UploadFileResponse, error) {
-	const op = "internal/grpc/auth/server/Login()"
+	const op = "internal/grpc/files/server/UploadFile()"
 	log := s.l.With(slog.String("op", op))
 
 	if !validateUploadFile(in) {
@@ -42,94 +60,201 @@ func (s *serverAPI) UploadFile(ctx context.Context, in *pb.UploadFileRequest) (*
 		return nil, status.Error(codes.InvalidArgument, "incorrect request")
 	}
 
+	userID, err := callerID(ctx, in.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if p, _ := authn.FromContext(ctx); !p.HasScope(scopeFilesWrite) {
+		log.Error("token has no scope", slog.String("user_id", userID))
+		return nil, status.Error(codes.PermissionDenied, "token has no "+scopeFilesWrite+" scope")
+	}
+
 	f := PbToFile(in.File)
-	f.UserID = in.UserId
+	f.UserID = userID
 
 	file, err := s.storage.UploadFile(ctx, f)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "incorrect request")
+		return nil, grpcerr.From(log, err)
 	}
 
 	return &pb.UploadFileResponse{File: FileToPb(file)}, nil
 }
 
-// GetFileById will return only for this user
+// GetFileById will return file of other user only if user has files:read:any permission
 func (s *serverAPI) GetFileById(ctx context.Context, in *pb.GetFileByIdRequest) (*pb.GetFileByIdResponse, error) {
-	const op = "internal/grpc/auth/server/GetFileById()"
+	const op = "internal/grpc/files/server/GetFileById()"
 	log := s.l.With(slog.String("op", op))
 	if !validateGetFileById(in) {
 		log.Error("haven't passed validation")
 		return nil, status.Error(codes.InvalidArgument, "incorrect request")
 	}
 
-	file, err := s.storage.GetFileById(ctx, in.UserId)
+	userID, err := callerID(ctx, in.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if p, _ := authn.FromContext(ctx); !p.HasScope(scopeFilesRead) {
+		log.Error("token has no scope", slog.String("user_id", userID))
+		return nil, status.Error(codes.PermissionDenied, "token has no "+scopeFilesRead+" scope")
+	}
+
+	file, err := s.storage.GetFileById(ctx, in.Id)
 	if err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			return nil, status.Error(codes.NotFound, "not found")
-		}
-		return nil, status.Error(codes.Internal, "incorrect request")
+		return nil, grpcerr.From(log, err)
 	}
 
-	if file.UserID != in.UserId {
-		// TODO: replace with permission checking
-		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	if err := s.checkCanRead(ctx, log, userID, file.UserID); err != nil {
+		return nil, err
 	}
 
 	return &pb.GetFileByIdResponse{File: FileToPb(file)}, nil
 }
 
-// GetFilesByName will return files by name for ALL users
+// GetFilesByName returns files with name of the caller,
+// files of all users are returned only if user has files:read:any permission
 func (s *serverAPI) GetFilesByName(ctx context.Context, in *pb.GetFilesByNameRequest) (*pb.GetFilesByNameResponse, error) {
 	const limit = 10
 
-	const op = "internal/grpc/auth/server/GetFilesByName()"
+	const op = "internal/grpc/files/server/GetFilesByName()"
 	log := s.l.With(slog.String("op", op))
 	if !validateGetFilesByName(in) {
 		log.Error("haven't passed validation")
 		return nil, status.Error(codes.InvalidArgument, "incorrect request")
 	}
 
-	files, err := s.storage.GetFilesByName(ctx, in.Name, limit)
+	userID, err := callerID(ctx, in.UserId)
 	if err != nil {
 		return nil, err
 	}
 
+	if p, _ := authn.FromContext(ctx); !p.HasScope(scopeFilesRead) {
+		log.Error("token has no scope", slog.String("user_id", userID))
+		return nil, status.Error(codes.PermissionDenied, "token has no "+scopeFilesRead+" scope")
+	}
+
+	readAny, err := s.canReadAny(ctx, log, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := userID
+	if readAny {
+		owner = ""
+	}
+
+	files, err := s.storage.GetFilesByName(ctx, in.Name, owner, limit)
+	if err != nil {
+		return nil, grpcerr.From(log, err)
+	}
+
 	var filesPb []*pb.File
 
 	for _, f := range files {
 		filesPb = append(filesPb, FileToPb(f))
 	}
 
-	return &pb.GetFilesByNameResponse{Files: filesPb}, err
+	return &pb.GetFilesByNameResponse{Files: filesPb}, nil
 }
 
+// GetFilesByUser returns files of user_id or of the caller if it's empty,
+// files of other users are returned only if user has files:read:any permission
 func (s *serverAPI) GetFilesByUser(ctx context.Context, in *pb.GetFilesByUserRequest) (*pb.GetFilesByUserResponse, error) {
 	const limit = 10
 
-	const op = "internal/grpc/auth/server/GetFilesByName()"
+	const op = "internal/grpc/files/server/GetFilesByUser()"
 	log := s.l.With(slog.String("op", op))
 	if !validateGetFilesByUser(in) {
 		log.Error("haven't passed validation")
 		return nil, status.Error(codes.InvalidArgument, "incorrect request")
 	}
 
-	files, err := s.storage.GetFilesByUser(ctx, in.UserId, limit)
+	userID, err := callerID(ctx, in.UserId)
 	if err != nil {
 		return nil, err
 	}
 
+	if p, _ := authn.FromContext(ctx); !p.HasScope(scopeFilesRead) {
+		log.Error("token has no scope", slog.String("user_id", userID))
+		return nil, status.Error(codes.PermissionDenied, "token has no "+scopeFilesRead+" scope")
+	}
+
+	owner := in.UserId
+	if owner == "" {
+		owner = userID
+	}
+
+	if err := s.checkCanRead(ctx, log, userID, owner); err != nil {
+		return nil, err
+	}
+
+	files, err := s.storage.GetFilesByUser(ctx, owner, limit)
+	if err != nil {
+		return nil, grpcerr.From(log, err)
+	}
+
 	var filesPb []*pb.File
 
 	for _, f := range files {
 		filesPb = append(filesPb, FileToPb(f))
 	}
 
-	return &pb.GetFilesByUserResponse{Files: filesPb}, err
+	return &pb.GetFilesByUserResponse{Files: filesPb}, nil
+}
+
+// callerID returns user the call is made for. Users act for themselves, so user_id of request
+// is ignored for them, services are trusted to act for user given in user_id of request.
+func callerID(ctx context.Context, requested string) (string, error) {
+	p, ok := authn.FromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "you need to pass token")
+	}
+
+	if p.Service == "" {
+		return p.UserID, nil
+	}
+
+	if len(requested) < 3 {
+		return "", status.Error(codes.InvalidArgument, "user_id is required for services")
+	}
+
+	return requested, nil
+}
+
+// checkCanRead returns error if user can't read files of owner,
+// files of other users need files:read:any permission
+func (s *serverAPI) checkCanRead(ctx context.Context, log *slog.Logger, userID, owner string) error {
+	if userID == owner {
+		return nil
+	}
+
+	ok, err := s.canReadAny(ctx, log, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.Error("permission denied", slog.String("user_id", userID))
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	return nil
+}
+
+// canReadAny tells if user has files:read:any permission, error is grpc status error
+func (s *serverAPI) canReadAny(ctx context.Context, log *slog.Logger, userID string) (bool, error) {
+	ok, err := s.permissions.HasPermission(ctx, userID, permissionReadAny)
+	if err != nil {
+		log.Error("cant check permission", utils.WrapErr(err))
+		return false, status.Error(codes.Internal, "internal error")
+	}
+
+	return ok, nil
 }
 
 // validateUploadFile returns true if all data is correct
 func validateUploadFile(in *pb.UploadFileRequest) bool {
-	if len(in.UserId) < 3 || len(in.File.Content) < 1 || len(in.File.Name) < 1 {
+	if in.File == nil || len(in.File.Content) < 1 || len(in.File.Name) < 1 {
 		return false
 	}
 
@@ -139,17 +264,17 @@ func validateUploadFile(in *pb.UploadFileRequest) bool {
 
 // validateGetFileById returns true if all data is correct
 func validateGetFileById(in *pb.GetFileByIdRequest) bool {
-	return !(len(in.UserId) < 3 || len(in.Id) < 3)
+	return !(len(in.Id) < 3)
 }
 
 // validateGetFilesByName returns true if all data is correct
 func validateGetFilesByName(in *pb.GetFilesByNameRequest) bool {
-	return !(len(in.UserId) < 3 || len(in.Name) < 3)
+	return !(len(in.Name) < 3)
 }
 
 // validateGetFilesByUser returns true if all data is correct
 func validateGetFilesByUser(in *pb.GetFilesByUserRequest) bool {
-	return !(len(in.UserId) < 3)
+	return in.UserId == "" || len(in.UserId) >= 3
 }
 
 func FileToPb(file models.File) *pb.File {
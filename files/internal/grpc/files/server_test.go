@@ -0,0 +1,167 @@
+package files
+
+import (
+	"context"
+	"files/internal/domain/models"
+	"files/internal/grpc/authn"
+	pb "files/pb/files"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	owner   = "owner-id"
+	other   = "other-id"
+	auditor = "auditor-id"
+)
+
+// fakeTokens accepts tokens equal to user IDs, "scoped:<user id>" is access token without files:read
+type fakeTokens struct{}
+
+func (fakeTokens) GetID(ctx context.Context, token string) (string, []string, error) {
+	if userID, ok := strings.CutPrefix(token, "scoped:"); ok {
+		return userID, []string{scopeFilesWrite}, nil
+	}
+	return token, nil, nil
+}
+
+// fakeStorage keeps files in slice
+type fakeStorage struct {
+	Storage
+	files []models.File
+}
+
+func (f *fakeStorage) GetFilesByName(ctx context.Context, name, userID string, limit int) ([]models.File, error) {
+	res := []models.File{}
+	for _, file := range f.files {
+		if file.Name == name && (userID == "" || file.UserID == userID) && len(res) < limit {
+			res = append(res, file)
+		}
+	}
+	return res, nil
+}
+
+// fakePermissions gives files:read:any to auditor only
+type fakePermissions struct{}
+
+func (fakePermissions) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	return userID == auditor && permission == permissionReadAny, nil
+}
+
+var services = map[string]string{"gateway": "gateway-credential"}
+
+// as returns context of call authenticated with authorization metadata
+func as(t *testing.T, authorization string) context.Context {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", authorization))
+
+	ctx, err := authn.AuthFunc(log, fakeTokens{}, services)(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ctx
+}
+
+func TestCallerID(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       func(t *testing.T) context.Context
+		requested string
+		want      string
+		wantCode  codes.Code
+	}{
+		{
+			name:     "not authenticated",
+			ctx:      func(t *testing.T) context.Context { return context.Background() },
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "user",
+			ctx:  func(t *testing.T) context.Context { return as(t, "Bearer "+owner) },
+			want: owner,
+		},
+		{
+			name:      "user can't override user_id",
+			ctx:       func(t *testing.T) context.Context { return as(t, "Bearer "+owner) },
+			requested: other,
+			want:      owner,
+		},
+		{
+			name:      "service with user_id",
+			ctx:       func(t *testing.T) context.Context { return as(t, "Service gateway-credential") },
+			requested: other,
+			want:      other,
+		},
+		{
+			name:     "service without user_id",
+			ctx:      func(t *testing.T) context.Context { return as(t, "Service gateway-credential") },
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := callerID(tt.ctx(t), tt.requested)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("callerID() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("callerID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFilesByName(t *testing.T) {
+	s := &serverAPI{
+		storage: &fakeStorage{files: []models.File{
+			{ID: "owner-report", UserID: owner, Name: "report"},
+			{ID: "other-report", UserID: other, Name: "report"},
+			{ID: "owner-notes", UserID: owner, Name: "notes"},
+		}},
+		l:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		permissions: fakePermissions{},
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		userID        string
+		want          []string
+		wantCode      codes.Code
+	}{
+		{name: "own files", authorization: "Bearer " + owner, want: []string{"owner-report"}},
+		{name: "user can't read files of others", authorization: "Bearer " + owner, userID: other, want: []string{"owner-report"}},
+		{name: "files:read:any", authorization: "Bearer " + auditor, want: []string{"other-report", "owner-report"}},
+		{name: "service for user", authorization: "Service gateway-credential", userID: other, want: []string{"other-report"}},
+		{name: "token without files:read", authorization: "Bearer scoped:" + owner, wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetFilesByName(as(t, tt.authorization), &pb.GetFilesByNameRequest{Name: "report", UserId: tt.userID})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("GetFilesByName() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+
+			var got []string
+			for _, f := range res.GetFiles() {
+				got = append(got, f.GetId())
+			}
+			sort.Strings(got)
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("GetFilesByName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 	"google.golang.org/grpc"
@@ -10,7 +11,9 @@ import (
 	"log/slog"
 )
 
-func CreateGrpcServer(log *slog.Logger) *grpc.Server {
+// CreateGrpcServer creates server with logging, recovery and auth interceptors,
+// authFunc puts caller of every call into context or rejects the call
+func CreateGrpcServer(log *slog.Logger, authFunc auth.AuthFunc) *grpc.Server {
 	loggingOpts := []logging.Option{
 		logging.WithLogOnEvents(
 			//logging.StartCall, logging.FinishCall,
@@ -27,10 +30,18 @@ func CreateGrpcServer(log *slog.Logger) *grpc.Server {
 		}),
 	}
 
-	return grpc.NewServer(grpc.ChainUnaryInterceptor(
-		recovery.UnaryServerInterceptor(recoveryOpts...),
-		logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
-	))
+	return grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recovery.UnaryServerInterceptor(recoveryOpts...),
+			logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
+			auth.UnaryServerInterceptor(authFunc),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(recoveryOpts...),
+			logging.StreamServerInterceptor(InterceptorLogger(log), loggingOpts...),
+			auth.StreamServerInterceptor(authFunc),
+		),
+	)
 }
 
 func InterceptorLogger(l *slog.Logger) logging.Logger {
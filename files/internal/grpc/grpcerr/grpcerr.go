@@ -0,0 +1,44 @@
+package grpcerr
+
+import (
+	"files/lib/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lib/errs"
+	"log/slog"
+)
+
+// Code returns status code of kind of domain error, codes.Internal if error has no kind
+func Code(err error) codes.Code {
+	switch errs.Kind(err) {
+	case errs.ErrInvalid:
+		return codes.InvalidArgument
+	case errs.ErrUnauthenticated:
+		return codes.Unauthenticated
+	case errs.ErrPermissionDenied:
+		return codes.PermissionDenied
+	case errs.ErrNotFound:
+		return codes.NotFound
+	case errs.ErrAlreadyExists:
+		return codes.AlreadyExists
+	case errs.ErrFailedPrecondition:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+// From converts domain error to grpc status error with code of its kind and its message.
+// Errors without kind aren't told to client, they are internal errors.
+// Errors with kind are expected errors of client, so they aren't logged as errors.
+func From(log *slog.Logger, err error) error {
+	code := Code(err)
+	if code == codes.Internal {
+		log.Error("internal error", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info(err.Error(), slog.String("code", code.String()))
+
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,62 @@
+package grpcerr
+
+import (
+	"bytes"
+	"errors"
+	"files/internal/storage"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"lib/errs"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFrom(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+		msg  string
+	}{
+		{"not found", fmt.Errorf("get file: %w", storage.ErrNotFound), codes.NotFound, "get file: file not found"},
+		{"already exists", errs.New(errs.ErrAlreadyExists, "file already exists"), codes.AlreadyExists, "file already exists"},
+		{"permission denied", errs.New(errs.ErrPermissionDenied, "not owner of file"), codes.PermissionDenied, "not owner of file"},
+		{"invalid", errs.New(errs.ErrInvalid, "empty name"), codes.InvalidArgument, "empty name"},
+		{"internal", errors.New("connection refused"), codes.Internal, "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(From(log, tt.err))
+			if st.Code() != tt.code || st.Message() != tt.msg {
+				t.Errorf("got %s %q, want %s %q", st.Code(), st.Message(), tt.code, tt.msg)
+			}
+		})
+	}
+}
+
+func TestFrom_LogLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"expected error of client", storage.ErrNotFound, "level=INFO"},
+		{"internal", errors.New("connection refused"), "level=ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			From(slog.New(slog.NewTextHandler(&buf, nil)), tt.err)
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("logged %q, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -61,7 +61,7 @@ func (s *Storage) GetFileById(ctx context.Context, id string) (models.File, erro
 		if errors.Is(err, storage.ErrObjectNotExist) {
 			return models.File{}, storage2.ErrNotFound
 		}
-		return models.File{}, nil
+		return models.File{}, err
 	}
 	defer reader.Close()
 
@@ -70,7 +70,7 @@ func (s *Storage) GetFileById(ctx context.Context, id string) (models.File, erro
 		if errors.Is(err, storage.ErrObjectNotExist) {
 			return models.File{}, storage2.ErrNotFound
 		}
-		return models.File{}, nil
+		return models.File{}, err
 	}
 
 	bytes, err := io.ReadAll(reader)
@@ -91,8 +91,10 @@ func (s *Storage) GetFileById(ctx context.Context, id string) (models.File, erro
 
 }
 
-func (s *Storage) GetFilesByName(ctx context.Context, name string, limit int) ([]models.File, error) {
-	var files []models.File
+// GetFilesByName returns files with name of userID, files of all users if userID is empty
+func (s *Storage) GetFilesByName(ctx context.Context, name, userID string, limit int) ([]models.File, error) {
+	// no files is empty result, not an error
+	files := []models.File{}
 
 	it := s.bucket.Objects(ctx, nil)
 	for {
@@ -117,7 +119,7 @@ func (s *Storage) GetFilesByName(ctx context.Context, name string, limit int) ([
 
 		parsedTime, _ := time.Parse(time.RFC3339Nano, attrs.Metadata[NameMetadataName])
 
-		if attrs.Metadata[NameMetadataName] == name {
+		if attrs.Metadata[NameMetadataName] == name && (userID == "" || attrs.Metadata[UserIDMetadataName] == userID) {
 			if len(files) >= limit {
 				return files, nil
 			}
@@ -132,11 +134,12 @@ func (s *Storage) GetFilesByName(ctx context.Context, name string, limit int) ([
 		}
 	}
 
-	return nil, storage2.ErrNotFound
+	return files, nil
 }
 
 func (s *Storage) GetFilesByUser(ctx context.Context, userId string, limit int) ([]models.File, error) {
-	var files []models.File
+	// no files is empty result, not an error
+	files := []models.File{}
 
 	it := s.bucket.Objects(ctx, nil)
 	for {
@@ -176,5 +179,5 @@ func (s *Storage) GetFilesByUser(ctx context.Context, userId string, limit int)
 		}
 	}
 
-	return nil, storage2.ErrNotFound
+	return files, nil
 }
@@ -1,7 +1,7 @@
 package storage
 
-import "errors"
+import "lib/errs"
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound = errs.New(errs.ErrNotFound, "file not found")
 )
@@ -9,6 +9,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -20,7 +21,9 @@ func main() {
 
 	client := pb.NewFilesClient(conn)
 
-	ctx := context.Background()
+	// files service accepts only users and services it knows, see auth.service_credentials of its config
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Service "+os.Getenv("FILES_SERVICE_CREDENTIAL"))
 
 	f, err := os.Open("a.jpg")
 	if err != nil {
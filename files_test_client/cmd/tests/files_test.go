@@ -10,6 +10,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -28,7 +29,9 @@ func connectToServer(t *testing.T, target string) pb.FilesClient {
 func TestUploadFileGetFilesByID(t *testing.T) {
 	cl := connectToServer(t, "localhost:1239")
 
-	ctx := context.Background()
+	// test acts as service for users of requests, see auth.service_credentials of files config
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Service "+os.Getenv("FILES_SERVICE_CREDENTIAL"))
 
 	type testCase struct {
 		name    string
@@ -0,0 +1,53 @@
+package errs
+
+import "errors"
+
+// Kinds of domain errors. Storage and services return errors of these kinds, so callers
+// don't depend on database or package specific errors, and gRPC servers of all services
+// map kind to status code with their grpcerr.From.
+var (
+	ErrInvalid            = errors.New("invalid")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrNotFound           = errors.New("not found")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrFailedPrecondition = errors.New("failed precondition")
+)
+
+// kindError is error with its own message, errors.Is reports it's of its kind
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// New returns error with message of kind, e.g. New(ErrNotFound, "session not found").
+// Every call returns distinct error, so it's used to declare sentinel errors of packages.
+func New(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+// Kind returns kind of err, nil if err isn't of any kind
+func Kind(err error) error {
+	for _, kind := range []error{
+		ErrInvalid,
+		ErrUnauthenticated,
+		ErrPermissionDenied,
+		ErrNotFound,
+		ErrAlreadyExists,
+		ErrFailedPrecondition,
+	} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+
+	return nil
+}
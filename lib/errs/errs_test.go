@@ -0,0 +1,44 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	errSessionNotFound := New(ErrNotFound, "session not found")
+	wrapped := fmt.Errorf("revoke: %w", errSessionNotFound)
+
+	if !errors.Is(wrapped, errSessionNotFound) || !errors.Is(wrapped, ErrNotFound) {
+		t.Error("wrapped error isn't its sentinel or its kind")
+	}
+	if errors.Is(wrapped, ErrAlreadyExists) {
+		t.Error("error is of another kind")
+	}
+	if errSessionNotFound.Error() != "session not found" {
+		t.Errorf("message is %q", errSessionNotFound.Error())
+	}
+	if errors.Is(New(ErrNotFound, "session not found"), errSessionNotFound) {
+		t.Error("errors with the same message are equal")
+	}
+}
+
+func TestKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{New(ErrAlreadyExists, "email already used"), ErrAlreadyExists},
+		{fmt.Errorf("op: %w", New(ErrInvalid, "empty fields")), ErrInvalid},
+		{ErrNotFound, ErrNotFound},
+		{errors.New("connection refused"), nil},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := Kind(tt.err); got != tt.want {
+			t.Errorf("Kind(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// keySetMaxAge is how long fetched keys are used, gateway lets JWKS be cached for the same time
+	keySetMaxAge = 5 * time.Minute
+	// keySetMinRefetch limits refetching on unknown kid, so tokens with random kid
+	// don't make verifier fetch JWKS on every call
+	keySetMinRefetch   = 30 * time.Second
+	keySetFetchTimeout = 5 * time.Second
+)
+
+// PublicKey returns key of JWK, it only verifies tokens
+func (j JWK) PublicKey() (Key, error) {
+	k := Key{ID: j.Kid}
+
+	switch {
+	case j.Kty == "RSA" && j.Alg == jwt.SigningMethodRS256.Alg():
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %s: bad modulus: %w", j.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %s: bad exponent: %w", j.Kid, err)
+		}
+
+		k.Method = jwt.SigningMethodRS256
+		k.verify = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case j.Kty == "OKP" && j.Crv == "Ed25519" && j.Alg == jwt.SigningMethodEdDSA.Alg():
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return Key{}, fmt.Errorf("key %s: bad public key", j.Kid)
+		}
+
+		k.Method = jwt.SigningMethodEdDSA
+		k.verify = ed25519.PublicKey(x)
+	default:
+		return Key{}, fmt.Errorf("key %s: unsupported key type %s %s", j.Kid, j.Kty, j.Alg)
+	}
+
+	return k, nil
+}
+
+// FetchFunc returns current JWKS of token issuer
+type FetchFunc func(ctx context.Context) ([]JWK, error)
+
+// FetchURL returns FetchFunc getting JWKS document from url, e.g. /.well-known/jwks.json of gateway
+func FetchURL(url string) FetchFunc {
+	return func(ctx context.Context) ([]JWK, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: %s", url, res.Status)
+		}
+
+		var doc struct {
+			Keys []JWK `json:"keys"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("GET %s: %w", url, err)
+		}
+
+		return doc.Keys, nil
+	}
+}
+
+// KeySet is set of public keys of token issuer, it verifies tokens offline.
+// Keys are fetched on first use and refetched when they are old or token has
+// unknown kid, that's how keys added by rotation are picked up.
+type KeySet struct {
+	fetch FetchFunc
+
+	mu        sync.Mutex
+	keys      map[string]Key
+	fetchedAt time.Time
+}
+
+func NewKeySet(fetch FetchFunc) *KeySet {
+	return &KeySet{fetch: fetch}
+}
+
+// Key returns key with given ID, it's KeyFunc of ParseWith.
+// ErrUnknownKey is returned if issuer has no such key.
+func (s *KeySet) Key(kid string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[kid]
+
+	age := time.Since(s.fetchedAt)
+	if age > keySetMaxAge || (!ok && age > keySetMinRefetch) {
+		if err := s.refetch(); err != nil {
+			if ok {
+				// issuer is unavailable, known key is still good
+				return k, nil
+			}
+			return Key{}, err
+		}
+		k, ok = s.keys[kid]
+	}
+
+	if !ok {
+		return Key{}, ErrUnknownKey
+	}
+
+	return k, nil
+}
+
+// Verify checks token signature and expiration time with keys of set, revocation can't be checked offline.
+// ErrUnknownKey is returned if token isn't signed with key of set, e.g. with secret of app,
+// such tokens can be verified only by issuer. Other errors are errors of Parse or of fetching JWKS.
+func (s *KeySet) Verify(token string) (Claims, error) {
+	t, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		// HS256 tokens have no kid, their secret isn't published
+		return Claims{}, ErrUnknownKey
+	}
+
+	if _, err := s.Key(kid); err != nil {
+		return Claims{}, err
+	}
+
+	return ParseWith(token, s.Key)
+}
+
+// refetch replaces keys with fetched ones, keys which can't be parsed are skipped
+func (s *KeySet) refetch() error {
+	ctx, cancel := context.WithTimeout(context.Background(), keySetFetchTimeout)
+	defer cancel()
+
+	// failed fetch is not retried until keySetMinRefetch passed
+	s.fetchedAt = time.Now()
+
+	jwks, err := s.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("cant fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]Key, len(jwks))
+	for _, j := range jwks {
+		k, err := j.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.ID] = k
+	}
+
+	s.keys = keys
+
+	return nil
+}
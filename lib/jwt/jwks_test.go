@@ -0,0 +1,151 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeIssuer serves JWKS of its keyring and counts fetches
+type fakeIssuer struct {
+	kr      *Keyring
+	err     error
+	fetches int
+}
+
+func (i *fakeIssuer) fetch(ctx context.Context) ([]JWK, error) {
+	i.fetches++
+	if i.err != nil {
+		return nil, i.err
+	}
+	return i.kr.JWKS(), nil
+}
+
+func TestJWK_PublicKey(t *testing.T) {
+	for _, key := range []Key{newEd25519Key(t, "ed"), newRSAKey(t, "rsa")} {
+		t.Run(key.Method.Alg(), func(t *testing.T) {
+			token, claims, err := NewToken("user-id", "user1@example.org", mustKeyring(t, key), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pub, err := mustKeyring(t, key).JWKS()[0].PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// public key can't sign
+			if _, err := Sign(claims, pub); err == nil {
+				t.Error("Sign() with public key error = nil")
+			}
+
+			got, err := Parse(token, mustKeyring(t, pub))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != claims {
+				t.Errorf("Parse() got = %v, want %v", got, claims)
+			}
+		})
+	}
+
+	if _, err := (JWK{Kty: "oct", Kid: "k", Alg: "HS256"}).PublicKey(); err == nil {
+		t.Error("PublicKey() of symmetric key error = nil")
+	}
+}
+
+func TestKeySet_Verify(t *testing.T) {
+	key := newEd25519Key(t, "current")
+	issuer := &fakeIssuer{kr: mustKeyring(t, key)}
+	ks := NewKeySet(issuer.fetch)
+
+	valid, claims, err := NewToken("user-id", "user1@example.org", mustKeyring(t, key), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, _, err := NewToken("user-id", "user1@example.org", mustKeyring(t, key), -time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged, _, err := NewToken("user-id", "user1@example.org", mustKeyring(t, newEd25519Key(t, "current")), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hmac, _, err := NewToken("user-id", "user1@example.org", mustKeyring(t, NewHMACKey(secret)), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: valid},
+		{name: "expired", token: expired, wantErr: ErrTokenExpired},
+		{name: "signed with other key", token: forged, wantErr: ErrInvalidSignature},
+		{name: "signed with secret", token: hmac, wantErr: ErrUnknownKey},
+		{name: "malformed", token: "not-a-token", wantErr: ErrTokenMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ks.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != claims {
+				t.Errorf("Verify() got = %v, want %v", got, claims)
+			}
+		})
+	}
+
+	if issuer.fetches != 1 {
+		t.Errorf("fetches = %d, want 1", issuer.fetches)
+	}
+}
+
+func TestKeySet_Refetch(t *testing.T) {
+	current := newEd25519Key(t, "current")
+	issuer := &fakeIssuer{kr: mustKeyring(t, current)}
+	ks := NewKeySet(issuer.fetch)
+
+	if _, err := ks.Key("current"); err != nil {
+		t.Fatal(err)
+	}
+
+	// key added by rotation isn't fetched again right away
+	next := newRSAKey(t, "next")
+	issuer.kr = mustKeyring(t, current, next)
+
+	if _, err := ks.Key("next"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Key() error = %v, want %v", err, ErrUnknownKey)
+	}
+	if issuer.fetches != 1 {
+		t.Fatalf("fetches = %d, want 1", issuer.fetches)
+	}
+
+	// but it's fetched on unknown kid after a while
+	ks.fetchedAt = time.Now().Add(-keySetMinRefetch - time.Second)
+
+	if _, err := ks.Key("next"); err != nil {
+		t.Fatalf("Key() error = %v, want nil", err)
+	}
+	if issuer.fetches != 2 {
+		t.Fatalf("fetches = %d, want 2", issuer.fetches)
+	}
+
+	// known keys are used while issuer is unavailable
+	issuer.err = errors.New("unavailable")
+	ks.fetchedAt = time.Now().Add(-keySetMaxAge - time.Second)
+
+	if _, err := ks.Key("current"); err != nil {
+		t.Errorf("Key() error = %v, want nil", err)
+	}
+	if _, err := ks.Key("unknown"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Key() error = %v, want %v", err, ErrUnknownKey)
+	}
+}
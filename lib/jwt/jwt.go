@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTokenExpired     = errors.New("token expired")
+	ErrTokenMalformed   = errors.New("token malformed")
+	ErrInvalidSignature = errors.New("invalid token signature")
+)
+
+// Claims are claims of auth token we care about
+type Claims struct {
+	UserID    string
+	Email     string
+	JTI       string // unique ID of the token, used for revocation
+	AppID     int32  // app the token was issued for, zero for tokens without app
+	Audience  string // name of the app, put into aud claim
+	SessionID string // login the token belongs to, it's kept when token is refreshed
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// KeyFunc returns key for kid header of the token
+type KeyFunc func(kid string) (Key, error)
+
+// NewClaims returns claims of new token for user with given id and email
+func NewClaims(id, email string, ttl time.Duration) Claims {
+	now := time.Now()
+
+	return Claims{
+		UserID:    id,
+		Email:     email,
+		JTI:       uuid.New().String(),
+		IssuedAt:  time.Unix(now.Unix(), 0),
+		ExpiresAt: time.Unix(now.Add(ttl).Unix(), 0),
+	}
+}
+
+// NewToken creates token for user with given id and email signed with
+// active key of keyring
+func NewToken(id, email string, kr *Keyring, ttl time.Duration) (string, Claims, error) {
+	key, err := kr.SigningKey(time.Now())
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	c := NewClaims(id, email, ttl)
+
+	t, err := Sign(c, key)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	return t, c, nil
+}
+
+// Sign creates token with given claims signed with key
+func Sign(c Claims, key Key) (string, error) {
+	payload := jwt.MapClaims{
+		"id":    c.UserID,
+		"email": c.Email,
+		"jti":   c.JTI,
+		"iat":   c.IssuedAt.Unix(),
+		"exp":   c.ExpiresAt.Unix(),
+	}
+
+	if c.AppID != 0 {
+		payload["app_id"] = c.AppID
+	}
+	if c.Audience != "" {
+		payload["aud"] = c.Audience
+	}
+	if c.SessionID != "" {
+		payload["sid"] = c.SessionID
+	}
+
+	token := jwt.NewWithClaims(key.Method, payload)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	return token.SignedString(key.sign)
+}
+
+// Parse verifies token signature and expiration time and returns its claims.
+// Key is picked by kid header, tokens with unknown kid or with algorithm
+// other than the one of the key are rejected with ErrInvalidSignature.
+func Parse(tokenString string, kr *Keyring) (Claims, error) {
+	return ParseWith(tokenString, kr.Key)
+}
+
+// ParseWith is Parse with keys looked up by keys function instead of keyring,
+// it's used when some keys aren't known in advance, e.g. secrets of apps.
+func ParseWith(tokenString string, keys KeyFunc) (Claims, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		k, err := keys(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method != k.Method {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		return k.verify, nil
+	})
+	if err != nil {
+		return Claims{}, validationErr(err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	// token without exp would never expire, we don't create such tokens
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	id, ok := claims["id"].(string)
+	if !ok || id == "" {
+		return Claims{}, ErrTokenMalformed
+	}
+
+	// tokens created before jti was added don't have jti and iat,
+	// they can't be revoked one by one, but expire as usual
+	email, _ := claims["email"].(string)
+	jti, _ := claims["jti"].(string)
+	iat, _ := claims["iat"].(float64)
+	appID, _ := claims["app_id"].(float64)
+	aud, _ := claims["aud"].(string)
+	sid, _ := claims["sid"].(string)
+
+	return Claims{
+		UserID:    id,
+		Email:     email,
+		JTI:       jti,
+		AppID:     int32(appID),
+		Audience:  aud,
+		SessionID: sid,
+		IssuedAt:  time.Unix(int64(iat), 0),
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}, nil
+}
+
+// validationErr converts jwt library errors to package errors.
+// Signature errors are checked first so forged tokens are never reported as expired.
+func validationErr(err error) error {
+	var vErr *jwt.ValidationError
+	if !errors.As(err, &vErr) {
+		return ErrTokenMalformed
+	}
+
+	switch {
+	case vErr.Errors&jwt.ValidationErrorMalformed != 0:
+		return ErrTokenMalformed
+	case vErr.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+		return ErrInvalidSignature
+	case vErr.Errors&jwt.ValidationErrorExpired != 0:
+		return ErrTokenExpired
+	default:
+		return ErrTokenMalformed
+	}
+}
@@ -0,0 +1,227 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const secret = "test-secret"
+
+func mustKeyring(t *testing.T, keys ...Key) *Keyring {
+	kr, err := NewKeyring(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+func newEd25519Key(t *testing.T, id string) Key {
+	pub, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Key{ID: id, Method: jwt.SigningMethodEdDSA, sign: private, verify: pub}
+}
+
+func newRSAKey(t *testing.T, id string) Key {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Key{ID: id, Method: jwt.SigningMethodRS256, sign: private, verify: &private.PublicKey}
+}
+
+func TestParse(t *testing.T) {
+	kr := mustKeyring(t, NewHMACKey(secret))
+
+	valid, _, err := NewToken("user-id", "user1@example.org", kr, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, _, err := NewToken("user-id", "user1@example.org", kr, -time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSecret, _, err := NewToken("user-id", "user1@example.org", mustKeyring(t, NewHMACKey("other-secret")), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownKid, _, err := NewToken("user-id", "user1@example.org", mustKeyring(t, newEd25519Key(t, "unknown")), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		"id":  "user-id",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noExp, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id": "user-id",
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "ok",
+			token: valid,
+			want:  "user-id",
+		},
+		{
+			name:    "expired",
+			token:   expired,
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:    "other secret",
+			token:   otherSecret,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "unknown kid",
+			token:   unknownKid,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "none algorithm",
+			token:   none,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "without exp",
+			token:   noExp,
+			wantErr: ErrTokenMalformed,
+		},
+		{
+			name:    "garbage",
+			token:   "not.a.token",
+			wantErr: ErrTokenMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.token, kr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.UserID != tt.want {
+				t.Errorf("Parse() got = %v, want %v", got.UserID, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+	}{
+		{name: "HS256", key: NewHMACKey(secret)},
+		{name: "EdDSA", key: newEd25519Key(t, "ed")},
+		{name: "RS256", key: newRSAKey(t, "rsa")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kr := mustKeyring(t, tt.key)
+
+			token, claims, err := NewToken("user-id", "user1@example.org", kr, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if claims.JTI == "" {
+				t.Fatal("NewToken() jti is empty")
+			}
+
+			got, err := Parse(token, kr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != claims {
+				t.Errorf("Parse() got = %v, want %v", got, claims)
+			}
+		})
+	}
+}
+
+func TestSign_OptionalClaims(t *testing.T) {
+	key := NewHMACKey(secret)
+
+	c := NewClaims("user-id", "user1@example.org", time.Hour)
+	c.AppID = 7
+	c.Audience = "app"
+	c.SessionID = "session-id"
+
+	token, err := Sign(c, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Parse(token, mustKeyring(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != c {
+		t.Errorf("Parse() got = %v, want %v", got, c)
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	now := time.Now()
+
+	old := newEd25519Key(t, "old")
+	old.NotAfter = now
+
+	current := newEd25519Key(t, "current")
+	current.NotBefore = now.Add(-time.Hour)
+
+	next := newEd25519Key(t, "next")
+	next.NotBefore = now.Add(time.Hour)
+
+	kr := mustKeyring(t, old, current, next)
+
+	key, err := kr.SigningKey(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != "current" {
+		t.Errorf("SigningKey() got = %v, want current", key.ID)
+	}
+
+	// tokens signed with old key are still valid until they expire
+	oldToken, _, err := NewToken("user-id", "user1@example.org", mustKeyring(t, Key{
+		ID: "old", Method: old.Method, sign: old.sign, verify: old.verify,
+	}), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(oldToken, kr); err != nil {
+		t.Errorf("Parse() error = %v, want nil", err)
+	}
+
+	if got := len(kr.JWKS()); got != 3 {
+		t.Errorf("JWKS() got %d keys, want 3", got)
+	}
+}
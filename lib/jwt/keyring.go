@@ -0,0 +1,210 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no active signing key")
+	ErrUnknownKey   = errors.New("unknown key")
+)
+
+// Key is key used to sign and verify tokens.
+// Key signs new tokens only between NotBefore and NotAfter (zero means no limit),
+// but verifies tokens as long as it is in Keyring. That's how keys are rotated:
+// new key is added with NotBefore in future, so verifiers can fetch it from JWKS
+// before first token is signed with it, and old key stays in Keyring with NotAfter
+// in past until all tokens signed with it are expired.
+type Key struct {
+	ID        string // kid header of signed tokens
+	Method    jwt.SigningMethod
+	NotBefore time.Time
+	NotAfter  time.Time
+
+	sign   interface{}
+	verify interface{}
+}
+
+// NewHMACKey returns HS256 key, it's used when no asymmetric keys are configured.
+// HS256 key is never published in JWKS.
+func NewHMACKey(secret string) Key {
+	return Key{
+		Method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// LoadKey reads PEM encoded RSA or Ed25519 private key from file.
+// RSA keys are used with RS256 and Ed25519 keys with EdDSA.
+func LoadKey(id, path string, notBefore, notAfter time.Time) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	var private interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	k := Key{
+		ID:        id,
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+		sign:      private,
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		k.Method = jwt.SigningMethodRS256
+		k.verify = &private.PublicKey
+	case ed25519.PrivateKey:
+		k.Method = jwt.SigningMethodEdDSA
+		k.verify = private.Public()
+	default:
+		return Key{}, fmt.Errorf("%s: unsupported key type %T", path, private)
+	}
+
+	return k, nil
+}
+
+// canSign returns true if key may be used for signing at given time
+func (k Key) canSign(now time.Time) bool {
+	if !k.NotBefore.IsZero() && now.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !now.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// JWK is public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Keyring is set of keys identified by kid
+type Keyring struct {
+	keys []Key
+}
+
+// NewKeyring returns Keyring with given keys, all asymmetric keys must have unique not empty ID.
+func NewKeyring(keys ...Key) (*Keyring, error) {
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.Method != jwt.SigningMethodHS256 && k.ID == "" {
+			return nil, errors.New("key without id")
+		}
+		if seen[k.ID] {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		seen[k.ID] = true
+	}
+
+	sorted := append([]Key(nil), keys...)
+	// newest keys first, so SigningKey picks the most recent active one
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].NotBefore.After(sorted[j].NotBefore)
+	})
+
+	return &Keyring{keys: sorted}, nil
+}
+
+// SigningKey returns newest key that can sign at given time.
+// Asymmetric keys are preferred over HS256 one.
+func (kr *Keyring) SigningKey(now time.Time) (Key, error) {
+	var hmacKey *Key
+	for i, k := range kr.keys {
+		if !k.canSign(now) {
+			continue
+		}
+		if k.Method == jwt.SigningMethodHS256 {
+			hmacKey = &kr.keys[i]
+			continue
+		}
+		return k, nil
+	}
+
+	if hmacKey != nil {
+		return *hmacKey, nil
+	}
+
+	return Key{}, ErrNoSigningKey
+}
+
+// Key returns key with given ID, it's KeyFunc of Parse.
+// HS256 key without ID is returned for tokens without kid header.
+func (kr *Keyring) Key(kid string) (Key, error) {
+	for _, k := range kr.keys {
+		if k.ID == kid {
+			return k, nil
+		}
+	}
+
+	return Key{}, ErrUnknownKey
+}
+
+// JWKS returns public keys of all asymmetric keys of keyring,
+// including not yet active and no longer active ones
+func (kr *Keyring) JWKS() []JWK {
+	var res []JWK
+
+	for _, k := range kr.keys {
+		switch pub := k.verify.(type) {
+		case *rsa.PublicKey:
+			res = append(res, JWK{
+				Kty: "RSA",
+				Kid: k.ID,
+				Alg: k.Method.Alg(),
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			res = append(res, JWK{
+				Kty: "OKP",
+				Kid: k.ID,
+				Alg: k.Method.Alg(),
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return res
+}
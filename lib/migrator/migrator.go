@@ -0,0 +1,107 @@
+// Package migrator applies goose migrations embedded in binary and checks
+// that database has the schema binary was built for.
+// It doesn't depend on any service, so every service with database can use it.
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+)
+
+var (
+	ErrSchemaTooOld = errors.New("schema is older than binary, run migrate up")
+	ErrSchemaTooNew = errors.New("schema is newer than binary, update binary or run its migrate down")
+)
+
+type Migrator struct {
+	p *goose.Provider
+	// latest is version of the last migration in fsys
+	latest int64
+}
+
+// New returns migrator of db with migrations from root of fsys,
+// dialect is goose.DialectPostgres, goose.DialectSQLite3 etc.
+func New(db *sql.DB, dialect goose.Dialect, fsys fs.FS) (*Migrator, error) {
+	p, err := goose.NewProvider(dialect, db, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest int64
+	for _, s := range p.ListSources() {
+		if s.Version > latest {
+			latest = s.Version
+		}
+	}
+
+	return &Migrator{
+		p:      p,
+		latest: latest,
+	}, nil
+}
+
+// Latest returns version of the last migration binary has
+func (m *Migrator) Latest() int64 {
+	return m.latest
+}
+
+// Up applies all pending migrations and returns versions of applied ones
+func (m *Migrator) Up(ctx context.Context) ([]int64, error) {
+	res, err := m.p.Up(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int64, 0, len(res))
+	for _, r := range res {
+		versions = append(versions, r.Source.Version)
+	}
+
+	return versions, nil
+}
+
+// Down rolls back the last applied migration and returns its version
+func (m *Migrator) Down(ctx context.Context) (int64, error) {
+	res, err := m.p.Down(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.Source.Version, nil
+}
+
+// Status returns state of every migration binary has, the oldest first
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.p.Status(ctx)
+}
+
+// Check returns ErrSchemaTooOld if some migration isn't applied
+// and ErrSchemaTooNew if database has migrations binary doesn't know
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.p.GetDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > m.latest {
+		return fmt.Errorf("%w: version %d, binary has %d", ErrSchemaTooNew, version, m.latest)
+	}
+
+	status, err := m.p.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range status {
+		if s.State == goose.StatePending {
+			return fmt.Errorf("%w: version %d isn't applied", ErrSchemaTooOld, s.Source.Version)
+		}
+	}
+
+	return nil
+}
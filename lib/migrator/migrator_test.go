@@ -0,0 +1,77 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+func migration(table string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(
+		"-- +goose Up\nCREATE TABLE " + table + " (id INTEGER);\n-- +goose Down\nDROP TABLE " + table + ";\n",
+	)}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	older := fstest.MapFS{"1_a.sql": migration("a")}
+	newer := fstest.MapFS{"1_a.sql": migration("a"), "2_b.sql": migration("b")}
+
+	m, err := New(db, goose.DialectSQLite3, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Latest() != 2 {
+		t.Errorf("Latest() = %d, want 2", m.Latest())
+	}
+
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaTooOld) {
+		t.Fatalf("Check() of empty database = %v, want %v", err, ErrSchemaTooOld)
+	}
+
+	versions, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Errorf("Up() = %v, want 2 versions", versions)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Check() after Up() = %v", err)
+	}
+
+	old, err := New(db, goose.DialectSQLite3, older)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Check(ctx); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Check() of older binary = %v, want %v", err, ErrSchemaTooNew)
+	}
+
+	version, err := m.Down(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Errorf("Down() = %d, want 2", version)
+	}
+	if err := m.Check(ctx); !errors.Is(err, ErrSchemaTooOld) {
+		t.Errorf("Check() after Down() = %v, want %v", err, ErrSchemaTooOld)
+	}
+	if err := old.Check(ctx); err != nil {
+		t.Errorf("Check() of older binary after Down() = %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package securetoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidSignature = errors.New("invalid token signature")
+
+// tokenLen is number of random bytes in token (256 bits)
+const tokenLen = 32
+
+// New returns new random url safe token and its hash.
+// Only hash should be stored, token is given to the client.
+func New() (token string, hash string, err error) {
+	b := make([]byte, tokenLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+
+	return token, Hash(token), nil
+}
+
+// Hash returns hex encoded sha256 of token.
+// Tokens have enough entropy, so there is no need for salt or slow hash.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign returns payload with its HMAC-SHA256 signature appended after dot.
+// Payload must be url safe, it isn't encoded.
+func Sign(secret []byte, payload string) string {
+	return payload + "." + mac(secret, payload)
+}
+
+// Verify checks signature of token created by Sign and returns its payload
+func Verify(secret []byte, token string) (string, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", ErrInvalidSignature
+	}
+
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, payload))) {
+		return "", ErrInvalidSignature
+	}
+
+	return payload, nil
+}
+
+func mac(secret []byte, payload string) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,95 @@
+package tokens
+
+import (
+	"context"
+	"lib/jwt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// appKeyPrefix is prefix of kid header of tokens signed with app secret
+const appKeyPrefix = "app:"
+
+// appCacheTTL is how long app settings are cached, changed secret or TTL
+// is picked up after it
+const appCacheTTL = time.Minute
+
+// appCache keeps apps by ID so every validated token doesn't hit storage
+type appCache struct {
+	mu      sync.RWMutex
+	entries map[int32]appEntry
+}
+
+type appEntry struct {
+	app       App
+	expiresAt time.Time
+}
+
+func newAppCache() *appCache {
+	return &appCache{
+		entries: make(map[int32]appEntry),
+	}
+}
+
+func (c *appCache) get(id int32) (App, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[id]
+	if !ok || !time.Now().Before(e.expiresAt) {
+		return App{}, false
+	}
+
+	return e.app, true
+}
+
+func (c *appCache) add(a App) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[a.ID] = appEntry{app: a, expiresAt: time.Now().Add(appCacheTTL)}
+}
+
+// app returns app with given ID from cache or apps
+func (m *Manager) app(ctx context.Context, id int32) (App, error) {
+	if a, ok := m.appCache.get(id); ok {
+		return a, nil
+	}
+
+	a, err := m.apps.FindApp(ctx, id)
+	if err != nil {
+		return App{}, err
+	}
+
+	m.appCache.add(a)
+
+	return a, nil
+}
+
+// appKey returns key of app secret for kid header app:<id>
+func (m *Manager) appKey(kid string) (jwt.Key, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(kid, appKeyPrefix), 10, 32)
+	if err != nil || m.apps == nil {
+		return jwt.Key{}, jwt.ErrUnknownKey
+	}
+
+	// Parse has no context, lookup is bounded by cache TTL anyway
+	a, err := m.app(context.Background(), int32(id))
+	if err != nil || a.Secret == "" {
+		return jwt.Key{}, jwt.ErrUnknownKey
+	}
+
+	return appKey(a), nil
+}
+
+func appKey(a App) jwt.Key {
+	k := jwt.NewHMACKey(a.Secret)
+	k.ID = appKeyID(a.ID)
+	return k
+}
+
+func appKeyID(id int32) string {
+	return appKeyPrefix + strconv.Itoa(int(id))
+}
@@ -0,0 +1,57 @@
+package tokens
+
+import (
+	"sync"
+	"time"
+)
+
+// cache is set of revoked jti, each entry is removed when token expires
+// because expired tokens are rejected anyway.
+type cache struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+	// nextSweep is time when expired entries will be removed on next add
+	nextSweep time.Time
+}
+
+const sweepInterval = time.Minute
+
+func newCache() *cache {
+	return &cache{
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (c *cache) add(jti string, expiresAt time.Time) {
+	if jti == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.After(c.nextSweep) {
+		for k, exp := range c.entries {
+			if !now.Before(exp) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = now.Add(sweepInterval)
+	}
+
+	c.entries[jti] = expiresAt
+}
+
+func (c *cache) has(jti string) bool {
+	if jti == "" {
+		return false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	exp, ok := c.entries[jti]
+
+	return ok && time.Now().Before(exp)
+}
@@ -0,0 +1,32 @@
+package tokens
+
+import "time"
+
+// RefreshToken is stored form of opaque refresh token, token itself is never stored.
+// All tokens created by rotation of the same login share FamilyID.
+type RefreshToken struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	Email     string        // email of user at the moment of login, it's put into refreshed auth tokens
+	AppID     int32         // app of the login, refreshed auth tokens are issued for it
+	Audience  string        // name of the app, kept only by storages of managers without Apps
+	TokenTTL  time.Duration // TTL of auth tokens of the app, kept only by storages of managers without Apps
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    time.Time // zero if token wasn't used yet
+	RevokedAt time.Time // zero if token isn't revoked
+	CreatedAt time.Time
+}
+
+func (t RefreshToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+func (t RefreshToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+func (t RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,402 @@
+// Package tokens issues, validates, refreshes and revokes auth tokens.
+// It's used by auth service and by user service when it works with tokens by itself,
+// services only implement Storage of revocations and refresh tokens.
+package tokens
+
+import (
+	"context"
+	"errors"
+	"lib/jwt"
+	"lib/securetoken"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTokenRevoked        = errors.New("token revoked")
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrWrongApp            = errors.New("token issued for another app")
+)
+
+// Errors of Storage and Apps, they may be wrapped
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrAlreadyUsed = errors.New("already used")
+)
+
+// Storage keeps revocations and refresh tokens.
+// RevokeUserTokens revokes tokens issued in the second of before too, iat has seconds only.
+// FindRefreshToken returns ErrNotFound for unknown token and RotateRefreshToken
+// returns ErrAlreadyUsed if old token was used or revoked already.
+type Storage interface {
+	RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID string, before time.Time) error
+	IsTokenRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error)
+
+	SaveRefreshToken(ctx context.Context, t RefreshToken) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID string, next RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+}
+
+// Apps finds apps by ID, ErrNotFound is returned for unknown app
+type Apps interface {
+	FindApp(ctx context.Context, id int32) (App, error)
+}
+
+// App is client application tokens are issued for.
+// App may have its own secret, key and TTL, otherwise active key and default TTL are used.
+type App struct {
+	ID           int32
+	Name         string        // put into aud claim
+	Secret       string        // HS256 secret of app tokens, empty if tokens are signed with keyring keys
+	SigningKeyID string        // kid of keyring key app tokens are signed with, empty for active key
+	TokenTTL     time.Duration // zero for default TTL
+}
+
+// Subject is user tokens are issued for and app he logged in to.
+// SessionID identifies the login, it's used as refresh token family and put
+// into sid claim of all tokens of the family. New ID is generated if it's empty.
+type Subject struct {
+	UserID    string
+	Email     string
+	App       App
+	SessionID string
+}
+
+// Pair is auth token with refresh token of the same login
+type Pair struct {
+	Token        string
+	RefreshToken string
+	Claims       jwt.Claims // claims of Token
+}
+
+// Manager issues, validates, refreshes and revokes tokens.
+// Revoked tokens are kept in storage and cached in memory until they expire.
+type Manager struct {
+	storage    Storage
+	apps       Apps
+	keys       *jwt.Keyring
+	tokenTTL   time.Duration
+	refreshTTL time.Duration
+	revoked    *cache
+	appCache   *appCache
+}
+
+// New returns manager of tokens signed with keys.
+// If apps is nil, app of login is kept in its refresh tokens and app secrets aren't supported,
+// otherwise app is looked up on every refresh and tokens signed with app secret are accepted.
+func New(storage Storage, apps Apps, keys *jwt.Keyring, tokenTTL, refreshTTL time.Duration) *Manager {
+	return &Manager{
+		storage:    storage,
+		apps:       apps,
+		keys:       keys,
+		tokenTTL:   tokenTTL,
+		refreshTTL: refreshTTL,
+		revoked:    newCache(),
+		appCache:   newAppCache(),
+	}
+}
+
+// Issue creates auth token and refresh token of new login
+func (m *Manager) Issue(ctx context.Context, s Subject) (Pair, error) {
+	if s.SessionID == "" {
+		s.SessionID = uuid.New().String()
+	}
+
+	if err := m.waitRevocation(ctx, s.UserID); err != nil {
+		return Pair{}, err
+	}
+
+	t, c, err := m.newToken(s)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	refresh, rt, err := m.newRefreshToken(s, s.SessionID)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	if err := m.storage.SaveRefreshToken(ctx, rt); err != nil {
+		return Pair{}, err
+	}
+
+	return Pair{Token: t, RefreshToken: refresh, Claims: c}, nil
+}
+
+// Refresh exchanges refresh token for a new pair, refresh token can be used only once.
+// If used token is presented again, it could be stolen, so whole family is revoked.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (Pair, error) {
+	old, err := m.storage.FindRefreshToken(ctx, securetoken.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Pair{}, ErrRefreshTokenInvalid
+		}
+		return Pair{}, err
+	}
+
+	if old.IsRevoked() {
+		return Pair{}, ErrRefreshTokenInvalid
+	}
+
+	if old.IsUsed() {
+		return Pair{}, m.reused(ctx, old)
+	}
+
+	if old.IsExpired(time.Now()) {
+		return Pair{}, ErrRefreshTokenExpired
+	}
+
+	s := Subject{
+		UserID:    old.UserID,
+		Email:     old.Email,
+		App:       App{ID: old.AppID, Name: old.Audience, TokenTTL: old.TokenTTL},
+		SessionID: old.FamilyID,
+	}
+
+	if m.apps != nil && old.AppID != 0 {
+		s.App, err = m.app(ctx, old.AppID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				// app was removed after login
+				return Pair{}, ErrRefreshTokenInvalid
+			}
+			return Pair{}, err
+		}
+	}
+
+	refresh, rt, err := m.newRefreshToken(s, old.FamilyID)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	if err := m.storage.RotateRefreshToken(ctx, old.ID, rt); err != nil {
+		if errors.Is(err, ErrAlreadyUsed) {
+			// lost race with another refresh of the same token, that's reuse too
+			return Pair{}, m.reused(ctx, old)
+		}
+		return Pair{}, err
+	}
+
+	t, c, err := m.newToken(s)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	return Pair{Token: t, RefreshToken: refresh, Claims: c}, nil
+}
+
+// Family returns family of refresh token, it's session ID of the login.
+// It lets caller check the login before Refresh rotates token of it.
+// ErrRefreshTokenInvalid is returned for unknown token, used and expired tokens are rejected only by Refresh.
+func (m *Manager) Family(ctx context.Context, refreshToken string) (string, error) {
+	rt, err := m.storage.FindRefreshToken(ctx, securetoken.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", ErrRefreshTokenInvalid
+		}
+		return "", err
+	}
+
+	return rt.FamilyID, nil
+}
+
+// waitRevocation waits for the next second if tokens of user were revoked in the current one,
+// tokens issued in the second of RevokeAll are revoked with it, so new login must not get them
+func (m *Manager) waitRevocation(ctx context.Context, userID string) error {
+	now := time.Now()
+
+	revoked, err := m.storage.IsTokenRevoked(ctx, "", userID, now.Truncate(time.Second))
+	if err != nil || !revoked {
+		return err
+	}
+
+	t := time.NewTimer(now.Truncate(time.Second).Add(time.Second).Sub(now))
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// newToken creates auth token scoped to app of subject
+func (m *Manager) newToken(s Subject) (string, jwt.Claims, error) {
+	ttl := m.tokenTTL
+	if s.App.TokenTTL > 0 {
+		ttl = s.App.TokenTTL
+	}
+
+	c := jwt.NewClaims(s.UserID, s.Email, ttl)
+	c.AppID = s.App.ID
+	c.Audience = s.App.Name
+	c.SessionID = s.SessionID
+
+	var key jwt.Key
+	var err error
+
+	switch {
+	case s.App.Secret != "":
+		key = appKey(s.App)
+	case s.App.SigningKeyID != "":
+		key, err = m.keys.Key(s.App.SigningKeyID)
+	default:
+		key, err = m.keys.SigningKey(time.Now())
+	}
+	if err != nil {
+		return "", jwt.Claims{}, err
+	}
+
+	t, err := jwt.Sign(c, key)
+	if err != nil {
+		return "", jwt.Claims{}, err
+	}
+
+	return t, c, nil
+}
+
+// reused revokes family of reused token
+func (m *Manager) reused(ctx context.Context, rt RefreshToken) error {
+	if err := m.storage.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenInvalid
+}
+
+// newRefreshToken returns refresh token for the client and its stored form
+func (m *Manager) newRefreshToken(s Subject, familyID string) (string, RefreshToken, error) {
+	token, hash, err := securetoken.New()
+	if err != nil {
+		return "", RefreshToken{}, err
+	}
+
+	now := time.Now()
+
+	return token, RefreshToken{
+		ID:        uuid.New().String(),
+		FamilyID:  familyID,
+		UserID:    s.UserID,
+		Email:     s.Email,
+		AppID:     s.App.ID,
+		Audience:  s.App.Name,
+		TokenTTL:  s.App.TokenTTL,
+		TokenHash: hash,
+		ExpiresAt: now.Add(m.refreshTTL),
+		CreatedAt: now,
+	}, nil
+}
+
+// JWKS returns public keys that can be used to verify tokens offline
+func (m *Manager) JWKS(ctx context.Context) ([]jwt.JWK, error) {
+	return m.keys.JWKS(), nil
+}
+
+// Validate checks token signature, expiration time and revocation.
+// Returns jwt package errors or ErrTokenRevoked.
+func (m *Manager) Validate(ctx context.Context, token string) (jwt.Claims, error) {
+	c, err := m.Parse(token)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	if m.revoked.has(c.JTI) {
+		return jwt.Claims{}, ErrTokenRevoked
+	}
+
+	revoked, err := m.storage.IsTokenRevoked(ctx, c.JTI, c.UserID, c.IssuedAt)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	if revoked {
+		m.revoked.add(c.JTI, c.ExpiresAt)
+		return jwt.Claims{}, ErrTokenRevoked
+	}
+
+	return c, nil
+}
+
+// Parse checks only token signature and expiration time, revocation is not checked.
+func (m *Manager) Parse(token string) (jwt.Claims, error) {
+	var kid string
+
+	c, err := jwt.ParseWith(token, func(k string) (jwt.Key, error) {
+		kid = k
+		if !strings.HasPrefix(k, appKeyPrefix) {
+			return m.keys.Key(k)
+		}
+		return m.appKey(k)
+	})
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	// app secret is known to the app, so it can sign only tokens of this app
+	if strings.HasPrefix(kid, appKeyPrefix) && kid != appKeyID(c.AppID) {
+		return jwt.Claims{}, jwt.ErrInvalidSignature
+	}
+
+	return c, nil
+}
+
+// CheckApp returns ErrWrongApp if token wasn't issued for app with given ID.
+// There is no "any app", zero appID matches only old tokens without app_id claim.
+func CheckApp(c jwt.Claims, appID int32) error {
+	if c.AppID != appID {
+		return ErrWrongApp
+	}
+
+	return nil
+}
+
+// Revoke revokes single token, it will be rejected by Validate until it expires.
+// If refreshToken of the same user is given, its family is revoked too.
+func (m *Manager) Revoke(ctx context.Context, c jwt.Claims, refreshToken string) error {
+	if c.JTI == "" {
+		// old tokens without jti can be revoked only with all user tokens
+		return m.RevokeAll(ctx, c.UserID)
+	}
+
+	if err := m.storage.RevokeToken(ctx, c.JTI, c.UserID, c.ExpiresAt); err != nil {
+		return err
+	}
+
+	m.revoked.add(c.JTI, c.ExpiresAt)
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	rt, err := m.storage.FindRefreshToken(ctx, securetoken.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// refresh token of somebody else is ignored, user can log out only himself
+	if rt.UserID != c.UserID {
+		return nil
+	}
+
+	return m.storage.RevokeRefreshTokenFamily(ctx, rt.FamilyID)
+}
+
+// RevokeAll revokes all auth tokens of user issued up to now and all his refresh tokens.
+// Personal access and OAuth tokens aren't JWT, they are revoked by service which keeps them.
+func (m *Manager) RevokeAll(ctx context.Context, userID string) error {
+	if err := m.storage.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	return m.storage.RevokeUserTokens(ctx, userID, time.Now())
+}
@@ -0,0 +1,505 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"lib/jwt"
+	"lib/securetoken"
+	"testing"
+	"time"
+)
+
+// fakeStorage keeps revocations and refresh tokens in maps
+type fakeStorage struct {
+	jtis    map[string]bool
+	users   map[string]time.Time
+	refresh map[string]RefreshToken // by hash
+}
+
+func (f *fakeStorage) RevokeToken(_ context.Context, jti, _ string, _ time.Time) error {
+	f.jtis[jti] = true
+	return nil
+}
+
+func (f *fakeStorage) RevokeUserTokens(_ context.Context, userID string, before time.Time) error {
+	f.users[userID] = before
+	return nil
+}
+
+func (f *fakeStorage) IsTokenRevoked(_ context.Context, jti, userID string, issuedAt time.Time) (bool, error) {
+	before, ok := f.users[userID]
+	return f.jtis[jti] || (ok && before.Truncate(time.Second).Add(time.Second).After(issuedAt)), nil
+}
+
+func (f *fakeStorage) SaveRefreshToken(_ context.Context, t RefreshToken) error {
+	f.refresh[t.TokenHash] = t
+	return nil
+}
+
+func (f *fakeStorage) FindRefreshToken(_ context.Context, tokenHash string) (RefreshToken, error) {
+	t, ok := f.refresh[tokenHash]
+	if !ok {
+		return RefreshToken{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (f *fakeStorage) RotateRefreshToken(_ context.Context, oldID string, next RefreshToken) error {
+	for hash, t := range f.refresh {
+		if t.ID != oldID {
+			continue
+		}
+		if t.IsUsed() || t.IsRevoked() {
+			return ErrAlreadyUsed
+		}
+		t.UsedAt = next.CreatedAt
+		f.refresh[hash] = t
+	}
+	f.refresh[next.TokenHash] = next
+	return nil
+}
+
+func (f *fakeStorage) RevokeRefreshTokenFamily(_ context.Context, familyID string) error {
+	for hash, t := range f.refresh {
+		if t.FamilyID == familyID {
+			t.RevokedAt = time.Now()
+			f.refresh[hash] = t
+		}
+	}
+	return nil
+}
+
+func (f *fakeStorage) RevokeUserRefreshTokens(_ context.Context, userID string) error {
+	for hash, t := range f.refresh {
+		if t.UserID == userID {
+			t.RevokedAt = time.Now()
+			f.refresh[hash] = t
+		}
+	}
+	return nil
+}
+
+// fakeApps are apps by ID
+type fakeApps map[int32]App
+
+func (f fakeApps) FindApp(_ context.Context, id int32) (App, error) {
+	a, ok := f[id]
+	if !ok {
+		return App{}, ErrNotFound
+	}
+	return a, nil
+}
+
+func newManager(t *testing.T) *Manager {
+	return newManagerWithApps(t, fakeApps{
+		webApp.ID: webApp,
+		cliApp.ID: cliApp,
+	})
+}
+
+func newManagerWithApps(t *testing.T, apps Apps) *Manager {
+	kr, err := jwt.NewKeyring(jwt.NewHMACKey("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(&fakeStorage{
+		jtis:    map[string]bool{},
+		users:   map[string]time.Time{},
+		refresh: map[string]RefreshToken{},
+	}, apps, kr, time.Hour, time.Hour)
+}
+
+var (
+	webApp  = App{ID: 1, Name: "web"}
+	cliApp  = App{ID: 2, Name: "cli", Secret: "cli-secret", TokenTTL: time.Minute}
+	subject = Subject{UserID: "user-id", Email: "user1@example.org"}
+)
+
+func TestManager_Revoke(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	revoked, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Revoke(ctx, revoked.Claims, revoked.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Validate(ctx, revoked.Token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	if _, err := m.Refresh(ctx, revoked.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	if _, err := m.Validate(ctx, other.Token); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestManager_RevokeAll(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	pair, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherUser, err := m.Issue(ctx, Subject{UserID: "other-id", Email: "user2@example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.RevokeAll(ctx, subject.UserID); err != nil {
+		t.Fatal(err)
+	}
+
+	// user logs in again right after revocation
+	relogin, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Validate(ctx, pair.Token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Validate() error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	if _, err := m.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	if _, err := m.Validate(ctx, otherUser.Token); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+
+	if _, err := m.Validate(ctx, relogin.Token); err != nil {
+		t.Errorf("Validate() of token issued after revocation error = %v, want nil", err)
+	}
+}
+
+func TestManager_RevokeAllInSameSecond(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	// token is issued and revoked in the same second, so its iat equals second of revocation
+	var pair Pair
+	for {
+		var err error
+		if pair, err = m.Issue(ctx, subject); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.RevokeAll(ctx, subject.UserID); err != nil {
+			t.Fatal(err)
+		}
+		if time.Now().Unix() == pair.Claims.IssuedAt.Unix() {
+			break
+		}
+	}
+
+	if _, err := m.Validate(ctx, pair.Token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Validate() of token issued in second of revocation error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	// new login waits for the next second, so its token isn't revoked
+	relogin, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !relogin.Claims.IssuedAt.After(pair.Claims.IssuedAt) {
+		t.Errorf("Issue() iat = %v, want after %v", relogin.Claims.IssuedAt, pair.Claims.IssuedAt)
+	}
+	if _, err := m.Validate(ctx, relogin.Token); err != nil {
+		t.Errorf("Validate() of token issued after revocation error = %v, want nil", err)
+	}
+}
+
+func TestManager_Refresh(t *testing.T) {
+	ctx := context.Background()
+
+	// expire makes stored form of refresh token expired
+	expire := func(m *Manager, refreshToken string) {
+		f := m.storage.(*fakeStorage)
+		rt := f.refresh[securetoken.Hash(refreshToken)]
+		rt.ExpiresAt = time.Now().Add(-time.Second)
+		f.refresh[rt.TokenHash] = rt
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(m *Manager, p Pair) string // returns refresh token to present
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			prepare: func(_ *Manager, p Pair) string { return p.RefreshToken },
+		},
+		{
+			name:    "unknown",
+			prepare: func(_ *Manager, _ Pair) string { return "unknown" },
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "expired",
+			prepare: func(m *Manager, p Pair) string {
+				expire(m, p.RefreshToken)
+				return p.RefreshToken
+			},
+			wantErr: ErrRefreshTokenExpired,
+		},
+		{
+			name: "revoked by logout",
+			prepare: func(m *Manager, p Pair) string {
+				if err := m.Revoke(ctx, p.Claims, p.RefreshToken); err != nil {
+					t.Fatal(err)
+				}
+				return p.RefreshToken
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "revoked by revoke all",
+			prepare: func(m *Manager, p Pair) string {
+				if err := m.RevokeAll(ctx, p.Claims.UserID); err != nil {
+					t.Fatal(err)
+				}
+				return p.RefreshToken
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(t)
+
+			p, err := m.Issue(ctx, subject)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = m.Refresh(ctx, tt.prepare(m, p))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Refresh() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_RefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	p, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{p.RefreshToken: true}
+
+	// every refresh returns new refresh token of the same family
+	for i := 0; i < 3; i++ {
+		next, err := m.Refresh(ctx, p.RefreshToken)
+		if err != nil {
+			t.Fatalf("Refresh() #%d error = %v", i, err)
+		}
+
+		if seen[next.RefreshToken] {
+			t.Errorf("Refresh() #%d returned refresh token issued before", i)
+		}
+		seen[next.RefreshToken] = true
+
+		if next.Claims.SessionID != p.Claims.SessionID {
+			t.Errorf("Refresh() #%d sid = %v, want %v", i, next.Claims.SessionID, p.Claims.SessionID)
+		}
+
+		if _, err := m.Validate(ctx, next.Token); err != nil {
+			t.Errorf("Validate() #%d error = %v, want nil", i, err)
+		}
+
+		p = next
+	}
+
+	// other logins of user aren't touched by reuse in this family
+	other, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Refresh(ctx, p.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Refresh(ctx, p.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	if _, err := m.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("Refresh() of other login error = %v, want nil", err)
+	}
+}
+
+func TestManager_Family(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	p, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	family, err := m.Family(ctx, p.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if family != p.Claims.SessionID {
+		t.Errorf("Family() = %v, want %v", family, p.Claims.SessionID)
+	}
+
+	// family is found without rotating refresh token
+	next, err := m.Refresh(ctx, p.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() after Family() error = %v", err)
+	}
+
+	if family, err := m.Family(ctx, next.RefreshToken); err != nil || family != p.Claims.SessionID {
+		t.Errorf("Family() of rotated token = %v, %v, want %v", family, err, p.Claims.SessionID)
+	}
+
+	if _, err := m.Family(ctx, "unknown"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Family() error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestManager_RefreshReuse(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	first, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := m.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.Claims.Email != subject.Email {
+		t.Errorf("Refresh() email = %v, want %v", second.Claims.Email, subject.Email)
+	}
+
+	if first.Claims.SessionID == "" || second.Claims.SessionID != first.Claims.SessionID {
+		t.Errorf("Refresh() sid = %v, want %v", second.Claims.SessionID, first.Claims.SessionID)
+	}
+
+	// first token is already used, whole family must be revoked
+	if _, err := m.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	if _, err := m.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+func TestManager_Apps(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t)
+
+	web, err := m.Issue(ctx, Subject{UserID: subject.UserID, Email: subject.Email, App: webApp})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli, err := m.Issue(ctx, Subject{UserID: subject.UserID, Email: subject.Email, App: cliApp})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cli.Claims.ExpiresAt.Sub(cli.Claims.IssuedAt); got != cliApp.TokenTTL {
+		t.Errorf("Issue() ttl = %v, want %v", got, cliApp.TokenTTL)
+	}
+
+	c, err := m.Validate(ctx, cli.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AppID != cliApp.ID || c.Audience != cliApp.Name {
+		t.Errorf("Validate() app = %v %v, want %v %v", c.AppID, c.Audience, cliApp.ID, cliApp.Name)
+	}
+
+	if err := CheckApp(c, webApp.ID); !errors.Is(err, ErrWrongApp) {
+		t.Errorf("CheckApp() error = %v, want %v", err, ErrWrongApp)
+	}
+
+	c, err = m.Validate(ctx, web.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckApp(c, webApp.ID); err != nil {
+		t.Errorf("CheckApp() error = %v, want nil", err)
+	}
+	if err := CheckApp(c, 0); !errors.Is(err, ErrWrongApp) {
+		t.Errorf("CheckApp() of zero app error = %v, want %v", err, ErrWrongApp)
+	}
+
+	// app secret can't be used to sign tokens of other apps
+	forged := jwt.NewClaims(subject.UserID, subject.Email, time.Hour)
+	forged.AppID = webApp.ID
+	token, err := jwt.Sign(forged, appKey(cliApp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Validate(ctx, token); !errors.Is(err, jwt.ErrInvalidSignature) {
+		t.Errorf("Validate() error = %v, want %v", err, jwt.ErrInvalidSignature)
+	}
+
+	refreshed, err := m.Refresh(ctx, cli.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Claims.AppID != cliApp.ID {
+		t.Errorf("Refresh() app = %v, want %v", refreshed.Claims.AppID, cliApp.ID)
+	}
+}
+
+func TestManager_WithoutApps(t *testing.T) {
+	ctx := context.Background()
+	m := newManagerWithApps(t, nil)
+
+	app := App{ID: 3, Name: "internal", TokenTTL: time.Minute}
+
+	p, err := m.Issue(ctx, Subject{UserID: subject.UserID, Email: subject.Email, App: app})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// app isn't looked up, it's kept in refresh token
+	refreshed, err := m.Refresh(ctx, p.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := refreshed.Claims
+	if c.AppID != app.ID || c.Audience != app.Name || c.ExpiresAt.Sub(c.IssuedAt) != app.TokenTTL {
+		t.Errorf("Refresh() claims = %+v, want app %+v", c, app)
+	}
+
+	// app secrets aren't known without apps
+	token, err := jwt.Sign(jwt.NewClaims(subject.UserID, subject.Email, time.Hour), appKey(cliApp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Validate(ctx, token); err == nil {
+		t.Error("Validate() of token signed with app secret error = nil")
+	}
+}
@@ -18,7 +18,7 @@ func main() {
 	cfg := config.MustGetConfig()
 	log := setupLogger(cfg.Env)
 	log.Debug("config readed, log configured")
-	s := server.MustNew(log, cfg.Services.Files, cfg.Services.Users, cfg.Timeout)
+	s := server.MustNew(log, cfg.Services.Files, cfg.Services.Users, cfg.Timeout, cfg.AppID, cfg.VerifyOffline)
 
 	err := s.Run(cfg.Addr)
 	if err != nil {
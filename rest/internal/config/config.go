@@ -13,6 +13,10 @@ type Config struct {
 	Addr     string        `yaml:"addr" env-required:"true"`
 	Services ServicesAddrs `yaml:"services" env-required:"true"`
 	Timeout  time.Duration `yaml:"timeout" env-default:"15s"`
+	AppID    int32         `yaml:"app_id" env-default:"1"`
+	// VerifyOffline makes gateway verify auth tokens with public keys of user service instead of calling it,
+	// revoked tokens and tokens of disabled users are accepted until they expire then
+	VerifyOffline bool `yaml:"verify_offline"`
 }
 
 type ServicesAddrs struct {
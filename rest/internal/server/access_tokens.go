@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"rest_grpc/pb/users"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type accessToken struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	Hint       string   `json:"hint"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"`
+	LastUsedAt int64    `json:"last_used_at,omitempty"`
+	CreatedAt  int64    `json:"created_at"`
+}
+
+func accessTokenFromPb(t *users.AccessToken) accessToken {
+	return accessToken{
+		ID:         t.GetId(),
+		Name:       t.GetName(),
+		Scopes:     t.GetScopes(),
+		Hint:       t.GetHint(),
+		ExpiresAt:  t.GetExpiresAt(),
+		LastUsedAt: t.GetLastUsedAt(),
+		CreatedAt:  t.GetCreatedAt(),
+	}
+}
+
+// CreateAccessToken creates personal access token, e.g. {"name": "ci", "scopes": ["files:write"]}.
+// Token is in the response only, it should be passed as Bearer token to /files routes.
+func (s *Server) CreateAccessToken() http.HandlerFunc {
+	type request struct {
+		Name      string   `json:"name" validate:"required"`
+		Scopes    []string `json:"scopes" validate:"required,min=1"`
+		ExpiresAt int64    `json:"expires_at"` // unix time, token never expires if it's omitted
+	}
+
+	type response struct {
+		Response
+		AccessToken string       `json:"access_token,omitempty"`
+		Details     *accessToken `json:"details,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.uCl.CreateAccessToken(ctx, &users.CreateAccessTokenRequest{
+			Token:     token,
+			Name:      req.Name,
+			Scopes:    req.Scopes,
+			ExpiresAt: req.ExpiresAt,
+			AppId:     s.AppID,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		details := accessTokenFromPb(res.GetDetails())
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			AccessToken: res.GetAccessToken(),
+			Details:     &details,
+		})
+	}
+}
+
+func (s *Server) ListAccessTokens() http.HandlerFunc {
+	type response struct {
+		Response
+		AccessTokens []accessToken `json:"access_tokens,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		res, err := s.uCl.ListAccessTokens(ctx, &users.ListAccessTokensRequest{Token: token, AppId: s.AppID})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		list := make([]accessToken, 0, len(res.GetAccessTokens()))
+		for _, t := range res.GetAccessTokens() {
+			list = append(list, accessTokenFromPb(t))
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			AccessTokens: list,
+		})
+	}
+}
+
+func (s *Server) RevokeAccessToken() http.HandlerFunc {
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		_, err := s.uCl.RevokeAccessToken(ctx, &users.RevokeAccessTokenRequest{
+			Token:         token,
+			AccessTokenId: chi.URLParam(r, "id"),
+			AppId:         s.AppID,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"rest_grpc/pb/admin"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type adminUser struct {
+	UserID        string `json:"user_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	DisplayName   string `json:"display_name"`
+	CreatedAt     int64  `json:"created_at"`
+	Status        string `json:"status"`
+	DisabledAt    int64  `json:"disabled_at,omitempty"`
+}
+
+func adminUserFromPb(u *admin.User) adminUser {
+	return adminUser{
+		UserID:        u.GetUserId(),
+		Email:         u.GetEmail(),
+		EmailVerified: u.GetEmailVerified(),
+		DisplayName:   u.GetDisplayName(),
+		CreatedAt:     u.GetCreatedAt(),
+		Status:        u.GetStatus(),
+		DisabledAt:    u.GetDisabledAt(),
+	}
+}
+
+// ListUsers returns page of users. Query parameters: email (prefix), status,
+// created_after and created_before (RFC 3339), page_size and cursor (next_cursor of previous page).
+func (s *Server) ListUsers() http.HandlerFunc {
+	type response struct {
+		Response
+		Users      []adminUser `json:"users,omitempty"`
+		NextCursor string      `json:"next_cursor,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		q := r.URL.Query()
+		req := &admin.ListUsersRequest{
+			Token:       token,
+			EmailPrefix: q.Get("email"),
+			Status:      q.Get("status"),
+			Cursor:      q.Get("cursor"),
+			AppId:       s.AppID,
+		}
+
+		var err error
+		req.CreatedAfter, err = unixQueryParam(q.Get("created_after"))
+		if err == nil {
+			req.CreatedBefore, err = unixQueryParam(q.Get("created_before"))
+		}
+		if err == nil && q.Get("page_size") != "" {
+			var n int
+			n, err = strconv.Atoi(q.Get("page_size"))
+			req.PageSize = int32(n)
+		}
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.adCl.ListUsers(ctx, req)
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		users := make([]adminUser, 0, len(res.GetUsers()))
+		for _, u := range res.GetUsers() {
+			users = append(users, adminUserFromPb(u))
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Users:      users,
+			NextCursor: res.GetNextCursor(),
+		})
+	}
+}
+
+// unixQueryParam returns unix time of RFC 3339 time, zero if v is empty
+func unixQueryParam(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Unix(), nil
+}
+
+func (s *Server) GetUser() http.HandlerFunc {
+	type response struct {
+		Response
+		User  *adminUser `json:"user,omitempty"`
+		Roles []string   `json:"roles,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		res, err := s.adCl.GetUser(ctx, &admin.GetUserRequest{Token: token, UserId: chi.URLParam(r, "id"), AppId: s.AppID})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		u := adminUserFromPb(res.GetUser())
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			User:  &u,
+			Roles: res.GetRoles(),
+		})
+	}
+}
+
+// DisableUser forbids login of user and signs him out everywhere
+func (s *Server) DisableUser() http.HandlerFunc {
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		_, err := s.adCl.DisableUser(ctx, &admin.DisableUserRequest{Token: token, UserId: chi.URLParam(r, "id"), AppId: s.AppID})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
+
+func (s *Server) EnableUser() http.HandlerFunc {
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		_, err := s.adCl.EnableUser(ctx, &admin.EnableUserRequest{Token: token, UserId: chi.URLParam(r, "id"), AppId: s.AppID})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
+
+// SetUserRoles replaces roles of user, e.g. {"roles": ["user", "admin"]} promotes user to admin
+func (s *Server) SetUserRoles() http.HandlerFunc {
+	type request struct {
+		Roles []string `json:"roles" validate:"required"`
+	}
+
+	type response struct {
+		Response
+		Roles []string `json:"roles,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.adCl.SetUserRoles(ctx, &admin.SetUserRolesRequest{
+			Token:  token,
+			UserId: chi.URLParam(r, "id"),
+			Roles:  req.Roles,
+			AppId:  s.AppID,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Roles: res.GetRoles(),
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"rest_grpc/pb/admin"
+	"strconv"
+
+	"github.com/go-chi/render"
+)
+
+type auditEvent struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Outcome   string `json:"outcome"`
+	ActorID   string `json:"actor_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	Email     string `json:"email,omitempty"`
+	AppID     int32  `json:"app_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Details   string `json:"details,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func auditEventFromPb(e *admin.AuditEvent) auditEvent {
+	return auditEvent{
+		ID:        e.GetId(),
+		Type:      e.GetType(),
+		Outcome:   e.GetOutcome(),
+		ActorID:   e.GetActorId(),
+		UserID:    e.GetUserId(),
+		Email:     e.GetEmail(),
+		AppID:     e.GetAppId(),
+		IP:        e.GetIp(),
+		UserAgent: e.GetUserAgent(),
+		Details:   e.GetDetails(),
+		CreatedAt: e.GetCreatedAt(),
+	}
+}
+
+// ListAuditEvents returns page of audit log, the newest events first. Query parameters: type, outcome,
+// actor_id, user_id, ip, created_after and created_before (RFC 3339), page_size and cursor.
+func (s *Server) ListAuditEvents() http.HandlerFunc {
+	type response struct {
+		Response
+		Events     []auditEvent `json:"events,omitempty"`
+		NextCursor string       `json:"next_cursor,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		q := r.URL.Query()
+		req := &admin.ListAuditEventsRequest{
+			Token:   token,
+			Type:    q.Get("type"),
+			Outcome: q.Get("outcome"),
+			ActorId: q.Get("actor_id"),
+			UserId:  q.Get("user_id"),
+			Ip:      q.Get("ip"),
+			Cursor:  q.Get("cursor"),
+			AppId:   s.AppID,
+		}
+
+		var err error
+		req.CreatedAfter, err = unixQueryParam(q.Get("created_after"))
+		if err == nil {
+			req.CreatedBefore, err = unixQueryParam(q.Get("created_before"))
+		}
+		if err == nil && q.Get("page_size") != "" {
+			var n int
+			n, err = strconv.Atoi(q.Get("page_size"))
+			req.PageSize = int32(n)
+		}
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.adCl.ListAuditEvents(ctx, req)
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		events := make([]auditEvent, 0, len(res.GetEvents()))
+		for _, e := range res.GetEvents() {
+			events = append(events, auditEventFromPb(e))
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Events:     events,
+			NextCursor: res.GetNextCursor(),
+		})
+	}
+}
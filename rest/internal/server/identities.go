@@ -0,0 +1,349 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"rest_grpc/pb/auth"
+	"rest_grpc/pb/users"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// oidcStateCookie binds login with identity provider to browser that started it,
+// callback with state of another browser is rejected, so nobody can log user into
+// account of attacker
+const oidcStateCookie = "oidc_state"
+
+// oidcStateTTL is lifetime of state cookie, user service checks expiration of state anyway
+const oidcStateTTL = 10 * time.Minute
+
+type identity struct {
+	ID        string `json:"id"`
+	Provider  string `json:"provider"`
+	Email     string `json:"email,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func identityFromPb(id *users.Identity) identity {
+	return identity{
+		ID:        id.GetId(),
+		Provider:  id.GetProvider(),
+		Email:     id.GetEmail(),
+		CreatedAt: id.GetCreatedAt(),
+	}
+}
+
+// StartOIDCLogin redirects user to identity provider, e.g. /users/oidc/google/start?app_id=1
+func (s *Server) StartOIDCLogin() http.HandlerFunc {
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		appID, err := strconv.Atoi(r.URL.Query().Get("app_id"))
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.aCl.StartOIDCLogin(ctx, &auth.StartOIDCLoginRequest{
+			Provider: chi.URLParam(r, "provider"),
+			AppId:    int32(appID),
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		setOIDCState(w, r, res.GetState())
+		http.Redirect(w, r, res.GetAuthorizationUrl(), http.StatusFound)
+	}
+}
+
+// OIDCCallback finishes login with identity provider. User is logged in as with /users/login,
+// or identity is linked to account if login was started with /users/me/identities/{provider}.
+func (s *Server) OIDCCallback() http.HandlerFunc {
+	type response struct {
+		Response
+		Token        string `json:"token,omitempty"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		// MFARequired is set if user has to pass MFAToken and code to /users/mfa/verify
+		MFARequired bool   `json:"mfa_required,omitempty"`
+		MFAToken    string `json:"mfa_token,omitempty"`
+		Created     bool   `json:"created,omitempty"`
+		Linked      bool   `json:"linked,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		q := r.URL.Query()
+		state, code := q.Get("state"), q.Get("code")
+
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid state",
+				},
+			})
+			return
+		}
+
+		setOIDCState(w, r, "")
+
+		// provider redirects with error if user didn't allow access
+		if e := q.Get("error"); e != "" || code == "" {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "identity provider rejected login",
+				},
+			})
+			return
+		}
+
+		// user service records client in session
+		ctx = forwardClient(ctx, r)
+
+		res, err := s.aCl.FinishOIDCLogin(ctx, &auth.FinishOIDCLoginRequest{
+			State: state,
+			Code:  code,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		login := res.GetLogin()
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Token:        login.GetToken(),
+			RefreshToken: login.GetRefreshToken(),
+			MFARequired:  login.GetMfaRequired(),
+			MFAToken:     login.GetMfaToken(),
+			Created:      res.GetCreated(),
+			Linked:       res.GetLinked(),
+		})
+	}
+}
+
+// LinkIdentity starts linking identity of provider to account, client should open
+// AuthorizationURL in the same browser, so state cookie is passed to callback
+func (s *Server) LinkIdentity() http.HandlerFunc {
+	type response struct {
+		Response
+		AuthorizationURL string `json:"authorization_url,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		res, err := s.aCl.StartOIDCLogin(ctx, &auth.StartOIDCLoginRequest{
+			Provider: chi.URLParam(r, "provider"),
+			Token:    token,
+			AppId:    s.AppID,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		setOIDCState(w, r, res.GetState())
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			AuthorizationURL: res.GetAuthorizationUrl(),
+		})
+	}
+}
+
+func (s *Server) ListIdentities() http.HandlerFunc {
+	type response struct {
+		Response
+		Identities []identity `json:"identities,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		res, err := s.uCl.ListIdentities(ctx, &users.ListIdentitiesRequest{Token: token, AppId: s.AppID})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		list := make([]identity, 0, len(res.GetIdentities()))
+		for _, id := range res.GetIdentities() {
+			list = append(list, identityFromPb(id))
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Identities: list,
+		})
+	}
+}
+
+func (s *Server) UnlinkIdentity() http.HandlerFunc {
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		_, err := s.uCl.UnlinkIdentity(ctx, &users.UnlinkIdentityRequest{
+			Token:      token,
+			IdentityId: chi.URLParam(r, "id"),
+			AppId:      s.AppID,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
+
+// setOIDCState sets state cookie, empty state removes it. Lax cookie is sent with
+// top-level redirect back from provider.
+func setOIDCState(w http.ResponseWriter, r *http.Request, state string) {
+	maxAge := int(oidcStateTTL.Seconds())
+	if state == "" {
+		maxAge = -1
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/users",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"rest_grpc/pb/auth"
+	"strconv"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// VerifyMFA is the second step of login of users with MFA,
+// it exchanges mfa_token of /users/login and code of authenticator for tokens
+func (s *Server) VerifyMFA() http.HandlerFunc {
+	type request struct {
+		MFAToken string `json:"mfa_token" validate:"required"`
+		Code     string `json:"code" validate:"required"`
+	}
+
+	type response struct {
+		Response
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		// user service counts failed codes per client IP and records client in session
+		ctx = forwardClient(ctx, r)
+
+		res, err := s.aCl.VerifyMFA(ctx, &auth.VerifyMFARequest{
+			MfaToken: req.MFAToken,
+			Code:     req.Code,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+			if lCode, lMsg, retryAfter, ok := lockout(err); ok {
+				code, msg = lCode, lMsg
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			}
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Token:        res.GetToken(),
+			RefreshToken: res.GetRefreshToken(),
+		})
+	}
+}
+
+// EnrollMFA starts enrollment of authenticator, it has to be confirmed with /users/mfa/confirm
+func (s *Server) EnrollMFA() http.HandlerFunc {
+	type response struct {
+		Response
+		Secret          string `json:"secret"`
+		ProvisioningURI string `json:"provisioning_uri"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		res, err := s.aCl.EnrollMFA(ctx, &auth.EnrollMFARequest{Token: token, AppId: s.AppID})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Secret:          res.GetSecret(),
+			ProvisioningURI: res.GetProvisioningUri(),
+		})
+	}
+}
+
+// ConfirmMFA enables enrolled authenticator, recovery codes are returned only once
+func (s *Server) ConfirmMFA() http.HandlerFunc {
+	type request struct {
+		Code string `json:"code" validate:"required"`
+	}
+
+	type response struct {
+		Response
+		RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.aCl.ConfirmMFA(ctx, &auth.ConfirmMFARequest{Token: token, Code: req.Code, AppId: s.AppID})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			RecoveryCodes: res.GetRecoveryCodes(),
+		})
+	}
+}
+
+// DisableMFA removes authenticator, code of authenticator or recovery code is required
+func (s *Server) DisableMFA() http.HandlerFunc {
+	type request struct {
+		Code string `json:"code" validate:"required"`
+	}
+
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		if _, err := s.aCl.DisableMFA(ctx, &auth.DisableMFARequest{Token: token, Code: req.Code, AppId: s.AppID}); err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
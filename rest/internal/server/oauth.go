@@ -0,0 +1,512 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"rest_grpc/pb/auth"
+	"rest_grpc/pb/oauth"
+	"rest_grpc/utils"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// oauthDomain is domain of ErrorInfo details of OAuth errors of user service
+const oauthDomain = "oauth"
+
+// codeChallengeS256 is the only PKCE method user service supports
+const codeChallengeS256 = "S256"
+
+// consentPage asks user to log in and approve access of client. Password is asked
+// every time, so there is no session of the gateway to steal with CSRF.
+var consentPage = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Authorize {{.ClientName}}</title>
+</head>
+<body>
+<h1>{{.ClientName}} wants to access your account</h1>
+<p>It will be allowed to:</p>
+<ul>
+{{range .Scopes}}<li>{{.}}</li>
+{{end}}</ul>
+{{if .Error}}<p style="color: red">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+<input type="hidden" name="client_id" value="{{.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Scope}}">
+<input type="hidden" name="state" value="{{.State}}">
+<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.CodeChallengeMethod}}">
+{{if .MFAToken}}<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+<p><label>Code of authenticator or recovery code <input name="code" autocomplete="one-time-code" required></label></p>
+{{else}}<p><label>Email <input type="email" name="email" autocomplete="username" required></label></p>
+<p><label>Password <input type="password" name="password" autocomplete="current-password" required></label></p>
+{{end}}<button type="submit" name="action" value="approve">Approve</button>
+<button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+</form>
+</body>
+</html>
+`))
+
+// consent is data of consentPage, fields of authorization request are passed back in the form
+type consent struct {
+	ClientName          string
+	Scopes              []string
+	Error               string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	MFAToken            string // set when user has to finish login with code of authenticator
+}
+
+// consentFromForm returns consent with fields of authorization request in query or form
+func consentFromForm(form url.Values) consent {
+	return consent{
+		ClientID:            form.Get("client_id"),
+		RedirectURI:         form.Get("redirect_uri"),
+		Scope:               form.Get("scope"),
+		State:               form.Get("state"),
+		CodeChallenge:       form.Get("code_challenge"),
+		CodeChallengeMethod: form.Get("code_challenge_method"),
+	}
+}
+
+// RegisterOAuthClient registers OAuth client of the token owner,
+// e.g. {"name": "backup", "redirect_uris": ["https://backup.example/callback"], "confidential": true}.
+// Client secret is in the response only.
+func (s *Server) RegisterOAuthClient() http.HandlerFunc {
+	type request struct {
+		Name         string   `json:"name" validate:"required"`
+		RedirectURIs []string `json:"redirect_uris" validate:"required,min=1"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	type response struct {
+		Response
+		ClientID     string `json:"client_id,omitempty"`
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token, ok := bearerToken(r)
+		if !ok {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusUnauthorized,
+					Ok:         "",
+					Error:      "you need to pass token",
+				},
+			})
+			return
+		}
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.oCl.RegisterClient(ctx, &oauth.RegisterClientRequest{
+			Token:        token,
+			Name:         req.Name,
+			RedirectUris: req.RedirectURIs,
+			Confidential: req.Confidential,
+			AppId:        s.AppID,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			ClientID:     res.GetClientId(),
+			ClientSecret: res.GetClientSecret(),
+		})
+	}
+}
+
+// Authorize shows consent page for authorization request of client, see RFC 6749 4.1.1.
+// Errors are redirected to client only if client and redirect_uri are valid.
+func (s *Server) Authorize() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		c := consentFromForm(r.URL.Query())
+		if c.ClientID == "" || c.RedirectURI == "" {
+			http.Error(w, "client_id and redirect_uri are required", http.StatusBadRequest)
+			return
+		}
+
+		res, err := s.oCl.GetConsent(ctx, &oauth.GetConsentRequest{
+			ClientId:    c.ClientID,
+			RedirectUri: c.RedirectURI,
+			Scope:       c.Scope,
+		})
+		if err != nil {
+			s.authorizeError(w, r, c, err)
+			return
+		}
+
+		if r.URL.Query().Get("response_type") != "code" {
+			redirectWithParams(w, r, c.RedirectURI, url.Values{
+				"error": {"unsupported_response_type"},
+				"state": {c.State},
+			})
+			return
+		}
+
+		// public clients are protected only by PKCE, so it's checked before user logs in
+		if c.CodeChallenge == "" || c.CodeChallengeMethod != codeChallengeS256 {
+			redirectWithParams(w, r, c.RedirectURI, url.Values{
+				"error":             {"invalid_request"},
+				"error_description": {"S256 code_challenge is required"},
+				"state":             {c.State},
+			})
+			return
+		}
+
+		c.ClientName = res.GetClientName()
+		c.Scopes = res.GetScopes()
+
+		s.renderConsent(w, http.StatusOK, c)
+	}
+}
+
+// Approve handles consent form: user is logged in, client gets authorization code
+// and login is ended, it was needed only to authorize client.
+func (s *Server) Approve() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+
+		c := consentFromForm(r.PostForm)
+
+		// request is checked again, hidden fields could be changed
+		consentRes, err := s.oCl.GetConsent(ctx, &oauth.GetConsentRequest{
+			ClientId:    c.ClientID,
+			RedirectUri: c.RedirectURI,
+			Scope:       c.Scope,
+		})
+		if err != nil {
+			s.authorizeError(w, r, c, err)
+			return
+		}
+		c.ClientName = consentRes.GetClientName()
+		c.Scopes = consentRes.GetScopes()
+
+		if r.PostForm.Get("action") != "approve" {
+			redirectWithParams(w, r, c.RedirectURI, url.Values{
+				"error": {"access_denied"},
+				"state": {c.State},
+			})
+			return
+		}
+
+		// user service counts failed logins per client IP
+		ctx = forwardClient(ctx, r)
+
+		var login *auth.LoginResponse
+		if mfaToken := r.PostForm.Get("mfa_token"); mfaToken != "" {
+			login, err = s.aCl.VerifyMFA(ctx, &auth.VerifyMFARequest{
+				MfaToken: mfaToken,
+				Code:     r.PostForm.Get("code"),
+			})
+			c.MFAToken = mfaToken
+		} else {
+			login, err = s.aCl.Login(ctx, &auth.LoginRequest{
+				Email:    r.PostForm.Get("email"),
+				Password: r.PostForm.Get("password"),
+				AppId:    s.AppID,
+			})
+		}
+		if err != nil {
+			code, msg := httpStatus(err)
+			if lCode, lMsg, _, ok := lockout(err); ok {
+				code, msg = lCode, lMsg
+			} else if code == http.StatusUnauthorized || code == http.StatusBadRequest {
+				msg = "invalid credentials"
+			}
+
+			c.Error = msg
+			s.renderConsent(w, code, c)
+			return
+		}
+
+		if login.GetMfaRequired() {
+			c.MFAToken = login.GetMfaToken()
+			s.renderConsent(w, http.StatusOK, c)
+			return
+		}
+
+		res, err := s.oCl.Authorize(ctx, &oauth.AuthorizeRequest{
+			Token:               login.GetToken(),
+			ClientId:            c.ClientID,
+			RedirectUri:         c.RedirectURI,
+			Scope:               c.Scope,
+			CodeChallenge:       c.CodeChallenge,
+			CodeChallengeMethod: c.CodeChallengeMethod,
+			AppId:               s.AppID,
+		})
+
+		_, lErr := s.aCl.Logout(ctx, &auth.LogoutRequest{
+			Token:        login.GetToken(),
+			RefreshToken: login.GetRefreshToken(),
+			AppId:        s.AppID,
+		})
+		if lErr != nil {
+			s.l.Error(utils.WrapErr("error in Logout", lErr))
+		}
+
+		if err != nil {
+			s.authorizeError(w, r, c, err)
+			return
+		}
+
+		redirectWithParams(w, r, c.RedirectURI, url.Values{
+			"code":  {res.GetCode()},
+			"state": {c.State},
+		})
+	}
+}
+
+// Token is token endpoint, see RFC 6749 3.2. Client credentials are passed
+// with Basic authentication or in form.
+func (s *Server) Token() http.HandlerFunc {
+	type response struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		Scope        string `json:"scope"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		if err := r.ParseForm(); err != nil {
+			s.oauthError(w, r, status.Error(codes.InvalidArgument, "invalid form"))
+			return
+		}
+
+		clientID, clientSecret, ok := clientCredentials(r)
+		if !ok {
+			s.oauthError(w, r, status.Error(codes.InvalidArgument, "invalid client credentials"))
+			return
+		}
+
+		res, err := s.oCl.Token(ctx, &oauth.TokenRequest{
+			GrantType:    r.PostForm.Get("grant_type"),
+			ClientId:     clientID,
+			ClientSecret: clientSecret,
+			Code:         r.PostForm.Get("code"),
+			RedirectUri:  r.PostForm.Get("redirect_uri"),
+			CodeVerifier: r.PostForm.Get("code_verifier"),
+			RefreshToken: r.PostForm.Get("refresh_token"),
+		})
+		if err != nil {
+			s.oauthError(w, r, err)
+			return
+		}
+
+		render.JSON(w, r, response{
+			AccessToken:  res.GetAccessToken(),
+			TokenType:    res.GetTokenType(),
+			ExpiresIn:    res.GetExpiresIn(),
+			RefreshToken: res.GetRefreshToken(),
+			Scope:        res.GetScope(),
+		})
+	}
+}
+
+// Revoke is revocation endpoint, see RFC 7009. Unknown tokens aren't an error.
+func (s *Server) Revoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		if err := r.ParseForm(); err != nil {
+			s.oauthError(w, r, status.Error(codes.InvalidArgument, "invalid form"))
+			return
+		}
+
+		clientID, clientSecret, ok := clientCredentials(r)
+		if !ok {
+			s.oauthError(w, r, status.Error(codes.InvalidArgument, "invalid client credentials"))
+			return
+		}
+
+		_, err := s.oCl.Revoke(ctx, &oauth.RevokeRequest{
+			ClientId:     clientID,
+			ClientSecret: clientSecret,
+			Token:        r.PostForm.Get("token"),
+		})
+		if err != nil {
+			s.oauthError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// clientCredentials returns client credentials of Basic authentication or form,
+// Basic credentials are form encoded, see RFC 6749 2.3.1
+func clientCredentials(r *http.Request) (string, string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), true
+	}
+
+	id, err := url.QueryUnescape(id)
+	if err != nil {
+		return "", "", false
+	}
+
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+// oauthCode returns OAuth error code of ErrorInfo detail of user service error
+func oauthCode(err error) (string, bool) {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == oauthDomain {
+			return info.GetReason(), true
+		}
+	}
+	return "", false
+}
+
+// oauthError writes error response of token and revocation endpoints, see RFC 6749 5.2
+func (s *Server) oauthError(w http.ResponseWriter, r *http.Request, err error) {
+	type response struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}
+
+	st := status.Convert(err)
+
+	code, ok := oauthCode(err)
+	if !ok {
+		code = "invalid_request"
+		if st.Code() != codes.InvalidArgument {
+			s.l.Error(utils.WrapErr("error in OAuth", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response{Error: "server_error"})
+			return
+		}
+	}
+
+	httpCode := http.StatusBadRequest
+	if code == "invalid_client" {
+		httpCode = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+
+	render.Status(r, httpCode)
+	render.JSON(w, r, response{Error: code, ErrorDescription: st.Message()})
+}
+
+// authorizeError redirects error of authorization request to client, see RFC 6749 4.1.2.1.
+// Invalid client or redirect_uri is shown to user, redirect_uri can't be trusted.
+func (s *Server) authorizeError(w http.ResponseWriter, r *http.Request, c consent, err error) {
+	code, ok := oauthCode(err)
+	if !ok {
+		s.l.Error(utils.WrapErr("error in OAuth", err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if code == "invalid_request" && c.ClientName == "" {
+		http.Error(w, status.Convert(err).Message(), http.StatusBadRequest)
+		return
+	}
+
+	redirectWithParams(w, r, c.RedirectURI, url.Values{
+		"error":             {code},
+		"error_description": {status.Convert(err).Message()},
+		"state":             {c.State},
+	})
+}
+
+func (s *Server) renderConsent(w http.ResponseWriter, code int, c consent) {
+	// consent page can't be framed, user could be tricked into approving
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+
+	if err := consentPage.Execute(w, c); err != nil {
+		s.l.Error(utils.WrapErr("cant render consent page", err))
+	}
+}
+
+// redirectWithParams redirects to uri with params added to its query, empty params are skipped
+func redirectWithParams(w http.ResponseWriter, r *http.Request, uri string, params url.Values) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			q.Set(k, v[0])
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
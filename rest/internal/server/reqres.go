@@ -4,4 +4,6 @@ type Response struct {
 	StatusCode int    `json:"status_code"`
 	Ok         string `json:"ok"`
 	Error      string `json:"error"`
+	// Fields has reasons why fields of request are invalid
+	Fields map[string]string `json:"fields,omitempty"`
 }
@@ -3,22 +3,35 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"lib/jwt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"rest_grpc/pb/admin"
 	"rest_grpc/pb/auth"
 	"rest_grpc/pb/files"
+	"rest_grpc/pb/oauth"
+	"rest_grpc/pb/users"
 	"rest_grpc/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -30,14 +43,26 @@ type contextType int
 
 const (
 	ctxTokenKey contextType = iota
+	// ctxScopesKey keeps scopes of personal access token or OAuth token, it isn't set for auth tokens
+	ctxScopesKey
 )
 
+// scopeFilesWrite is permission personal access token or OAuth token needs to upload files
+const scopeFilesWrite = "files:write"
+
 type Server struct {
 	r          *chi.Mux
 	l          *slog.Logger
 	fCl        files.FilesClient
 	aCl        auth.AuthClient
+	uCl        users.UsersClient
+	adCl       admin.AdminClient
+	oCl        oauth.OAuthClient
 	CtxTimeout time.Duration
+	// AppID is app of the gateway, tokens of other apps are rejected
+	AppID int32
+	// keys verify auth tokens offline, nil if every token is verified by user service
+	keys *jwt.KeySet
 }
 
 func (s *Server) Run(addr string) error {
@@ -45,7 +70,9 @@ func (s *Server) Run(addr string) error {
 	return http.ListenAndServe(addr, s.r)
 }
 
-func MustNew(l *slog.Logger, filesAddr string, authAddr string, timeout time.Duration) *Server {
+// MustNew returns gateway of files and user services. If verifyOffline is true, auth tokens signed with
+// public keys of user service are verified by the gateway, so they are accepted until they expire even if revoked.
+func MustNew(l *slog.Logger, filesAddr string, authAddr string, timeout time.Duration, appID int32, verifyOffline bool) *Server {
 	r := chi.NewRouter()
 
 	var srv Server
@@ -66,11 +93,19 @@ func MustNew(l *slog.Logger, filesAddr string, authAddr string, timeout time.Dur
 
 	srv.fCl = files.NewFilesClient(conn)
 	srv.aCl = auth.NewAuthClient(conn2)
+	srv.uCl = users.NewUsersClient(conn2)
+	srv.adCl = admin.NewAdminClient(conn2)
+	srv.oCl = oauth.NewOAuthClient(conn2)
 
 	srv.l = l
 	srv.r = r
 
 	srv.CtxTimeout = timeout
+	srv.AppID = appID
+
+	if verifyOffline {
+		srv.keys = jwt.NewKeySet(srv.fetchJWKS)
+	}
 
 	return &srv
 }
@@ -80,17 +115,76 @@ func (s *Server) configureRouter(r *chi.Mux) {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
+	r.Get("/.well-known/jwks.json", s.JWKS())
+
 	r.Route("/users", func(r chi.Router) {
 		r.Use(middleware.AllowContentType("application/json"))
 
 		r.Post("/login", s.Login())
 		r.Post("/register", s.Register())
+		r.Post("/refresh", s.Refresh())
+		r.Get("/verify", s.VerifyEmail())
+		r.Post("/verify/resend", s.ResendVerification())
+		r.Post("/password/forgot", s.ForgotPassword())
+		r.Post("/password/reset", s.ResetPassword())
+		r.Get("/oidc/{provider}/start", s.StartOIDCLogin())
+		r.Get("/oidc/{provider}/callback", s.OIDCCallback())
+
+		r.Route("/me", func(r chi.Router) {
+			r.Get("/", s.GetMe())
+			r.Patch("/", s.UpdateProfile())
+			r.Delete("/", s.DeleteAccount())
+			r.Post("/email", s.ChangeEmail())
+			r.Post("/password", s.ChangePassword())
+			r.Get("/sessions", s.ListSessions())
+			r.Delete("/sessions/{id}", s.RevokeSession())
+			r.Get("/tokens", s.ListAccessTokens())
+			r.Post("/tokens", s.CreateAccessToken())
+			r.Delete("/tokens/{id}", s.RevokeAccessToken())
+			r.Get("/identities", s.ListIdentities())
+			r.Post("/identities/{provider}", s.LinkIdentity())
+			r.Delete("/identities/{id}", s.UnlinkIdentity())
+		})
+
+		r.Route("/mfa", func(r chi.Router) {
+			r.Post("/verify", s.VerifyMFA())
+			r.Post("/enroll", s.EnrollMFA())
+			r.Post("/confirm", s.ConfirmMFA())
+			r.Post("/disable", s.DisableMFA())
+		})
+	})
+
+	r.Route("/admin/users", func(r chi.Router) {
+		r.Use(middleware.AllowContentType("application/json"))
+
+		r.Get("/", s.ListUsers())
+		r.Get("/{id}", s.GetUser())
+		r.Post("/{id}/disable", s.DisableUser())
+		r.Post("/{id}/enable", s.EnableUser())
+		r.Put("/{id}/roles", s.SetUserRoles())
+	})
+
+	r.Get("/admin/audit", s.ListAuditEvents())
+
+	// OAuth 2.0 authorization server, token and revocation endpoints take forms as RFC 6749 requires
+	r.Route("/oauth", func(r chi.Router) {
+		r.With(middleware.AllowContentType("application/json")).Post("/clients", s.RegisterOAuthClient())
+		r.Get("/authorize", s.Authorize())
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AllowContentType("application/x-www-form-urlencoded"))
+
+			r.Post("/authorize", s.Approve())
+			r.Post("/token", s.Token())
+			r.Post("/revoke", s.Revoke())
+		})
 	})
 
 	r.Route("/files", func(r chi.Router) {
 		r.Use(middleware.AllowContentType("multipart/form-data", "application/json"))
+		r.Use(s.GetIDFromToken)
 
-		r.Post("/upload", s.Upload())
+		r.With(s.RequireScope(scopeFilesWrite)).Post("/upload", s.Upload())
 	})
 }
 
@@ -103,6 +197,7 @@ func (s *Server) GetFileBy() http.HandlerFunc {
 
 	type response struct {
 		Response
+		File *files.File `json:"file,omitempty"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -116,6 +211,7 @@ func (s *Server) GetFileBy() http.HandlerFunc {
 		json.NewDecoder(r.Body).Decode(&req)
 		err := validator.New().Struct(req)
 		if err != nil {
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -123,31 +219,43 @@ func (s *Server) GetFileBy() http.HandlerFunc {
 					Error:      "invalid data",
 				},
 			})
-
-			w.WriteHeader(400)
+			return
 		}
 
+		// files service takes the caller from token
+		ctx = forwardToken(ctx, r)
+
 		if req.ID != "" && req.Name == "" && req.UserID != "" {
 			res, err := s.fCl.GetFileById(ctx, &files.GetFileByIdRequest{
 				UserId: req.UserID,
 				Id:     req.ID,
 			})
 			if err != nil {
+				code, msg := httpStatus(err)
+
+				render.Status(r, code)
 				render.JSON(w, r, response{
 					Response: Response{
-						StatusCode: 500,
-						Error:      "internal error",
+						StatusCode: code,
+						Error:      msg,
 					},
 				})
-
-				w.WriteHeader(500)
 				return
 			}
+
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: 200,
+					Ok:         "ok",
+				},
+				File: res.GetFile(),
+			})
 		} else if req.Name != "" && req.ID == "" && req.UserID == "" {
 			// s.fCl.GetFilesByName()
 		} else if req.UserID != "" && req.Name == "" && req.ID == "" {
 			// s.fCl.GetFilesByUser()
 		} else { // all vals are empty
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -155,8 +263,7 @@ func (s *Server) GetFileBy() http.HandlerFunc {
 					Error:      "you need to pass name or id or user",
 				},
 			})
-
-			w.WriteHeader(400)
+			return
 		}
 	}
 }
@@ -180,6 +287,7 @@ func (s *Server) Upload() http.HandlerFunc {
 
 		err := r.ParseMultipartForm(maxMBSize << 20) // 10 MB max file size
 		if err != nil {
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -187,13 +295,12 @@ func (s *Server) Upload() http.HandlerFunc {
 					Error:      "cant get multipart form",
 				},
 			})
-
-			w.WriteHeader(400)
+			return
 		}
 
 		file, _, err := r.FormFile(requestMultipartFormFileName)
 		if err != nil {
-
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -201,14 +308,13 @@ func (s *Server) Upload() http.HandlerFunc {
 					Error:      "cant get your file",
 				},
 			})
-
-			w.WriteHeader(400)
+			return
 		}
 		defer file.Close()
 
 		bytes, err := io.ReadAll(file)
 		if err != nil {
-
+			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 500,
@@ -216,14 +322,14 @@ func (s *Server) Upload() http.HandlerFunc {
 					Error:      "cant read file",
 				},
 			})
-
-			w.WriteHeader(500) // idk if this 500 or 400
+			return
 		}
 
 		var req request
 		json.NewDecoder(r.Body).Decode(&req)
 		err = validator.New().Struct(req)
 		if err != nil {
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -231,15 +337,12 @@ func (s *Server) Upload() http.HandlerFunc {
 					Error:      "invalid data",
 				},
 			})
-
-			w.WriteHeader(400)
+			return
 		}
 
-		// getting id from middleware
-		userID := r.Context().Value(ctxTokenKey).(string)
-
 		t, err := time.Parse(req.CreatedAt, time.RFC3339)
 		if err != nil {
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -247,12 +350,13 @@ func (s *Server) Upload() http.HandlerFunc {
 					Error:      "you must pass created_at field in RFC3339 format",
 				},
 			})
-
-			w.WriteHeader(400)
+			return
 		}
 
+		// files service takes the owner of the file from token
+		ctx = forwardToken(ctx, r)
+
 		res, err := s.fCl.UploadFile(ctx, &files.UploadFileRequest{
-			UserId: userID,
 			File: &files.File{
 				Content:   bytes,
 				Name:      req.Name,
@@ -261,15 +365,17 @@ func (s *Server) Upload() http.HandlerFunc {
 		})
 
 		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
 			render.JSON(w, r, response{
 				Response: Response{
-					StatusCode: 500,
+					StatusCode: code,
 					Ok:         "",
-					Error:      "internal error",
+					Error:      msg,
 				},
 			})
-
-			w.WriteHeader(500)
+			return
 		}
 
 		render.JSON(w, r, response{
@@ -279,21 +385,23 @@ func (s *Server) Upload() http.HandlerFunc {
 			},
 			ID: res.GetFile().GetId(),
 		})
-
-		w.WriteHeader(200)
 	}
 }
 
 func (s *Server) Login() http.HandlerFunc {
 	type request struct {
-		Email    string `json:"email" validate:"required"`
-		Passowrd string `json:"password" validate:"required,email"`
+		Email    string `json:"email" validate:"required,email"`
+		Passowrd string `json:"password" validate:"required"`
 		AppID    int    `json:"app_id"`
 	}
 
 	type response struct {
 		Response
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		// MFARequired is set if user has to pass MFAToken and code to /users/mfa/verify
+		MFARequired bool   `json:"mfa_required,omitempty"`
+		MFAToken    string `json:"mfa_token,omitempty"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -304,6 +412,7 @@ func (s *Server) Login() http.HandlerFunc {
 		json.NewDecoder(r.Body).Decode(&req)
 		err := validator.New().Struct(req)
 		if err != nil {
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -312,9 +421,16 @@ func (s *Server) Login() http.HandlerFunc {
 				},
 				Token: "",
 			})
-
-			w.WriteHeader(400)
+			return
+		}
+		// user service checks origin against allowed origins of the app
+		if origin := r.Header.Get("Origin"); origin != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "origin", origin)
 		}
+
+		// user service counts failed logins per client IP and records client in session
+		ctx = forwardClient(ctx, r)
+
 		res, err := s.aCl.Login(ctx, &auth.LoginRequest{
 			Email:    req.Email,
 			Password: req.Passowrd,
@@ -322,16 +438,21 @@ func (s *Server) Login() http.HandlerFunc {
 		})
 
 		if err != nil {
+			code, msg := httpStatus(err)
+			if lCode, lMsg, retryAfter, ok := lockout(err); ok {
+				code, msg = lCode, lMsg
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			}
+
+			render.Status(r, code)
 			render.JSON(w, r, response{
 				Response: Response{
-					StatusCode: 500,
+					StatusCode: code,
 					Ok:         "",
-					Error:      "internal server error",
+					Error:      msg,
 				},
-				Token: "",
 			})
-
-			w.WriteHeader(500)
+			return
 		}
 
 		render.JSON(w, r, response{
@@ -339,21 +460,133 @@ func (s *Server) Login() http.HandlerFunc {
 				StatusCode: 200,
 				Ok:         "ok",
 			},
-			Token: res.GetToken(),
+			Token:        res.GetToken(),
+			RefreshToken: res.GetRefreshToken(),
+			MFARequired:  res.GetMfaRequired(),
+			MFAToken:     res.GetMfaToken(),
 		})
 	}
 }
 
+// lockout returns HTTP status of login lockout error of user service:
+// 423 if account is locked and 429 if client made too many attempts
+func lockout(err error) (code int, msg string, retryAfter time.Duration, ok bool) {
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		return 0, "", 0, false
+	}
+
+	code = http.StatusTooManyRequests
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			if d.GetReason() == "ACCOUNT_LOCKED" {
+				code = http.StatusLocked
+			}
+		case *errdetails.RetryInfo:
+			retryAfter = d.GetRetryDelay().AsDuration()
+		}
+	}
+
+	return code, st.Message(), retryAfter, true
+}
+
+// fieldViolations returns descriptions of invalid fields by their names
+// from BadRequest detail of InvalidArgument error of user service
+func fieldViolations(err error) (map[string]string, bool) {
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		return nil, false
+	}
+
+	fields := map[string]string{}
+	for _, d := range st.Details() {
+		br, ok := d.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, v := range br.GetFieldViolations() {
+			fields[v.GetField()] = v.GetDescription()
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	return fields, true
+}
+
+// forwardToken passes token of the user to files service, it authenticates the caller itself
+func forwardToken(ctx context.Context, r *http.Request) context.Context {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	}
+
+	return ctx
+}
+
+// forwardClient passes IP and user agent of the client to user service
+func forwardClient(ctx context.Context, r *http.Request) context.Context {
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-forwarded-for", ip)
+	}
+
+	if ua := r.UserAgent(); ua != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-forwarded-user-agent", ua)
+	}
+
+	return ctx
+}
+
+// bearerToken returns token of Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+// httpStatus returns HTTP status and message of error of user or files service.
+// Messages of internal errors aren't told to clients.
+func httpStatus(err error) (int, string) {
+	st := status.Convert(err)
+
+	switch st.Code() {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest, st.Message()
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized, st.Message()
+	case codes.PermissionDenied:
+		return http.StatusForbidden, st.Message()
+	case codes.NotFound:
+		return http.StatusNotFound, st.Message()
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return http.StatusConflict, st.Message()
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests, st.Message()
+	case codes.Unimplemented:
+		return http.StatusNotImplemented, "not implemented"
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable, "service unavailable"
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout, "service timeout"
+	default:
+		return http.StatusInternalServerError, "internal server error"
+	}
+}
+
 func (s *Server) Register() http.HandlerFunc {
 	type request struct {
-		Email    string `json:"email" validate:"required"`
-		Passowrd string `json:"password" validate:"required,email"`
+		Email    string `json:"email" validate:"required,email"`
+		Passowrd string `json:"password" validate:"required"`
 		AppID    int    `json:"app_id"`
 	}
 
 	type response struct {
 		Response
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		// VerificationRequired is true if app requires verified email, user gets no tokens till he verifies it
+		VerificationRequired bool `json:"verification_required,omitempty"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -364,6 +597,7 @@ func (s *Server) Register() http.HandlerFunc {
 		json.NewDecoder(r.Body).Decode(&req)
 		err := validator.New().Struct(req)
 		if err != nil {
+			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: 400,
@@ -372,27 +606,45 @@ func (s *Server) Register() http.HandlerFunc {
 				},
 				Token: "",
 			})
-
-			w.WriteHeader(400)
+			return
 		}
 
-		// we doesnt need any info from resp so just checking error
-		_, err = s.aCl.Register(ctx, &auth.RegisterRequest{
+		res, err := s.aCl.Register(ctx, &auth.RegisterRequest{
 			Email:    req.Email,
 			Password: req.Passowrd,
+			AppId:    int32(req.AppID),
 		})
 
 		if err != nil {
+			code, msg := httpStatus(err)
+			fields, ok := fieldViolations(err)
+			if ok {
+				code, msg = http.StatusBadRequest, "invalid data"
+			}
+
+			render.Status(r, code)
 			render.JSON(w, r, response{
 				Response: Response{
-					StatusCode: 500,
+					StatusCode: code,
 					Ok:         "",
-					Error:      "internal server error",
+					Error:      msg,
+					Fields:     fields,
 				},
 				Token: "",
 			})
+			return
+		}
 
-			w.WriteHeader(500)
+		// login would fail till email is verified, registration succeeded anyway
+		if res.GetVerificationRequired() {
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: 200,
+					Ok:         "ok",
+				},
+				VerificationRequired: true,
+			})
+			return
 		}
 
 		res2, err := s.aCl.Login(ctx, &auth.LoginRequest{
@@ -402,16 +654,18 @@ func (s *Server) Register() http.HandlerFunc {
 		})
 
 		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
 			render.JSON(w, r, response{
 				Response: Response{
-					StatusCode: 500,
+					StatusCode: code,
 					Ok:         "",
-					Error:      "internal server error",
+					Error:      msg,
 				},
 				Token: "",
 			})
-
-			w.WriteHeader(500)
+			return
 		}
 
 		render.JSON(w, r, response{
@@ -419,9 +673,330 @@ func (s *Server) Register() http.HandlerFunc {
 				StatusCode: 200,
 				Ok:         "ok",
 			},
-			Token: res2.GetToken(),
+			Token:        res2.GetToken(),
+			RefreshToken: res2.GetRefreshToken(),
+		})
+	}
+}
+
+func (s *Server) Refresh() http.HandlerFunc {
+	type request struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	type response struct {
+		Response
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		// user service records client of the last refresh in session
+		ctx = forwardClient(ctx, r)
+
+		res, err := s.aCl.Refresh(ctx, &auth.RefreshRequest{RefreshToken: req.RefreshToken})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			Token:        res.GetToken(),
+			RefreshToken: res.GetRefreshToken(),
+		})
+	}
+}
+
+// VerifyEmail handles link from verification email, token is passed in query
+func (s *Server) VerifyEmail() http.HandlerFunc {
+	type response struct {
+		Response
+		UserID string `json:"user_id"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		res, err := s.aCl.VerifyEmail(ctx, &auth.VerifyEmailRequest{Token: token})
+		if err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+			UserID: res.GetUserId(),
+		})
+	}
+}
+
+func (s *Server) ResendVerification() http.HandlerFunc {
+	type request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		if _, err := s.aCl.ResendVerification(ctx, &auth.ResendVerificationRequest{Email: req.Email}); err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
+
+// ForgotPassword mails password reset link, response is the same whether user exists or not
+func (s *Server) ForgotPassword() http.HandlerFunc {
+	type request struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		if _, err := s.aCl.RequestPasswordReset(ctx, &auth.RequestPasswordResetRequest{Email: req.Email}); err != nil {
+			code, msg := httpStatus(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
+
+func (s *Server) ResetPassword() http.HandlerFunc {
+	type request struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	type response struct {
+		Response
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		err := validator.New().Struct(req)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: http.StatusBadRequest,
+					Ok:         "",
+					Error:      "invalid data",
+				},
+			})
+			return
+		}
+
+		_, err = s.aCl.ResetPassword(ctx, &auth.ResetPasswordRequest{
+			Token:    req.Token,
+			Password: req.Password,
+		})
+		if err != nil {
+			code, msg := httpStatus(err)
+			fields, _ := fieldViolations(err)
+
+			render.Status(r, code)
+			render.JSON(w, r, response{
+				Response: Response{
+					StatusCode: code,
+					Ok:         "",
+					Error:      msg,
+					Fields:     fields,
+				},
+			})
+			return
+		}
+
+		render.JSON(w, r, response{
+			Response: Response{
+				StatusCode: http.StatusOK,
+				Ok:         "ok",
+			},
+		})
+	}
+}
+
+// JWKS serves public keys of user service, so other services can verify tokens offline
+func (s *Server) JWKS() http.HandlerFunc {
+	type response struct {
+		Keys []jwt.JWK `json:"keys"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
+		defer cancel()
+
+		keys, err := s.fetchJWKS(ctx)
+		if err != nil {
+			s.l.Error(utils.WrapErr("error in GetJWKS", err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{
+				StatusCode: http.StatusInternalServerError,
+				Error:      "internal server error",
+			})
+			return
+		}
+
+		// verifiers should refetch keys from time to time to see rotated ones
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		render.JSON(w, r, response{Keys: keys})
+	}
+}
+
+// fetchJWKS returns public keys of user service, it's jwt.FetchFunc of offline verification
+func (s *Server) fetchJWKS(ctx context.Context) ([]jwt.JWK, error) {
+	res, err := s.aCl.GetJWKS(ctx, &auth.GetJWKSRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]jwt.JWK, 0, len(res.GetKeys()))
+	for _, k := range res.GetKeys() {
+		keys = append(keys, jwt.JWK{
+			Kty: k.GetKty(),
+			Kid: k.GetKid(),
+			Alg: k.GetAlg(),
+			Use: k.GetUse(),
+			N:   k.GetN(),
+			E:   k.GetE(),
+			Crv: k.GetCrv(),
+			X:   k.GetX(),
 		})
 	}
+
+	return keys, nil
 }
 
 func (s *Server) GetIDFromToken(next http.Handler) http.Handler {
@@ -433,8 +1008,9 @@ func (s *Server) GetIDFromToken(next http.Handler) http.Handler {
 		ctx, cancel := context.WithTimeout(context.Background(), s.CtxTimeout)
 		defer cancel()
 
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
+		tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || tokenString == "" {
+			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response{
 				Response: Response{
 					StatusCode: http.StatusUnauthorized,
@@ -442,26 +1018,111 @@ func (s *Server) GetIDFromToken(next http.Handler) http.Handler {
 					Error:      "you need to pass token",
 				},
 			})
+			return
+		}
 
-			w.WriteHeader(http.StatusUnauthorized)
+		if s.keys != nil {
+			userID, err := s.verifyOffline(tokenString)
+			if err == nil {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxTokenKey, userID)))
+				return
+			}
+			if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, errWrongApp) {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, response{
+					Response: Response{
+						StatusCode: http.StatusUnauthorized,
+						Ok:         "",
+						Error:      err.Error(),
+					},
+				})
+				return
+			}
+			// other tokens are verified by user service
 		}
-		tokenString = tokenString[len("Bearer "):]
 
-		res, err := s.aCl.GetID(ctx, &auth.GetIDRequest{Token: tokenString})
+		// token is auth token of login, personal access token or OAuth access token
+		res, err := s.aCl.GetID(ctx, &auth.GetIDRequest{Token: tokenString, AppId: s.AppID})
 		if err != nil {
+			// any problem of the token is 401, failures of user service are told as they are
+			code, msg := httpStatus(err)
+			if code < http.StatusInternalServerError {
+				code, msg = http.StatusUnauthorized, "invalid token"
+				if st := status.Convert(err); st.Code() == codes.Unauthenticated {
+					msg = st.Message()
+				}
+			}
+
+			render.Status(r, code)
 			render.JSON(w, r, response{
 				Response: Response{
-					StatusCode: http.StatusUnauthorized,
+					StatusCode: code,
 					Ok:         "",
-					Error:      "invalid token",
+					Error:      msg,
 				},
 			})
-
-			w.WriteHeader(http.StatusUnauthorized)
+			return
 		}
 
 		newCtx := context.WithValue(r.Context(), ctxTokenKey, res.GetUserId())
+		if res.GetAccessToken() {
+			newCtx = context.WithValue(newCtx, ctxScopesKey, res.GetScopes())
+		}
 
 		next.ServeHTTP(w, r.WithContext(newCtx))
 	})
 }
+
+var errWrongApp = errors.New("token issued for another app")
+
+// verifyOffline returns user ID of auth token signed with public key of user service.
+// jwt.ErrUnknownKey is returned for tokens which only user service can verify: personal access tokens,
+// OAuth tokens and tokens signed with secret.
+func (s *Server) verifyOffline(token string) (string, error) {
+	c, err := s.keys.Verify(token)
+	if err != nil {
+		return "", err
+	}
+
+	if c.AppID != s.AppID {
+		return "", errWrongApp
+	}
+
+	return c.UserID, nil
+}
+
+// RequireScope rejects personal access tokens and OAuth tokens without scope, it's used after GetIDFromToken.
+// Auth tokens of login aren't limited by scopes.
+func (s *Server) RequireScope(scope string) func(http.Handler) http.Handler {
+	type response struct {
+		Response
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := r.Context().Value(ctxScopesKey).([]string)
+			if ok && !hasScope(scopes, scope) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response{
+					Response: Response{
+						StatusCode: http.StatusForbidden,
+						Ok:         "",
+						Error:      "token has no " + scope + " scope",
+					},
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"rest_grpc/pb/auth"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// fakeAuth registers every user, login is counted
+type fakeAuth struct {
+	auth.AuthClient
+	verificationRequired bool
+	logins               int
+}
+
+func (f *fakeAuth) Register(ctx context.Context, in *auth.RegisterRequest, opts ...grpc.CallOption) (*auth.RegisterResponse, error) {
+	return &auth.RegisterResponse{UserId: "user-id", VerificationRequired: f.verificationRequired}, nil
+}
+
+func (f *fakeAuth) Login(ctx context.Context, in *auth.LoginRequest, opts ...grpc.CallOption) (*auth.LoginResponse, error) {
+	f.logins++
+	return &auth.LoginResponse{Token: "token", RefreshToken: "refresh-token"}, nil
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name                 string
+		verificationRequired bool
+		wantToken            string
+		wantLogins           int
+	}{
+		{name: "login right away", wantToken: "token", wantLogins: 1},
+		{name: "verification required", verificationRequired: true, wantLogins: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeAuth{verificationRequired: tt.verificationRequired}
+			s := &Server{
+				l:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+				aCl:        a,
+				CtxTimeout: time.Second,
+			}
+
+			body := `{"email": "user1@example.org", "password": "password-1", "app_id": 1}`
+			r := httptest.NewRequest(http.MethodPost, "/users/register", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			s.Register()(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Register() status = %v, want %v", w.Code, http.StatusOK)
+			}
+
+			var res struct {
+				Token                string `json:"token"`
+				VerificationRequired bool   `json:"verification_required"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Token != tt.wantToken {
+				t.Errorf("Register() token = %q, want %q", res.Token, tt.wantToken)
+			}
+			if res.VerificationRequired != tt.verificationRequired {
+				t.Errorf("Register() verification_required = %v, want %v", res.VerificationRequired, tt.verificationRequired)
+			}
+			if a.logins != tt.wantLogins {
+				t.Errorf("Register() logged in %d times, want %d", a.logins, tt.wantLogins)
+			}
+		})
+	}
+}
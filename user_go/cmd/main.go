@@ -1,18 +1,51 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
 	"fmt"
+	"io/fs"
+	"lib/jwt"
+	"lib/migrator"
+	"lib/tokens"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"syscall"
+	"time"
+	"user_service/internal/accesstokens"
+	"user_service/internal/audit"
+	authclient "user_service/internal/clients/auth"
 	"user_service/internal/config"
 	"user_service/internal/grpc"
+	"user_service/internal/grpc/admin"
 	"user_service/internal/grpc/auth"
+	oauthgrpc "user_service/internal/grpc/oauth"
+	"user_service/internal/grpc/permissions"
+	"user_service/internal/grpc/users"
+	"user_service/internal/mailer"
+	"user_service/internal/mfa"
+	"user_service/internal/oauth"
+	"user_service/internal/oidc"
+	"user_service/internal/passwordreset"
+	"user_service/internal/policy"
+	"user_service/internal/sessions"
+	"user_service/internal/storage"
+	"user_service/internal/storage/memory"
 	"user_service/internal/storage/postgres"
+	"user_service/internal/storage/sqlite"
+	"user_service/internal/throttle"
+	"user_service/internal/verification"
+	"user_service/lib/passhash"
 	"user_service/lib/slogpretty"
 	"user_service/lib/utils"
+	"user_service/migrations"
+
+	"github.com/pressly/goose/v3"
 )
 
 const (
@@ -26,14 +59,91 @@ func main() {
 	log := setupLogger(cfg.Env)
 	log.Info("Config read!")
 
-	grpcSrv := grpc.CreateGrpcServer(log)
+	// `--config=... migrate up|down|status` manages schema of storage instead of running service
+	if flag.Arg(0) == "migrate" {
+		migrate(cfg, log, flag.Arg(1))
+		return
+	}
+
+	grpcSrv := grpc.CreateGrpcServer(log, cfg.LoginThrottling.TrustForwardedFor)
+
+	storage := mustSetupStorage(cfg, log)
+
+	log.Info(fmt.Sprintf("Storage created: %s", cfg.Storage))
+
+	// every login is recorded as session, so user can see and revoke them
+	tokenService := sessions.New(storage, mustSetupTokens(cfg, storage, log), cfg.RefreshTokenTTL)
+
+	mail := mustSetupMailer(cfg)
+
+	verificationService := verification.New(
+		storage,
+		mail,
+		cfg.EmailVerification.Secret,
+		cfg.EmailVerification.TTL,
+		cfg.EmailVerification.Link,
+	)
+
+	hasher := mustSetupHasher(cfg)
+
+	passwordResetService := passwordreset.New(
+		storage,
+		mail,
+		hasher,
+		cfg.PasswordReset.TTL,
+		cfg.PasswordReset.Link,
+	)
+
+	limiter := throttle.New(
+		storage,
+		lockoutPolicy(cfg.LoginThrottling.Account),
+		lockoutPolicy(cfg.LoginThrottling.IP),
+	)
+
+	passwordPolicy := mustSetupPolicy(cfg)
 
-	db := postgres.MustOpenPostgresDB(cfg.PostgresStorageURI)
-	storage := postgres.New(db)
+	accessTokenService := accesstokens.New(storage)
 
-	log.Info("Storage created!")
+	oauthService := oauth.New(storage, cfg.OAuth.AccessTokenTTL, cfg.OAuth.RefreshTokenTTL, cfg.OAuth.CodeTTL)
 
-	auth.Register(grpcSrv, storage, log, cfg.TokenSecret, cfg.TokenTTL)
+	oidcService := mustSetupOIDC(cfg)
+
+	auditLog := audit.New(storage, cfg.Audit.Retention)
+
+	mfaService := mfa.New(storage, cfg.MFA.Issuer, cfg.MFA.ChallengeSecret, cfg.MFA.ChallengeTTL)
+
+	auth.Register(
+		grpcSrv,
+		storage,
+		log,
+		tokenService,
+		verificationService,
+		passwordResetService,
+		limiter,
+		mfaService,
+		hasher,
+		passwordPolicy,
+		accessTokenService,
+		oauthService,
+		oidcService,
+		auditLog,
+	)
+	permissions.Register(grpcSrv, storage, log, tokenService, cfg.ServiceCredentials)
+	admin.Register(grpcSrv, storage, log, tokenService, auditLog)
+	oauthgrpc.Register(grpcSrv, storage, log, tokenService, oauthService)
+	users.Register(
+		grpcSrv,
+		storage,
+		log,
+		tokenService,
+		tokenService,
+		accessTokenService,
+		verificationService,
+		hasher,
+		passwordPolicy,
+		auditLog,
+		limiter,
+	)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
 	if err != nil {
@@ -48,6 +158,11 @@ func main() {
 		}
 	}()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go auditLog.Run(ctx, cfg.Audit.PruneInterval, log)
+
 	// Graceful shutdown
 
 	stop := make(chan os.Signal, 1)
@@ -60,6 +175,249 @@ func main() {
 	log.Info("Server stopped...")
 }
 
+// mustSetupStorage returns storage of configured kind,
+// database of storage must have schema of this binary
+func mustSetupStorage(cfg *config.Config, log *slog.Logger) storage.Storage {
+	if cfg.Storage == "memory" {
+		return memory.New()
+	}
+
+	s, m := mustOpenStorage(cfg)
+
+	ctx := context.Background()
+	if cfg.MigrateOnStart {
+		versions, err := m.Up(ctx)
+		if err != nil {
+			panic("cant apply migrations " + err.Error())
+		}
+		log.Info(fmt.Sprintf("Migrations applied: %d", len(versions)))
+	}
+
+	if err := m.Check(ctx); err != nil {
+		panic("wrong storage schema: " + err.Error())
+	}
+
+	return s
+}
+
+// mustOpenStorage opens database storage with migrator of its schema
+func mustOpenStorage(cfg *config.Config) (storage.Storage, *migrator.Migrator) {
+	switch cfg.Storage {
+	case "postgres", "postgresql":
+		if cfg.StorageDSN == "" {
+			panic("storage_dsn must be set for postgres storage")
+		}
+		db := postgres.MustOpenPostgresDB(cfg.StorageDSN)
+		return postgres.New(db), mustSetupMigrator(db.DB, goose.DialectPostgres, migrations.Postgres)
+	case "sqlite", "file":
+		if cfg.StorageDSN == "" {
+			panic("storage_dsn must be set for sqlite storage")
+		}
+		db := sqlite.MustOpenSQLiteDB(cfg.StorageDSN)
+		return sqlite.New(db), mustSetupMigrator(db.DB, goose.DialectSQLite3, migrations.SQLite)
+	case "memory":
+		panic("memory storage has no schema")
+	case "":
+		panic("storage_dsn must be set")
+	default:
+		panic("unknown storage: " + cfg.Storage)
+	}
+}
+
+func mustSetupMigrator(db *sql.DB, dialect goose.Dialect, fsys fs.FS) *migrator.Migrator {
+	m, err := migrator.New(db, dialect, fsys)
+	if err != nil {
+		panic("cant setup migrator " + err.Error())
+	}
+
+	return m
+}
+
+// migrate runs `migrate up|down|status` subcommand
+func migrate(cfg *config.Config, log *slog.Logger, cmd string) {
+	_, m := mustOpenStorage(cfg)
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		versions, err := m.Up(ctx)
+		if err != nil {
+			log.Error("cant apply migrations", utils.WrapErr(err))
+			os.Exit(1)
+		}
+		log.Info(fmt.Sprintf("Migrations applied: %d, schema version: %d", len(versions), m.Latest()))
+	case "down":
+		version, err := m.Down(ctx)
+		if err != nil {
+			log.Error("cant roll back migration", utils.WrapErr(err))
+			os.Exit(1)
+		}
+		log.Info(fmt.Sprintf("Migration rolled back: %d", version))
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			log.Error("cant get migrations status", utils.WrapErr(err))
+			os.Exit(1)
+		}
+		for _, s := range status {
+			state := string(s.State)
+			if s.State == goose.StateApplied {
+				state = "applied at " + s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Printf("%d %s %s\n", s.Source.Version, path.Base(s.Source.Path), state)
+		}
+	default:
+		log.Error("usage: migrate up|down|status")
+		os.Exit(2)
+	}
+}
+
+func lockoutPolicy(c config.LockoutPolicy) throttle.Policy {
+	return throttle.Policy{
+		MaxFailures: c.MaxFailures,
+		BaseLockout: c.BaseLockout,
+		MaxLockout:  c.MaxLockout,
+		ResetAfter:  c.ResetAfter,
+	}
+}
+
+// mustSetupHasher returns hasher of configured algorithm, the other one is kept to verify old hashes
+func mustSetupHasher(cfg *config.Config) *passhash.Hasher {
+	c := cfg.PasswordHashing
+
+	argon2id := passhash.DefaultArgon2id
+	argon2id.Memory = c.Argon2id.Memory
+	argon2id.Iterations = c.Argon2id.Iterations
+	argon2id.Parallelism = c.Argon2id.Parallelism
+
+	bcrypt := passhash.Bcrypt{Cost: c.BcryptCost}
+
+	switch c.Algorithm {
+	case "argon2id":
+		return passhash.New(argon2id, bcrypt)
+	case "bcrypt":
+		return passhash.New(bcrypt, argon2id)
+	default:
+		panic("unknown password hashing algorithm: " + c.Algorithm)
+	}
+}
+
+// mustSetupPolicy returns policy of new passwords with blocklist loaded from file
+func mustSetupPolicy(cfg *config.Config) *policy.Policy {
+	c := cfg.PasswordPolicy
+
+	var blocklist []string
+	if c.Blocklist != "" {
+		var err error
+		blocklist, err = policy.LoadBlocklist(c.Blocklist)
+		if err != nil {
+			panic("cant load password blocklist: " + err.Error())
+		}
+	}
+
+	return policy.New(policy.Password{
+		MinLength:     c.MinLength,
+		MaxLength:     c.MaxLength,
+		RequireLower:  c.RequireLower,
+		RequireUpper:  c.RequireUpper,
+		RequireDigit:  c.RequireDigit,
+		RequireSymbol: c.RequireSymbol,
+	}, blocklist)
+}
+
+// mustSetupOIDC returns manager of configured identity providers
+func mustSetupOIDC(cfg *config.Config) *oidc.Manager {
+	providers := make([]oidc.Provider, 0, len(cfg.OIDC.Providers))
+	for _, p := range cfg.OIDC.Providers {
+		providers = append(providers, oidc.Provider{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+	}
+
+	m, err := oidc.New(
+		cfg.OIDC.StateSecret,
+		cfg.OIDC.StateTTL,
+		&http.Client{Timeout: cfg.OIDC.Timeout},
+		providers...,
+	)
+	if err != nil {
+		panic("cant setup oidc: " + err.Error())
+	}
+
+	return m
+}
+
+// mustSetupMailer returns mailer of configured transport
+func mustSetupMailer(cfg *config.Config) mailer.Mailer {
+	switch cfg.Mailer.Transport {
+	case "smtp":
+		c := cfg.Mailer.SMTP
+		return mailer.NewSMTP(c.Host, c.Port, c.Username, c.Password, cfg.Mailer.From)
+	case "outbox":
+		outbox, err := mailer.NewOutbox(cfg.Mailer.OutboxDir, cfg.Mailer.From)
+		if err != nil {
+			panic("cant create outbox: " + err.Error())
+		}
+		return outbox
+	case "memory":
+		return mailer.NewMemory()
+	default:
+		panic("unknown mailer transport: " + cfg.Mailer.Transport)
+	}
+}
+
+// mustSetupTokens returns client of standalone auth service if it's configured
+// and local token manager otherwise
+func mustSetupTokens(cfg *config.Config, st storage.Storage, log *slog.Logger) sessions.Tokens {
+	if cfg.AuthService.Addr != "" {
+		client, err := authclient.New(cfg.AuthService.Addr, cfg.AuthService.Credential, cfg.AuthService.Timeout)
+		if err != nil {
+			panic("cant create auth service client: " + err.Error())
+		}
+
+		log.Info(fmt.Sprintf("Using auth service on %s", cfg.AuthService.Addr))
+
+		return client
+	}
+
+	ts := storage.Tokens(st)
+
+	return tokens.New(ts, ts, mustLoadKeyring(cfg), cfg.TokenTTL, cfg.RefreshTokenTTL)
+}
+
+// mustLoadKeyring loads signing keys from config, token secret is added as HS256 key
+func mustLoadKeyring(cfg *config.Config) *jwt.Keyring {
+	var keys []jwt.Key
+
+	for _, k := range cfg.SigningKeys {
+		key, err := jwt.LoadKey(k.ID, k.Path, k.NotBefore, k.NotAfter)
+		if err != nil {
+			panic("cant load signing key: " + err.Error())
+		}
+		keys = append(keys, key)
+	}
+
+	if cfg.TokenSecret != "" {
+		keys = append(keys, jwt.NewHMACKey(cfg.TokenSecret))
+	}
+
+	if len(keys) == 0 {
+		panic("token_secret or signing_keys must be set")
+	}
+
+	keyring, err := jwt.NewKeyring(keys...)
+	if err != nil {
+		panic("cant create keyring: " + err.Error())
+	}
+
+	return keyring
+}
+
 func setupLogger(env string) *slog.Logger {
 	var log *slog.Logger
 
@@ -0,0 +1,177 @@
+package accesstokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lib/errs"
+	"lib/securetoken"
+	"sort"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken    = errs.New(errs.ErrUnauthenticated, "invalid access token")
+	ErrTokenExpired    = errs.New(errs.ErrUnauthenticated, "access token expired")
+	ErrTokenRevoked    = errs.New(errs.ErrUnauthenticated, "access token revoked")
+	ErrScopeNotAllowed = errs.New(errs.ErrPermissionDenied, "scope not allowed")
+	ErrNotFound        = errs.New(errs.ErrNotFound, "access token not found")
+)
+
+// hintLen is number of the last characters of token kept to tell tokens apart
+const hintLen = 4
+
+type Storage interface {
+	SaveAccessToken(ctx context.Context, t models.AccessToken) error
+	FindAccessToken(ctx context.Context, tokenHash string) (models.AccessToken, error)
+	UserAccessTokens(ctx context.Context, userID string) ([]models.AccessToken, error)
+	TouchAccessToken(ctx context.Context, id string, at time.Time) error
+	// RevokeAccessToken returns storage.ErrNotFound if user has no such token
+	RevokeAccessToken(ctx context.Context, userID, id string, at time.Time) error
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
+// Manager creates and checks personal access tokens, they are used by scripts
+// instead of password. Token is random with models.AccessTokenPrefix,
+// only its hash is stored, so it's shown to user only once.
+type Manager struct {
+	storage Storage
+}
+
+func New(storage Storage) *Manager {
+	return &Manager{
+		storage: storage,
+	}
+}
+
+// Is reports whether token is personal access token and not JWT
+func Is(token string) bool {
+	return strings.HasPrefix(token, models.AccessTokenPrefix)
+}
+
+// Create creates token of user limited to scopes, zero expiresAt means token never expires.
+// User can give token only permissions he has, otherwise ErrScopeNotAllowed is returned.
+func (m *Manager) Create(
+	ctx context.Context,
+	userID, name string,
+	scopes []string,
+	expiresAt time.Time,
+) (string, models.AccessToken, error) {
+	scopes = uniqueScopes(scopes)
+
+	for _, scope := range scopes {
+		ok, err := m.storage.HasPermission(ctx, userID, scope)
+		if err != nil {
+			return "", models.AccessToken{}, err
+		}
+		if !ok {
+			return "", models.AccessToken{}, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+
+	secret, _, err := securetoken.New()
+	if err != nil {
+		return "", models.AccessToken{}, err
+	}
+
+	token := models.AccessTokenPrefix + secret
+
+	t := models.AccessToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		TokenHash: securetoken.Hash(token),
+		Hint:      token[len(token)-hintLen:],
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := m.storage.SaveAccessToken(ctx, t); err != nil {
+		return "", models.AccessToken{}, err
+	}
+
+	return token, t, nil
+}
+
+// Validate returns token by its value and records its use. Scopes of returned token
+// are only those its owner still has, permissions taken from user are taken from token too.
+func (m *Manager) Validate(ctx context.Context, token string) (models.AccessToken, error) {
+	if !Is(token) {
+		return models.AccessToken{}, ErrInvalidToken
+	}
+
+	t, err := m.storage.FindAccessToken(ctx, securetoken.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.AccessToken{}, ErrInvalidToken
+		}
+		return models.AccessToken{}, err
+	}
+
+	now := time.Now()
+
+	if t.IsRevoked() {
+		return models.AccessToken{}, ErrTokenRevoked
+	}
+
+	if t.IsExpired(now) {
+		return models.AccessToken{}, ErrTokenExpired
+	}
+
+	var scopes []string
+	for _, scope := range t.Scopes {
+		ok, err := m.storage.HasPermission(ctx, t.UserID, scope)
+		if err != nil {
+			return models.AccessToken{}, err
+		}
+		if ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	t.Scopes = scopes
+
+	if err := m.storage.TouchAccessToken(ctx, t.ID, now); err != nil {
+		return models.AccessToken{}, err
+	}
+	t.LastUsedAt = now
+
+	return t, nil
+}
+
+// List returns not revoked tokens of user, the newest first
+func (m *Manager) List(ctx context.Context, userID string) ([]models.AccessToken, error) {
+	return m.storage.UserAccessTokens(ctx, userID)
+}
+
+// Revoke revokes token of user, ErrNotFound is returned if user has no such token
+func (m *Manager) Revoke(ctx context.Context, userID, id string) error {
+	err := m.storage.RevokeAccessToken(ctx, userID, id, time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// uniqueScopes returns sorted scopes without duplicates
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	var res []string
+	for _, s := range scopes {
+		if !seen[s] {
+			seen[s] = true
+			res = append(res, s)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,194 @@
+package accesstokens
+
+import (
+	"context"
+	"errors"
+	"lib/securetoken"
+	"strings"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// fakeStorage keeps tokens by hash and permissions of users in maps
+type fakeStorage struct {
+	tokens      map[string]models.AccessToken
+	permissions map[string][]string
+}
+
+func (f *fakeStorage) SaveAccessToken(_ context.Context, t models.AccessToken) error {
+	f.tokens[t.TokenHash] = t
+	return nil
+}
+
+func (f *fakeStorage) FindAccessToken(_ context.Context, tokenHash string) (models.AccessToken, error) {
+	t, ok := f.tokens[tokenHash]
+	if !ok {
+		return models.AccessToken{}, storage.ErrNotFound
+	}
+	return t, nil
+}
+
+func (f *fakeStorage) UserAccessTokens(_ context.Context, userID string) ([]models.AccessToken, error) {
+	var res []models.AccessToken
+	for _, t := range f.tokens {
+		if t.UserID == userID && !t.IsRevoked() {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStorage) TouchAccessToken(_ context.Context, id string, at time.Time) error {
+	for hash, t := range f.tokens {
+		if t.ID == id {
+			t.LastUsedAt = at
+			f.tokens[hash] = t
+		}
+	}
+	return nil
+}
+
+func (f *fakeStorage) RevokeAccessToken(_ context.Context, userID, id string, at time.Time) error {
+	for hash, t := range f.tokens {
+		if t.ID == id && t.UserID == userID && !t.IsRevoked() {
+			t.RevokedAt = at
+			f.tokens[hash] = t
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+func (f *fakeStorage) HasPermission(_ context.Context, userID, permission string) (bool, error) {
+	for _, p := range f.permissions[userID] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+const userID = "user-id"
+
+func newManager() (*Manager, *fakeStorage) {
+	st := &fakeStorage{
+		tokens: map[string]models.AccessToken{},
+		permissions: map[string][]string{
+			userID: {models.PermissionFilesRead, models.PermissionFilesWrite},
+		},
+	}
+
+	return New(st), st
+}
+
+func TestManager_Create(t *testing.T) {
+	ctx := context.Background()
+	m, st := newManager()
+
+	token, at, err := m.Create(ctx, userID, "ci", []string{models.PermissionFilesWrite, models.PermissionFilesWrite}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(token, models.AccessTokenPrefix) || !Is(token) {
+		t.Errorf("Create() token = %v, want prefix %v", token, models.AccessTokenPrefix)
+	}
+
+	if len(at.Scopes) != 1 {
+		t.Errorf("Create() scopes = %v, want one scope", at.Scopes)
+	}
+
+	if _, ok := st.tokens[securetoken.Hash(token)]; !ok {
+		t.Errorf("Create() didn't store hash of token")
+	}
+
+	_, _, err = m.Create(ctx, userID, "ci", []string{models.PermissionUsersManage}, time.Time{})
+	if !errors.Is(err, ErrScopeNotAllowed) {
+		t.Errorf("Create() error = %v, want %v", err, ErrScopeNotAllowed)
+	}
+}
+
+func TestManager_Validate(t *testing.T) {
+	ctx := context.Background()
+	m, st := newManager()
+
+	token, _, err := m.Create(ctx, userID, "ci", []string{models.PermissionFilesRead, models.PermissionFilesWrite}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, _, err := m.Create(ctx, userID, "old", []string{models.PermissionFilesRead}, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, revokedToken, err := m.Create(ctx, userID, "lost", []string{models.PermissionFilesRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Revoke(ctx, userID, revokedToken.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: token},
+		{name: "expired", token: expired, wantErr: ErrTokenExpired},
+		{name: "revoked", token: revoked, wantErr: ErrTokenRevoked},
+		{name: "unknown", token: models.AccessTokenPrefix + "unknown", wantErr: ErrInvalidToken},
+		{name: "jwt", token: "eyJhbGciOiJIUzI1NiJ9.e30.sig", wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Validate(ctx, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.LastUsedAt.IsZero() {
+				t.Errorf("Validate() didn't record use of token")
+			}
+		})
+	}
+
+	// permission taken from user is taken from his tokens too
+	st.permissions[userID] = []string{models.PermissionFilesRead}
+
+	got, err := m.Validate(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.HasScope(models.PermissionFilesWrite) || !got.HasScope(models.PermissionFilesRead) {
+		t.Errorf("Validate() scopes = %v, want only %v", got.Scopes, models.PermissionFilesRead)
+	}
+}
+
+func TestManager_Revoke(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newManager()
+
+	_, at, err := m.Create(ctx, userID, "ci", []string{models.PermissionFilesRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Revoke(ctx, "other-id", at.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke() of other user error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := m.Revoke(ctx, userID, at.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := m.List(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Errorf("List() got %d tokens, want 0", len(list))
+	}
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/clientip"
+	"user_service/lib/utils"
+)
+
+type Storage interface {
+	SaveAuditEvent(ctx context.Context, e models.AuditEvent) error
+	// DeleteAuditEvents deletes events created before time and returns their number
+	DeleteAuditEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Log records audit events and deletes them after retention period
+type Log struct {
+	storage   Storage
+	retention time.Duration // zero keeps events forever
+}
+
+func New(storage Storage, retention time.Duration) *Log {
+	return &Log{
+		storage:   storage,
+		retention: retention,
+	}
+}
+
+// Record saves event, IP and user agent of the client are taken from context if they aren't set
+func (l *Log) Record(ctx context.Context, e models.AuditEvent) error {
+	if e.IP == "" {
+		e.IP = clientip.FromContext(ctx)
+	}
+	if e.UserAgent == "" {
+		e.UserAgent = clientip.UserAgent(ctx)
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	return l.storage.SaveAuditEvent(ctx, e)
+}
+
+// RecordOrLog records event like Record, its error is only logged. It's for events
+// of actions which are already done, failed audit log mustn't fail them.
+func (l *Log) RecordOrLog(ctx context.Context, log *slog.Logger, e models.AuditEvent) {
+	if err := l.Record(ctx, e); err != nil {
+		log.Error("cant record audit event", slog.String("type", e.Type), utils.WrapErr(err))
+	}
+}
+
+// Prune deletes events older than retention period, it returns number of deleted events
+func (l *Log) Prune(ctx context.Context, now time.Time) (int64, error) {
+	if l.retention == 0 {
+		return 0, nil
+	}
+
+	return l.storage.DeleteAuditEvents(ctx, now.Add(-l.retention))
+}
+
+// Run prunes events every interval until context is canceled, errors are only logged
+func (l *Log) Run(ctx context.Context, interval time.Duration, log *slog.Logger) {
+	const op = "internal/audit/audit/Run()"
+	log = log.With(slog.String("op", op))
+
+	if l.retention == 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		n, err := l.Prune(ctx, time.Now())
+		if err != nil {
+			log.Error("cant prune audit events", utils.WrapErr(err))
+		} else if n > 0 {
+			log.Info("audit events pruned", slog.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/clientip"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeStorage keeps events in slice, it fails to save them if err is set
+type fakeStorage struct {
+	events []models.AuditEvent
+	err    error
+}
+
+func (f *fakeStorage) SaveAuditEvent(_ context.Context, e models.AuditEvent) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeStorage) DeleteAuditEvents(_ context.Context, before time.Time) (int64, error) {
+	var kept []models.AuditEvent
+	for _, e := range f.events {
+		if !e.CreatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	n := int64(len(f.events) - len(kept))
+	f.events = kept
+	return n, nil
+}
+
+// withClient returns context with client as clientip.UnaryServerInterceptor sets it
+func withClient(t *testing.T, ip, userAgent string) context.Context {
+	t.Helper()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-forwarded-for", ip,
+		"x-forwarded-user-agent", userAgent,
+	))
+
+	var res context.Context
+	_, err := clientip.UnaryServerInterceptor(true)(ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, _ any) (any, error) {
+			res = ctx
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestLog_Record(t *testing.T) {
+	st := &fakeStorage{}
+	l := New(st, 0)
+
+	ctx := withClient(t, "203.0.113.7", "curl/8.0")
+
+	err := l.Record(ctx, models.AuditEvent{
+		Type:    models.AuditLogin,
+		Outcome: models.AuditFailure,
+		Email:   "user@example.com",
+		AppID:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(st.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(st.events))
+	}
+
+	e := st.events[0]
+	if e.IP != "203.0.113.7" || e.UserAgent != "curl/8.0" {
+		t.Errorf("client is %q %q, want it from context", e.IP, e.UserAgent)
+	}
+	if e.CreatedAt.IsZero() {
+		t.Error("time of event isn't set")
+	}
+	if e.Type != models.AuditLogin || e.Outcome != models.AuditFailure || e.AppID != 1 {
+		t.Errorf("event is changed: %+v", e)
+	}
+}
+
+func TestLog_RecordOrLog(t *testing.T) {
+	st := &fakeStorage{}
+	l := New(st, 0)
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, nil))
+
+	l.RecordOrLog(context.Background(), log, models.AuditEvent{Type: models.AuditLogin})
+	if len(st.events) != 1 || buf.Len() != 0 {
+		t.Fatalf("got %d events and log %q, want 1 event without log", len(st.events), buf.String())
+	}
+
+	st.err = errors.New("database is down")
+	l.RecordOrLog(context.Background(), log, models.AuditEvent{Type: models.AuditLogin})
+	if !strings.Contains(buf.String(), "database is down") {
+		t.Errorf("log = %q, want error of storage", buf.String())
+	}
+}
+
+func TestLog_Prune(t *testing.T) {
+	now := time.Now()
+
+	st := &fakeStorage{events: []models.AuditEvent{
+		{ID: 1, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: 2, CreatedAt: now.Add(-time.Hour)},
+	}}
+
+	n, err := New(st, 24*time.Hour).Prune(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(st.events) != 1 || st.events[0].ID != 2 {
+		t.Errorf("pruned %d, left %+v, want only event 2 left", n, st.events)
+	}
+
+	// events are kept forever without retention
+	n, err = New(st, 0).Prune(context.Background(), now.Add(365*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || len(st.events) != 1 {
+		t.Errorf("pruned %d events without retention", n)
+	}
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"lib/jwt"
+	"lib/tokens"
+	"time"
+	pb "user_service/pb/authservice"
+)
+
+// Client is client of standalone auth service (auth_go),
+// it's used instead of tokens.Manager when token lifecycle is delegated.
+// Auth service signs tokens of all apps with its own keys,
+// so apps with own secret or signing key ID can be used only with tokens.Manager.
+type Client struct {
+	api     pb.AuthServiceClient
+	timeout time.Duration
+}
+
+// New returns client of auth service on addr, credential is passed with every call,
+// auth service accepts calls of services with known credentials only
+func New(addr, credential string, timeout time.Duration) (*Client, error) {
+	conn, err := grpc.Dial(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(serviceCredential(credential)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		api:     pb.NewAuthServiceClient(conn),
+		timeout: timeout,
+	}, nil
+}
+
+// ErrAppKeyNotSupported is returned by Issue for apps with own secret or signing key,
+// auth service can't sign their tokens, so login to such app fails instead of getting token signed with wrong key
+var ErrAppKeyNotSupported = errors.New("app secret and signing key aren't supported by auth service")
+
+func (c *Client) Issue(ctx context.Context, s tokens.Subject) (tokens.Pair, error) {
+	if s.App.Secret != "" || s.App.SigningKeyID != "" {
+		return tokens.Pair{}, fmt.Errorf("app %d: %w", s.App.ID, ErrAppKeyNotSupported)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := c.api.Authenticate(ctx, &pb.AuthRequest{
+		UserId:    s.UserID,
+		Email:     s.Email,
+		AppId:     s.App.ID,
+		Audience:  s.App.Name,
+		TokenTtl:  int64(s.App.TokenTTL / time.Second),
+		SessionId: s.SessionID,
+	})
+	if err != nil {
+		return tokens.Pair{}, err
+	}
+
+	return tokens.Pair{
+		Token:        res.GetToken(),
+		RefreshToken: res.GetRefreshToken(),
+		Claims:       pbToClaims(res.GetClaims()),
+	}, nil
+}
+
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (tokens.Pair, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := c.api.Refresh(ctx, &pb.RefreshRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return tokens.Pair{}, err
+	}
+
+	switch res.GetStatus() {
+	case pb.RefreshStatus_REFRESH_STATUS_REFRESHED:
+		return tokens.Pair{
+			Token:        res.GetToken(),
+			RefreshToken: res.GetRefreshToken(),
+			Claims:       pbToClaims(res.GetClaims()),
+		}, nil
+	case pb.RefreshStatus_REFRESH_STATUS_EXPIRED:
+		return tokens.Pair{}, tokens.ErrRefreshTokenExpired
+	case pb.RefreshStatus_REFRESH_STATUS_INVALID:
+		return tokens.Pair{}, tokens.ErrRefreshTokenInvalid
+	default:
+		return tokens.Pair{}, fmt.Errorf("unexpected refresh status %v", res.GetStatus())
+	}
+}
+
+func (c *Client) Family(ctx context.Context, refreshToken string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := c.api.GetFamily(ctx, &pb.GetFamilyRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return "", err
+	}
+
+	if res.GetFamilyId() == "" {
+		return "", tokens.ErrRefreshTokenInvalid
+	}
+
+	return res.GetFamilyId(), nil
+}
+
+func (c *Client) Validate(ctx context.Context, token string) (jwt.Claims, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := c.api.Validate(ctx, &pb.ValidateRequest{Token: token})
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	switch res.GetStatus() {
+	case pb.TokenStatus_TOKEN_STATUS_VALID:
+		return pbToClaims(res.GetClaims()), nil
+	case pb.TokenStatus_TOKEN_STATUS_EXPIRED:
+		return jwt.Claims{}, jwt.ErrTokenExpired
+	case pb.TokenStatus_TOKEN_STATUS_REVOKED:
+		return jwt.Claims{}, tokens.ErrTokenRevoked
+	case pb.TokenStatus_TOKEN_STATUS_INVALID_SIGNATURE:
+		return jwt.Claims{}, jwt.ErrInvalidSignature
+	case pb.TokenStatus_TOKEN_STATUS_MALFORMED:
+		return jwt.Claims{}, jwt.ErrTokenMalformed
+	default:
+		return jwt.Claims{}, fmt.Errorf("unexpected token status %v", res.GetStatus())
+	}
+}
+
+func (c *Client) Revoke(ctx context.Context, claims jwt.Claims, refreshToken string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.api.Revoke(ctx, &pb.RevokeRequest{
+		Jti:          claims.JTI,
+		UserId:       claims.UserID,
+		ExpiresAt:    claims.ExpiresAt.Unix(),
+		RefreshToken: refreshToken,
+	})
+
+	return err
+}
+
+func (c *Client) RevokeAll(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.api.RevokeAll(ctx, &pb.RevokeAllRequest{UserId: userID})
+
+	return err
+}
+
+func (c *Client) JWKS(ctx context.Context) ([]jwt.JWK, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	res, err := c.api.GetJWKS(ctx, &pb.GetJWKSRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []jwt.JWK
+
+	for _, k := range res.GetKeys() {
+		keys = append(keys, jwt.JWK{
+			Kty: k.GetKty(),
+			Kid: k.GetKid(),
+			Alg: k.GetAlg(),
+			Use: k.GetUse(),
+			N:   k.GetN(),
+			E:   k.GetE(),
+			Crv: k.GetCrv(),
+			X:   k.GetX(),
+		})
+	}
+
+	return keys, nil
+}
+
+func pbToClaims(c *pb.Claims) jwt.Claims {
+	return jwt.Claims{
+		UserID:    c.GetUserId(),
+		Email:     c.GetEmail(),
+		JTI:       c.GetJti(),
+		AppID:     c.GetAppId(),
+		Audience:  c.GetAudience(),
+		SessionID: c.GetSessionId(),
+		IssuedAt:  time.Unix(c.GetIssuedAt(), 0),
+		ExpiresAt: time.Unix(c.GetExpiresAt(), 0),
+	}
+}
+
+// serviceCredential puts `authorization: Service <credential>` metadata into every call
+type serviceCredential string
+
+func (c serviceCredential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Service " + string(c)}, nil
+}
+
+// RequireTransportSecurity is false, services talk over insecure connections in local network
+func (c serviceCredential) RequireTransportSecurity() bool {
+	return false
+}
@@ -5,15 +5,173 @@ import (
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"os"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Env                string        `yaml:"env" env-default:"local"`
-	PostgresStorageURI string        `yaml:"postgres_storage_uri" env-required:"true"`
-	GRPC               GRPCConfig    `yaml:"grpc"`
-	TokenSecret        string        `yaml:"token_secret" env-required:"true"`
-	TokenTTL           time.Duration `yaml:"token_ttl" env-default:"24h"`
+	Env                string            `yaml:"env" env-default:"local"`
+	Storage            string            `yaml:"storage"`              // postgres, sqlite or memory, scheme of storage_dsn by default
+	StorageDSN         string            `yaml:"storage_dsn"`          // postgresql://... or sqlite:path/to/file.db
+	PostgresStorageURI string            `yaml:"postgres_storage_uri"` // deprecated, used if storage_dsn isn't set
+	MigrateOnStart     bool              `yaml:"migrate_on_start"`     // apply migrations before schema check
+	GRPC               GRPCConfig        `yaml:"grpc"`
+	TokenSecret        string            `yaml:"token_secret"` // HS256 secret, used only if no signing_keys are active, such tokens can be verified only by this service
+	TokenTTL           time.Duration     `yaml:"token_ttl" env-default:"24h"`
+	RefreshTokenTTL    time.Duration     `yaml:"refresh_token_ttl" env-default:"720h"`
+	SigningKeys        []SigningKey      `yaml:"signing_keys"`
+	AuthService        AuthService       `yaml:"auth_service"`
+	ServiceCredentials map[string]string `yaml:"service_credentials"` // credentials of services by names, services may check permissions of any user
+	Mailer             Mailer            `yaml:"mailer"`
+	EmailVerification  EmailVerification `yaml:"email_verification"`
+	PasswordReset      PasswordReset     `yaml:"password_reset"`
+	LoginThrottling    LoginThrottling   `yaml:"login_throttling"`
+	MFA                MFA               `yaml:"mfa"`
+	PasswordHashing    PasswordHashing   `yaml:"password_hashing"`
+	PasswordPolicy     PasswordPolicy    `yaml:"password_policy"`
+	OAuth              OAuth             `yaml:"oauth"`
+	OIDC               OIDC              `yaml:"oidc"`
+	Audit              Audit             `yaml:"audit"`
+}
+
+// PasswordPolicy are rules for new passwords. Blocklist is path to file
+// with common passwords, one per line, they are rejected regardless of case.
+type PasswordPolicy struct {
+	MinLength     int    `yaml:"min_length" env-default:"8"`
+	MaxLength     int    `yaml:"max_length" env-default:"128"`
+	RequireLower  bool   `yaml:"require_lower"`
+	RequireUpper  bool   `yaml:"require_upper"`
+	RequireDigit  bool   `yaml:"require_digit"`
+	RequireSymbol bool   `yaml:"require_symbol"`
+	Blocklist     string `yaml:"blocklist"`
+}
+
+// PasswordHashing configures hashing of passwords. New hashes are made with algorithm
+// (argon2id or bcrypt), hashes of the other algorithm or with lower cost are upgraded on login.
+type PasswordHashing struct {
+	Algorithm  string   `yaml:"algorithm" env-default:"argon2id"`
+	Argon2id   Argon2id `yaml:"argon2id"`
+	BcryptCost int      `yaml:"bcrypt_cost" env-default:"10"`
+}
+
+// Argon2id parameters, defaults are the second recommended option of RFC 9106
+type Argon2id struct {
+	Memory      uint32 `yaml:"memory" env-default:"65536"` // KiB
+	Iterations  uint32 `yaml:"iterations" env-default:"3"`
+	Parallelism uint8  `yaml:"parallelism" env-default:"4"`
+}
+
+// MFA configures TOTP two-factor login. Issuer is shown in authenticator apps,
+// challenge_secret signs challenges of the second login step.
+type MFA struct {
+	Issuer          string        `yaml:"issuer" env-default:"user_service"`
+	ChallengeSecret string        `yaml:"challenge_secret" env-required:"true"`
+	ChallengeTTL    time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+}
+
+// OAuth configures lifetime of tokens and authorization codes issued to OAuth clients
+type OAuth struct {
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" env-default:"1h"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	CodeTTL         time.Duration `yaml:"code_ttl" env-default:"10m"`
+}
+
+// Audit configures how long audit events are kept, zero Retention keeps them forever.
+// Old events are deleted every PruneInterval.
+type Audit struct {
+	Retention     time.Duration `yaml:"retention" env-default:"2160h"`
+	PruneInterval time.Duration `yaml:"prune_interval" env-default:"1h"`
+}
+
+// OIDC configures login with external OpenID Connect providers. StateSecret signs
+// state of logins in progress, it's required if there are providers.
+type OIDC struct {
+	StateSecret string         `yaml:"state_secret"`
+	StateTTL    time.Duration  `yaml:"state_ttl" env-default:"10m"`
+	Timeout     time.Duration  `yaml:"timeout" env-default:"10s"`
+	Providers   []OIDCProvider `yaml:"providers"`
+}
+
+// OIDCProvider is client registered at provider. RedirectURL is callback route of gateway,
+// Scopes are requested in addition to openid.
+type OIDCProvider struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// LoginThrottling configures lockout after failed logins. Failures are counted
+// per account and per client IP, client IP is taken from x-forwarded-for set by gateway
+// only if trust_forwarded_for is set, it must not be set if clients can reach service directly.
+type LoginThrottling struct {
+	TrustForwardedFor bool          `yaml:"trust_forwarded_for"`
+	Account           LockoutPolicy `yaml:"account"`
+	IP                LockoutPolicy `yaml:"ip"`
+}
+
+// LockoutPolicy locks for base_lockout after max_failures failures in a row,
+// every next failure doubles lockout up to max_lockout. Failures are forgotten
+// reset_after the last one. Zero max_failures disables lockout.
+type LockoutPolicy struct {
+	MaxFailures int           `yaml:"max_failures" env-default:"5"`
+	BaseLockout time.Duration `yaml:"base_lockout" env-default:"1m"`
+	MaxLockout  time.Duration `yaml:"max_lockout" env-default:"1h"`
+	ResetAfter  time.Duration `yaml:"reset_after" env-default:"1h"`
+}
+
+// Mailer is transport of emails: smtp, outbox writes them to directory
+// and memory only keeps them, it's for tests
+type Mailer struct {
+	Transport string `yaml:"transport" env-default:"outbox"`
+	From      string `yaml:"from" env-default:"no-reply@localhost"`
+	OutboxDir string `yaml:"outbox_dir" env-default:"./outbox"`
+	SMTP      SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// PasswordReset configures tokens sent to reset password.
+// Link is fmt format with token as the only argument, it should lead to page
+// that posts token with new password to /users/password/reset of the gateway.
+type PasswordReset struct {
+	TTL  time.Duration `yaml:"ttl" env-default:"1h"`
+	Link string        `yaml:"link" env-default:"http://localhost:3333/users/password/reset?token=%s"`
+}
+
+// EmailVerification configures tokens sent to confirm email.
+// Link is fmt format with token as the only argument.
+type EmailVerification struct {
+	Secret string        `yaml:"secret" env-required:"true"`
+	TTL    time.Duration `yaml:"ttl" env-default:"24h"`
+	Link   string        `yaml:"link" env-default:"http://localhost:3333/users/verify?token=%s"`
+}
+
+// AuthService is standalone token service (auth_go), if addr is empty
+// tokens are issued and validated by this service itself
+type AuthService struct {
+	Addr string `yaml:"addr"`
+	// Credential is service credential of this service in auth service config
+	Credential string        `yaml:"credential"`
+	Timeout    time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+// SigningKey is PEM encoded RSA or Ed25519 private key used to sign tokens.
+// Key signs tokens between not_before and not_after, but verifies them and is
+// published in JWKS while it is in config. To rotate keys add new key with not_before
+// in future and set not_after of the old one, remove old key after token_ttl passed.
+type SigningKey struct {
+	ID        string    `yaml:"kid"`
+	Path      string    `yaml:"path"`
+	NotBefore time.Time `yaml:"not_before"`
+	NotAfter  time.Time `yaml:"not_after"`
 }
 
 type GRPCConfig struct {
@@ -42,6 +200,13 @@ func MustLoadPath(configPath string) *Config {
 		panic("cannot read config: " + err.Error())
 	}
 
+	if cfg.StorageDSN == "" {
+		cfg.StorageDSN = cfg.PostgresStorageURI
+	}
+	if cfg.Storage == "" {
+		cfg.Storage, _, _ = strings.Cut(cfg.StorageDSN, ":")
+	}
+
 	return &cfg
 }
 
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// AccessTokenPrefix starts every personal access token, so leaked tokens are easy to find
+const AccessTokenPrefix = "qpat_"
+
+// AccessToken is stored form of personal access token, token itself is never stored.
+// Scopes are permissions the token is limited to, token can't do more than its owner.
+type AccessToken struct {
+	ID         string
+	UserID     string
+	Name       string
+	Scopes     []string
+	TokenHash  string
+	Hint       string    // last characters of token, shown in list to tell tokens apart
+	ExpiresAt  time.Time // zero if token never expires
+	LastUsedAt time.Time // zero if token wasn't used yet
+	RevokedAt  time.Time // zero if token isn't revoked
+	CreatedAt  time.Time
+}
+
+func (t AccessToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+func (t AccessToken) IsExpired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+func (t AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
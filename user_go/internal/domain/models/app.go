@@ -0,0 +1,44 @@
+package models
+
+import (
+	"lib/tokens"
+	"time"
+)
+
+// App is client application users log in to, e.g. web UI or CLI.
+// Tokens of one app are rejected by others.
+type App struct {
+	ID             int32
+	Name           string
+	Secret         string        // HS256 secret of app tokens, empty if tokens are signed with service keys
+	SigningKeyID   string        // kid of service key app tokens are signed with, empty for active key
+	TokenTTL       time.Duration // zero for default TTL
+	AllowedOrigins []string      // origins app may log in from, empty allows any
+	// RequireVerifiedEmail forbids login of users who haven't verified email yet
+	RequireVerifiedEmail bool
+}
+
+func (a App) AllowsOrigin(origin string) bool {
+	if len(a.AllowedOrigins) == 0 {
+		return true
+	}
+
+	for _, o := range a.AllowedOrigins {
+		if o == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TokenApp returns settings tokens of app are issued with
+func (a App) TokenApp() tokens.App {
+	return tokens.App{
+		ID:           a.ID,
+		Name:         a.Name,
+		Secret:       a.Secret,
+		SigningKeyID: a.SigningKeyID,
+		TokenTTL:     a.TokenTTL,
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Types of audit events
+const (
+	AuditRegister       = "register"
+	AuditLogin          = "login"
+	AuditPasswordChange = "password_change"
+	AuditPasswordReset  = "password_reset"
+	AuditTokenRevoke    = "token_revoke"
+	AuditRoleChange     = "role_change"
+	AuditUserDisable    = "user_disable"
+	AuditUserEnable     = "user_enable"
+)
+
+// Outcomes of audit events
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent is durable record of security relevant action. ActorID is user who did it,
+// UserID is user it was done to, they are equal for own actions and both are empty
+// if user is unknown, e.g. on login with unknown email.
+type AuditEvent struct {
+	ID        int64
+	Type      string
+	Outcome   string
+	ActorID   string
+	UserID    string
+	Email     string // email of login and register
+	AppID     int32  // zero if action isn't done in app
+	IP        string
+	UserAgent string
+	Details   string // reason of failure or what was changed
+	CreatedAt time.Time
+}
+
+// AuditFilter selects audit events, zero fields don't filter.
+// Events are ordered from the newest, page starts before event with BeforeID if it's set.
+type AuditFilter struct {
+	Type     string
+	Outcome  string
+	ActorID  string
+	UserID   string
+	IP       string
+	After    time.Time
+	Before   time.Time
+	BeforeID int64
+	Limit    int
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// EmailVerification is stored form of email verification token sent to user.
+// Token confirms Email, so it's useless if user changed email after it was sent.
+type EmailVerification struct {
+	TokenHash string
+	UserID    string
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    time.Time // zero if token wasn't used yet
+	CreatedAt time.Time
+}
+
+func (v EmailVerification) IsUsed() bool {
+	return !v.UsedAt.IsZero()
+}
+
+func (v EmailVerification) IsExpired(now time.Time) bool {
+	return !now.Before(v.ExpiresAt)
+}
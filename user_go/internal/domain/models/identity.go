@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Identity is account of external OpenID Connect provider linked to user,
+// user can log in with any of his identities
+type Identity struct {
+	ID        string
+	UserID    string
+	Provider  string // name of provider in config
+	Subject   string // sub claim, it's unique only within provider
+	Email     string // email provider had when identity was linked
+	CreatedAt time.Time
+}
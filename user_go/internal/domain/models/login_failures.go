@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginFailures counts failed logins of one account or one client IP.
+// Key is account:<user id> or ip:<address>.
+type LoginFailures struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time // zero if not locked
+}
+
+func (f LoginFailures) IsLocked(now time.Time) bool {
+	return now.Before(f.LockedUntil)
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// MFA is TOTP authenticator of user. It's pending after enrollment
+// and enabled after user confirmed it with the first code.
+type MFA struct {
+	UserID       string
+	Secret       string // base32 encoded TOTP secret
+	Enabled      bool
+	LastUsedStep int64 // time step of the last accepted code, codes of earlier steps are rejected
+	CreatedAt    time.Time
+}
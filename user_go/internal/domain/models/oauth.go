@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// Prefixes of OAuth tokens, they are opaque like personal access tokens
+const (
+	OAuthAccessTokenPrefix  = "qoat_"
+	OAuthRefreshTokenPrefix = "qort_"
+)
+
+// Kinds of OAuth tokens
+const (
+	OAuthTokenAccess  = "access"
+	OAuthTokenRefresh = "refresh"
+)
+
+// OAuthClient is app registered to get delegated access to users' data
+// with authorization code flow. Public clients, e.g. CLI, have no secret
+// and are authenticated only by PKCE.
+type OAuthClient struct {
+	ID           string // client_id
+	Name         string
+	OwnerID      string // user who registered the client
+	SecretHash   string // empty for public clients
+	RedirectURIs []string
+	CreatedAt    time.Time
+}
+
+func (c OAuthClient) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsRedirect reports whether uri is one of registered ones, only exact match is allowed
+func (c OAuthClient) AllowsRedirect(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthCode is stored form of authorization code, it's exchanged for tokens once.
+// GrantID is shared by the code and all tokens issued by it.
+type OAuthCode struct {
+	CodeHash      string
+	GrantID       string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string // S256 PKCE challenge
+	ExpiresAt     time.Time
+	UsedAt        time.Time // zero if code wasn't used yet
+	CreatedAt     time.Time
+}
+
+func (c OAuthCode) IsUsed() bool {
+	return !c.UsedAt.IsZero()
+}
+
+func (c OAuthCode) IsExpired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// OAuthToken is stored form of OAuth access or refresh token. Refresh tokens
+// rotate like refresh tokens of login, all tokens of one authorization share GrantID.
+type OAuthToken struct {
+	ID        string
+	GrantID   string
+	Kind      string // OAuthTokenAccess or OAuthTokenRefresh
+	TokenHash string
+	ClientID  string
+	UserID    string
+	Scopes    []string
+	ExpiresAt time.Time
+	UsedAt    time.Time // zero if refresh token wasn't used yet
+	RevokedAt time.Time // zero if token isn't revoked
+	CreatedAt time.Time
+}
+
+func (t OAuthToken) IsUsed() bool {
+	return !t.UsedAt.IsZero()
+}
+
+func (t OAuthToken) IsRevoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+func (t OAuthToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
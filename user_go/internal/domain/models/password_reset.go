@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PasswordReset is stored form of password reset token, token itself is never stored
+type PasswordReset struct {
+	TokenHash string
+	UserID    string
+	ExpiresAt time.Time
+	UsedAt    time.Time // zero if token wasn't used yet
+	CreatedAt time.Time
+}
+
+func (r PasswordReset) IsUsed() bool {
+	return !r.UsedAt.IsZero()
+}
+
+func (r PasswordReset) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
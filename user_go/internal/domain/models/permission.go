@@ -0,0 +1,40 @@
+package models
+
+import "strings"
+
+const (
+	RoleUser  = "user" // given to every registered user
+	RoleAdmin = "admin"
+)
+
+const (
+	PermissionFilesRead    = "files:read"
+	PermissionFilesWrite   = "files:write"
+	PermissionFilesReadAny = "files:read:any"
+	PermissionRolesManage  = "roles:manage"
+	PermissionUsersUnlock  = "users:unlock"
+	PermissionUsersRead    = "users:read"
+	PermissionUsersManage  = "users:manage"
+	PermissionAuditRead    = "audit:read"
+)
+
+// Permission is named as resource:action[:scope], e.g. files:read:any
+type Permission struct {
+	Name string
+}
+
+func (p Permission) Resource() string {
+	resource, _, _ := strings.Cut(p.Name, ":")
+	return resource
+}
+
+func (p Permission) Action() string {
+	_, action, _ := strings.Cut(p.Name, ":")
+	return action
+}
+
+type Role struct {
+	Name        string
+	Description string
+	Permissions []string
+}
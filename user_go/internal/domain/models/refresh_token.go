@@ -0,0 +1,6 @@
+package models
+
+import "lib/tokens"
+
+// RefreshToken is stored form of opaque refresh token, it's issued by tokens.Manager
+type RefreshToken = tokens.RefreshToken
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session is login of user on some device. Its ID is refresh token family
+// and sid claim of tokens, so it lives while tokens of the login are refreshed.
+type Session struct {
+	ID         string
+	UserID     string
+	AppID      int32 // zero for login without app
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time // time of login or of the last refresh
+	RevokedAt  time.Time // zero if session isn't revoked
+}
+
+func (s Session) IsRevoked() bool {
+	return !s.RevokedAt.IsZero()
+}
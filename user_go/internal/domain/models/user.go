@@ -1,16 +1,71 @@
 package models
 
-import "golang.org/x/crypto/bcrypt"
+import "time"
 
 type User struct {
 	ID          string `json:"id,omitempty"`
 	Email       string `json:"email,omitempty"`
 	Password    string `json:"password,omitempty"`
 	EncPassword []byte `json:"-"`
+	// EmailVerified is true when user followed link sent to Email
+	EmailVerified bool      `json:"email_verified"`
+	DisplayName   string    `json:"display_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	// DisabledAt is set when admin disabled user, disabled users can't login
+	DisabledAt time.Time `json:"disabled_at,omitempty"`
+	DeletedAt  time.Time `json:"deleted_at,omitempty"`
 }
 
-func (u *User) EncryptPassword() error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+// Statuses of users, every user has one of them
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	UserStatusDeleted  = "deleted"
+)
+
+func (u *User) IsDisabled() bool {
+	return !u.DisabledAt.IsZero()
+}
+
+// HasPassword reports whether user can log in with password,
+// users created by login with identity provider have no password
+func (u *User) HasPassword() bool {
+	return len(u.EncPassword) != 0
+}
+
+func (u *User) Status() string {
+	switch {
+	case !u.DeletedAt.IsZero():
+		return UserStatusDeleted
+	case u.IsDisabled():
+		return UserStatusDisabled
+	default:
+		return UserStatusActive
+	}
+}
+
+// UserFilter selects users of admin directory, zero fields don't filter.
+// Users are ordered by creation time and ID, page starts after user
+// with AfterCreatedAt and AfterID if AfterID is set.
+type UserFilter struct {
+	EmailPrefix    string
+	Status         string // deleted users are selected only by UserStatusDeleted
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	AfterCreatedAt time.Time
+	AfterID        string
+	Limit          int
+}
+
+// PasswordHasher hashes passwords, it's implemented by passhash.Hasher
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	// Verify reports whether password matches hash and whether hash should be upgraded
+	Verify(hash []byte, password string) (ok, rehash bool)
+}
+
+func (u *User) EncryptPassword(h PasswordHasher) error {
+	hash, err := h.Hash(u.Password)
 	if err != nil {
 		return err
 	}
@@ -18,6 +73,8 @@ func (u *User) EncryptPassword() error {
 	return nil
 }
 
-func (u *User) ComparePassword(password string) bool {
-	return bcrypt.CompareHashAndPassword(u.EncPassword, []byte(password)) == nil
+// ComparePassword reports whether password matches, rehash is true
+// if EncPassword was made with legacy algorithm or lower cost
+func (u *User) ComparePassword(h PasswordHasher, password string) (ok, rehash bool) {
+	return h.Verify(u.EncPassword, password)
 }
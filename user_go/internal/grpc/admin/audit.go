@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strconv"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/lib/utils"
+	pb "user_service/pb/admin"
+)
+
+func (s *serverAPI) ListAuditEvents(
+	ctx context.Context,
+	in *pb.ListAuditEventsRequest,
+) (*pb.ListAuditEventsResponse, error) {
+	const op = "internal/grpc/admin/audit/ListAuditEvents()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetPageSize() < 0 || !validOutcome(in.GetOutcome()) {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if _, err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionAuditRead); err != nil {
+		return nil, err
+	}
+
+	f := models.AuditFilter{
+		Type:    in.GetType(),
+		Outcome: in.GetOutcome(),
+		ActorID: in.GetActorId(),
+		UserID:  in.GetUserId(),
+		IP:      in.GetIp(),
+		Limit:   int(in.GetPageSize()),
+	}
+	if f.Limit == 0 {
+		f.Limit = defaultPageSize
+	}
+	if f.Limit > maxPageSize {
+		f.Limit = maxPageSize
+	}
+
+	if in.GetCreatedAfter() != 0 {
+		f.After = time.Unix(in.CreatedAfter, 0).UTC()
+	}
+	if in.GetCreatedBefore() != 0 {
+		f.Before = time.Unix(in.CreatedBefore, 0).UTC()
+	}
+
+	if in.GetCursor() != "" {
+		var err error
+		f.BeforeID, err = decodeAuditCursor(in.Cursor)
+		if err != nil {
+			log.Error("invalid cursor", utils.WrapErr(err))
+			return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+		}
+	}
+
+	// one more event tells if there is next page
+	limit := f.Limit
+	f.Limit++
+
+	events, err := s.storage.ListAuditEvents(ctx, f)
+	if err != nil {
+		log.Error("error in ListAuditEvents", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	var next string
+	if len(events) > limit {
+		events = events[:limit]
+		next = encodeAuditCursor(events[limit-1].ID)
+	}
+
+	eventsPb := make([]*pb.AuditEvent, 0, len(events))
+	for _, e := range events {
+		eventsPb = append(eventsPb, AuditEventToPb(e))
+	}
+
+	return &pb.ListAuditEventsResponse{Events: eventsPb, NextCursor: next}, nil
+}
+
+func validOutcome(o string) bool {
+	switch o {
+	case "", models.AuditSuccess, models.AuditFailure:
+		return true
+	default:
+		return false
+	}
+}
+
+// encodeAuditCursor returns cursor of page before event, it's opaque for clients
+func encodeAuditCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// decodeAuditCursor returns ID of the last event of previous page
+func decodeAuditCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("cursor with invalid id")
+	}
+
+	return id, nil
+}
+
+func AuditEventToPb(e models.AuditEvent) *pb.AuditEvent {
+	return &pb.AuditEvent{
+		Id:        e.ID,
+		Type:      e.Type,
+		Outcome:   e.Outcome,
+		ActorId:   e.ActorID,
+		UserId:    e.UserID,
+		Email:     e.Email,
+		AppId:     e.AppID,
+		Ip:        e.IP,
+		UserAgent: e.UserAgent,
+		Details:   e.Details,
+		CreatedAt: e.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,398 @@
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lib/jwt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/internal/policy"
+	"user_service/internal/storage"
+	"user_service/lib/utils"
+	pb "user_service/pb/admin"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type serverAPI struct {
+	pb.UnimplementedAdminServer
+	storage Storage
+	l       *slog.Logger
+	tokens  Tokens
+	audit   Audit
+}
+
+type Storage interface {
+	ListUsers(ctx context.Context, f models.UserFilter) ([]models.User, error)
+	FindUserByID(ctx context.Context, id string) (models.User, error)
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+	DisableUser(ctx context.Context, userID string, at time.Time) error
+	EnableUser(ctx context.Context, userID string) error
+	SetUserRoles(ctx context.Context, userID string, roles []string) error
+	ListAuditEvents(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+type Tokens interface {
+	Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error)
+	// RevokeAll revokes sessions of user with his personal access and OAuth tokens
+	RevokeAll(ctx context.Context, userID string) error
+}
+
+// Audit records actions of administrators, it's implemented by audit.Log
+type Audit interface {
+	RecordOrLog(ctx context.Context, log *slog.Logger, e models.AuditEvent)
+}
+
+func Register(grpcServer *grpc.Server, storage Storage, logger *slog.Logger, tokens Tokens, audit Audit) {
+	pb.RegisterAdminServer(grpcServer, &serverAPI{
+		storage: storage,
+		l:       logger,
+		tokens:  tokens,
+		audit:   audit,
+	})
+}
+
+func (s *serverAPI) ListUsers(
+	ctx context.Context,
+	in *pb.ListUsersRequest,
+) (*pb.ListUsersResponse, error) {
+	const op = "internal/grpc/admin/server/ListUsers()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetPageSize() < 0 || !validStatus(in.GetStatus()) {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if _, err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionUsersRead); err != nil {
+		return nil, err
+	}
+
+	f := models.UserFilter{
+		EmailPrefix: policy.NormalizeEmail(in.GetEmailPrefix()),
+		Status:      in.GetStatus(),
+		Limit:       int(in.GetPageSize()),
+	}
+	if f.Limit == 0 {
+		f.Limit = defaultPageSize
+	}
+	if f.Limit > maxPageSize {
+		f.Limit = maxPageSize
+	}
+
+	if in.GetCreatedAfter() != 0 {
+		f.CreatedAfter = time.Unix(in.CreatedAfter, 0).UTC()
+	}
+	if in.GetCreatedBefore() != 0 {
+		f.CreatedBefore = time.Unix(in.CreatedBefore, 0).UTC()
+	}
+
+	if in.GetCursor() != "" {
+		var err error
+		f.AfterCreatedAt, f.AfterID, err = decodeCursor(in.Cursor)
+		if err != nil {
+			log.Error("invalid cursor", utils.WrapErr(err))
+			return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+		}
+	}
+
+	// one more user tells if there is next page
+	limit := f.Limit
+	f.Limit++
+
+	users, err := s.storage.ListUsers(ctx, f)
+	if err != nil {
+		log.Error("error in ListUsers", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	var next string
+	if len(users) > limit {
+		users = users[:limit]
+		next = encodeCursor(users[limit-1])
+	}
+
+	usersPb := make([]*pb.User, 0, len(users))
+	for _, u := range users {
+		usersPb = append(usersPb, UserToPb(u))
+	}
+
+	return &pb.ListUsersResponse{Users: usersPb, NextCursor: next}, nil
+}
+
+func (s *serverAPI) GetUser(
+	ctx context.Context,
+	in *pb.GetUserRequest,
+) (*pb.GetUserResponse, error) {
+	const op = "internal/grpc/admin/server/GetUser()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetUserId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if _, err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionUsersRead); err != nil {
+		return nil, err
+	}
+
+	u, err := s.storage.FindUserByID(ctx, in.UserId)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found")
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	roles, err := s.storage.GetUserRoles(ctx, u.ID)
+	if err != nil {
+		log.Error("error in GetUserRoles", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.GetUserResponse{User: UserToPb(u), Roles: roles}, nil
+}
+
+func (s *serverAPI) DisableUser(
+	ctx context.Context,
+	in *pb.DisableUserRequest,
+) (*pb.DisableUserResponse, error) {
+	const op = "internal/grpc/admin/server/DisableUser()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetUserId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+
+	// otherwise the last admin could lock everybody out
+	if c.UserID == in.UserId {
+		log.Error("admin tried to disable himself")
+		return nil, status.Error(codes.FailedPrecondition, "cant disable yourself")
+	}
+
+	if err := s.storage.DisableUser(ctx, in.UserId, time.Now()); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found")
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		log.Error("error in DisableUser", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("user disabled", slog.String("user_id", in.UserId), slog.String("by", c.UserID))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditUserDisable,
+		Outcome: models.AuditSuccess,
+		ActorID: c.UserID,
+		UserID:  in.UserId,
+	})
+
+	if err := s.tokens.RevokeAll(ctx, in.UserId); err != nil {
+		log.Error("cant revoke sessions", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DisableUserResponse{}, nil
+}
+
+func (s *serverAPI) EnableUser(
+	ctx context.Context,
+	in *pb.EnableUserRequest,
+) (*pb.EnableUserResponse, error) {
+	const op = "internal/grpc/admin/server/EnableUser()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetUserId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionUsersManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.EnableUser(ctx, in.UserId); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found")
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		log.Error("error in EnableUser", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("user enabled", slog.String("user_id", in.UserId), slog.String("by", c.UserID))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditUserEnable,
+		Outcome: models.AuditSuccess,
+		ActorID: c.UserID,
+		UserID:  in.UserId,
+	})
+
+	return &pb.EnableUserResponse{}, nil
+}
+
+func (s *serverAPI) SetUserRoles(
+	ctx context.Context,
+	in *pb.SetUserRolesRequest,
+) (*pb.SetUserRolesResponse, error) {
+	const op = "internal/grpc/admin/server/SetUserRoles()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetUserId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionRolesManage)
+	if err != nil {
+		return nil, err
+	}
+
+	// otherwise the last admin could take admin role from himself
+	if c.UserID == in.UserId {
+		log.Error("admin tried to change own roles")
+		return nil, status.Error(codes.FailedPrecondition, "cant change own roles")
+	}
+
+	roles := uniqueRoles(in.GetRoles())
+
+	if err := s.storage.SetUserRoles(ctx, in.UserId, roles); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user or role not found")
+			return nil, status.Error(codes.NotFound, "user or role not found")
+		}
+		log.Error("error in SetUserRoles", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("roles set",
+		slog.String("user_id", in.UserId),
+		slog.String("roles", strings.Join(roles, ",")),
+		slog.String("by", c.UserID),
+	)
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditRoleChange,
+		Outcome: models.AuditSuccess,
+		ActorID: c.UserID,
+		UserID:  in.UserId,
+		Details: "roles: " + strings.Join(roles, ","),
+	})
+
+	return &pb.SetUserRolesResponse{Roles: roles}, nil
+}
+
+// authorize returns claims of token or grpc status error if token is invalid
+// or its owner doesn't have permission
+func (s *serverAPI) authorize(ctx context.Context, log *slog.Logger, token string, appID int32, permission string) (jwt.Claims, error) {
+	c, err := s.tokens.Validate(ctx, token, appID)
+	if err != nil {
+		return jwt.Claims{}, grpcerr.FromToken(log, err)
+	}
+
+	ok, err := s.storage.HasPermission(ctx, c.UserID, permission)
+	if err != nil {
+		log.Error("error in HasPermission", utils.WrapErr(err))
+		return jwt.Claims{}, status.Error(codes.Internal, "internal error")
+	}
+
+	if !ok {
+		log.Error("permission denied", slog.String("user_id", c.UserID), slog.String("permission", permission))
+		return jwt.Claims{}, status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	return c, nil
+}
+
+// uniqueRoles returns sorted roles without duplicates
+func uniqueRoles(roles []string) []string {
+	set := make(map[string]struct{}, len(roles))
+	res := make([]string, 0, len(roles))
+	for _, r := range roles {
+		if _, ok := set[r]; ok {
+			continue
+		}
+		set[r] = struct{}{}
+		res = append(res, r)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func validStatus(s string) bool {
+	switch s {
+	case "", models.UserStatusActive, models.UserStatusDisabled, models.UserStatusDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// encodeCursor returns cursor of page after user, it's opaque for clients
+func encodeCursor(u models.User) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(strconv.FormatInt(u.CreatedAt.UnixMicro(), 10) + "." + u.ID),
+	)
+}
+
+// decodeCursor returns creation time and ID of the last user of previous page
+func decodeCursor(cursor string) (time.Time, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	micro, id, ok := strings.Cut(string(b), ".")
+	if !ok || id == "" {
+		return time.Time{}, "", errors.New("cursor without id")
+	}
+
+	n, err := strconv.ParseInt(micro, 10, 64)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	return time.UnixMicro(n).UTC(), id, nil
+}
+
+func UserToPb(u models.User) *pb.User {
+	var disabledAt int64
+	if u.IsDisabled() {
+		disabledAt = u.DisabledAt.Unix()
+	}
+
+	return &pb.User{
+		UserId:        u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		DisplayName:   u.DisplayName,
+		CreatedAt:     u.CreatedAt.Unix(),
+		Status:        u.Status(),
+		DisabledAt:    disabledAt,
+	}
+}
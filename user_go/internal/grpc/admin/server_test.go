@@ -0,0 +1,204 @@
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"lib/jwt"
+	"log/slog"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage/memory"
+	pb "user_service/pb/admin"
+)
+
+// fakeTokens accepts tokens equal to user IDs and remembers revocations
+type fakeTokens struct {
+	revoked map[string]bool
+}
+
+func (t *fakeTokens) Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error) {
+	return jwt.Claims{UserID: token}, nil
+}
+
+func (t *fakeTokens) RevokeAll(ctx context.Context, userID string) error {
+	t.revoked[userID] = true
+	return nil
+}
+
+type fakeAudit struct{}
+
+func (fakeAudit) RecordOrLog(ctx context.Context, log *slog.Logger, e models.AuditEvent) {}
+
+func newServer() (*serverAPI, *memory.Storage, *fakeTokens) {
+	st := memory.New()
+	tokens := &fakeTokens{revoked: make(map[string]bool)}
+
+	return &serverAPI{
+		storage: st,
+		l:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		tokens:  tokens,
+		audit:   fakeAudit{},
+	}, st, tokens
+}
+
+func newUser(t *testing.T, st *memory.Storage, roles ...string) string {
+	t.Helper()
+
+	u, err := st.SaveUser(context.Background(), models.User{
+		Email:       uuid.New().String() + "@example.org",
+		EncPassword: []byte("hash"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range roles {
+		if err := st.AssignRole(context.Background(), u.ID, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return u.ID
+}
+
+func encode(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func TestCursor(t *testing.T) {
+	u := models.User{ID: uuid.New().String(), CreatedAt: time.Now()}
+
+	createdAt, id, err := decodeCursor(encodeCursor(u))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != u.ID || !createdAt.Equal(u.CreatedAt.Truncate(time.Microsecond)) {
+		t.Errorf("decodeCursor() = %v %v, want %v %v", createdAt, id, u.CreatedAt, u.ID)
+	}
+
+	tampered := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not base64!"},
+		{name: "without id", cursor: encode("1700000000000000")},
+		{name: "empty id", cursor: encode("1700000000000000.")},
+		{name: "time not a number", cursor: encode("yesterday." + u.ID)},
+		{name: "empty time", cursor: encode("." + u.ID)},
+	}
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("decodeCursor(%q) error = nil", tt.cursor)
+			}
+		})
+	}
+}
+
+func TestAuditCursor(t *testing.T) {
+	id, err := decodeAuditCursor(encodeAuditCursor(42))
+	if err != nil || id != 42 {
+		t.Errorf("decodeAuditCursor() = %v, %v, want 42", id, err)
+	}
+
+	for _, cursor := range []string{"not base64!", encode("zero"), encode("0"), encode("-1"), encode("1.5")} {
+		if _, err := decodeAuditCursor(cursor); err == nil {
+			t.Errorf("decodeAuditCursor(%q) error = nil", cursor)
+		}
+	}
+}
+
+func TestListUsers_Pages(t *testing.T) {
+	s, st, _ := newServer()
+	admin := newUser(t, st, models.RoleAdmin)
+	for i := 0; i < 4; i++ {
+		newUser(t, st)
+	}
+
+	seen := make(map[string]bool)
+	cursor := ""
+	for page := 0; ; page++ {
+		res, err := s.ListUsers(context.Background(), &pb.ListUsersRequest{Token: admin, PageSize: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, u := range res.GetUsers() {
+			if seen[u.GetUserId()] {
+				t.Fatalf("ListUsers() page %d repeats user %s", page, u.GetUserId())
+			}
+			seen[u.GetUserId()] = true
+		}
+
+		cursor = res.GetNextCursor()
+		if cursor == "" {
+			break
+		}
+	}
+
+	if len(seen) != 5 {
+		t.Errorf("ListUsers() returned %d users, want 5", len(seen))
+	}
+
+	_, err := s.ListUsers(context.Background(), &pb.ListUsersRequest{Token: admin, Cursor: encode("yesterday.id")})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListUsers() with tampered cursor code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestDisableEnableUser(t *testing.T) {
+	disable := func(s *serverAPI, token, userID string) error {
+		_, err := s.DisableUser(context.Background(), &pb.DisableUserRequest{Token: token, UserId: userID})
+		return err
+	}
+	enable := func(s *serverAPI, token, userID string) error {
+		_, err := s.EnableUser(context.Background(), &pb.EnableUserRequest{Token: token, UserId: userID})
+		return err
+	}
+
+	s, st, tokens := newServer()
+	admin := newUser(t, st, models.RoleAdmin)
+	user := newUser(t, st)
+	other := newUser(t, st)
+
+	tests := []struct {
+		name     string
+		call     func(s *serverAPI, token, userID string) error
+		token    string
+		userID   string
+		wantCode codes.Code
+	}{
+		{name: "disable without users:manage", call: disable, token: user, userID: other, wantCode: codes.PermissionDenied},
+		{name: "enable without users:manage", call: enable, token: user, userID: other, wantCode: codes.PermissionDenied},
+		{name: "disable yourself", call: disable, token: admin, userID: admin, wantCode: codes.FailedPrecondition},
+		{name: "disable unknown user", call: disable, token: admin, userID: uuid.New().String(), wantCode: codes.NotFound},
+		{name: "disable", call: disable, token: admin, userID: user},
+		{name: "disabled user can't manage users", call: enable, token: user, userID: user, wantCode: codes.PermissionDenied},
+		{name: "enable", call: enable, token: admin, userID: user},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(s, tt.token, tt.userID); status.Code(err) != tt.wantCode {
+				t.Fatalf("code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+		})
+	}
+
+	if !tokens.revoked[user] || tokens.revoked[other] {
+		t.Errorf("revoked = %v, want tokens of disabled user only", tokens.revoked)
+	}
+
+	u, err := st.FindUserByID(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.IsDisabled() {
+		t.Error("user is disabled after EnableUser()")
+	}
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/clientip"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/internal/mfa"
+	"user_service/internal/storage"
+	"user_service/lib/utils"
+	pb "user_service/pb/auth"
+)
+
+func (s *serverAPI) VerifyMFA(
+	ctx context.Context,
+	in *pb.VerifyMFARequest,
+) (*pb.LoginResponse, error) {
+	const op = "internal/grpc/auth/mfa/VerifyMFA()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetMfaToken() == "" || in.GetCode() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	userID, appID, err := s.mfa.ParseChallenge(in.MfaToken)
+	if err != nil {
+		if errors.Is(err, mfa.ErrChallengeExpired) {
+			log.Error("mfa challenge expired")
+			return nil, status.Error(codes.Unauthenticated, "mfa token expired")
+		}
+		log.Error("invalid mfa challenge", utils.WrapErr(err))
+		return nil, status.Error(codes.InvalidArgument, "invalid mfa token")
+	}
+
+	ip := clientip.FromContext(ctx)
+
+	if err := s.limiter.CheckIP(ctx, ip); err != nil {
+		return nil, grpcerr.FromLock(log, err)
+	}
+
+	if err := s.limiter.CheckAccount(ctx, userID); err != nil {
+		return nil, grpcerr.FromLock(log, err)
+	}
+
+	u, err := s.storage.FindUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found")
+			return nil, status.Error(codes.InvalidArgument, "invalid mfa token")
+		}
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	app, err := s.storage.FindAppByID(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("app not found", slog.Int("app_id", int(appID)))
+			return nil, status.Error(codes.InvalidArgument, "unknown app")
+		}
+		log.Error("error in FindAppByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	// user could be disabled after first step
+	if u.IsDisabled() {
+		log.Error("user disabled", slog.String("user_id", u.ID))
+		return nil, status.Error(codes.PermissionDenied, "account disabled")
+	}
+
+	if err := s.mfa.CheckCode(ctx, u.ID, in.Code); err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) || errors.Is(err, mfa.ErrNotEnrolled) {
+			log.Error("invalid mfa code")
+			s.loginFailed(ctx, log, ip, models.AuditEvent{UserID: u.ID, Email: u.Email, AppID: app.ID, Details: "invalid mfa code"})
+			return nil, status.Error(codes.InvalidArgument, "invalid mfa code")
+		}
+		log.Error("error in CheckCode", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return s.issue(ctx, log, u, app)
+}
+
+func (s *serverAPI) EnrollMFA(
+	ctx context.Context,
+	in *pb.EnrollMFARequest,
+) (*pb.EnrollMFAResponse, error) {
+	const op = "internal/grpc/auth/mfa/EnrollMFA()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	u, err := s.storage.FindUserByID(ctx, c.UserID)
+	if err != nil {
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	secret, uri, err := s.mfa.Enroll(ctx, u)
+	if err != nil {
+		return nil, grpcerr.From(log, err)
+	}
+
+	return &pb.EnrollMFAResponse{Secret: secret, ProvisioningUri: uri}, nil
+}
+
+func (s *serverAPI) ConfirmMFA(
+	ctx context.Context,
+	in *pb.ConfirmMFARequest,
+) (*pb.ConfirmMFAResponse, error) {
+	const op = "internal/grpc/auth/mfa/ConfirmMFA()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetCode() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	recoveryCodes, err := s.mfa.Confirm(ctx, c.UserID, in.Code)
+	if err != nil {
+		return nil, grpcerr.From(log, err)
+	}
+
+	log.Info("mfa enabled", slog.String("user_id", c.UserID))
+
+	return &pb.ConfirmMFAResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *serverAPI) DisableMFA(
+	ctx context.Context,
+	in *pb.DisableMFARequest,
+) (*pb.DisableMFAResponse, error) {
+	const op = "internal/grpc/auth/mfa/DisableMFA()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetCode() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	if err := s.mfa.Disable(ctx, c.UserID, in.Code); err != nil {
+		return nil, grpcerr.From(log, err)
+	}
+
+	log.Info("mfa disabled", slog.String("user_id", c.UserID))
+
+	return &pb.DisableMFAResponse{}, nil
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/internal/oidc"
+	"user_service/internal/policy"
+	"user_service/internal/storage"
+	"user_service/lib/utils"
+	pb "user_service/pb/auth"
+)
+
+// maxDisplayNameLen is length of display_name column, longer names of providers aren't used
+const maxDisplayNameLen = 255
+
+func (s *serverAPI) StartOIDCLogin(
+	ctx context.Context,
+	in *pb.StartOIDCLoginRequest,
+) (*pb.StartOIDCLoginResponse, error) {
+	const op = "internal/grpc/auth/oidc/StartOIDCLogin()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetProvider() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	l := oidc.Login{Provider: in.Provider, AppID: in.GetAppId()}
+
+	if in.GetToken() != "" {
+		c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+		if err != nil {
+			return nil, grpcerr.FromToken(log, err)
+		}
+		l.LinkUserID = c.UserID
+	} else if _, err := s.storage.FindAppByID(ctx, l.AppID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("app not found", slog.Int("app_id", int(l.AppID)))
+			return nil, status.Error(codes.InvalidArgument, "unknown app")
+		}
+		log.Error("error in FindAppByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	authURL, state, err := s.oidc.AuthURL(ctx, l)
+	if err != nil {
+		return nil, oidcErr(log, err)
+	}
+
+	return &pb.StartOIDCLoginResponse{AuthorizationUrl: authURL, State: state}, nil
+}
+
+func (s *serverAPI) FinishOIDCLogin(
+	ctx context.Context,
+	in *pb.FinishOIDCLoginRequest,
+) (*pb.FinishOIDCLoginResponse, error) {
+	const op = "internal/grpc/auth/oidc/FinishOIDCLogin()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetState() == "" || in.GetCode() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	id, l, err := s.oidc.Exchange(ctx, in.State, in.Code)
+	if err != nil {
+		return nil, oidcErr(log, err)
+	}
+
+	log = log.With(slog.String("provider", id.Provider))
+
+	if l.LinkUserID != "" {
+		if _, err := s.storage.FindUserByID(ctx, l.LinkUserID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				log.Error("user not found", slog.String("user_id", l.LinkUserID))
+				return nil, status.Error(codes.Unauthenticated, "user deleted")
+			}
+			log.Error("error in FindUserByID", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		if err := s.link(ctx, log, l.LinkUserID, id); err != nil {
+			return nil, err
+		}
+
+		return &pb.FinishOIDCLoginResponse{Linked: true}, nil
+	}
+
+	app, err := s.storage.FindAppByID(ctx, l.AppID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("app not found", slog.Int("app_id", int(l.AppID)))
+			return nil, status.Error(codes.InvalidArgument, "unknown app")
+		}
+		log.Error("error in FindAppByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	res := &pb.FinishOIDCLoginResponse{}
+
+	u, err := s.identityUser(ctx, log, id)
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+
+		u, res.Created, res.Linked, err = s.federatedUser(ctx, log, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res.Login, err = s.finishLogin(ctx, log, u, app)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// identityUser returns user identity is linked to, error is grpc status error, NotFound if identity isn't linked
+func (s *serverAPI) identityUser(ctx context.Context, log *slog.Logger, id oidc.Identity) (models.User, error) {
+	linked, err := s.storage.FindIdentity(ctx, id.Provider, id.Subject)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.User{}, status.Error(codes.NotFound, "identity not linked")
+		}
+		log.Error("error in FindIdentity", utils.WrapErr(err))
+		return models.User{}, status.Error(codes.Internal, "internal error")
+	}
+
+	u, err := s.storage.FindUserByID(ctx, linked.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found", slog.String("user_id", linked.UserID))
+			return models.User{}, status.Error(codes.Unauthenticated, "user deleted")
+		}
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return models.User{}, status.Error(codes.Internal, "internal error")
+	}
+
+	return u, nil
+}
+
+// federatedUser links not linked identity to user with the same email or creates new user.
+// Identity is linked only if both provider and we verified email, otherwise anybody could
+// take account over by registering its email at provider.
+func (s *serverAPI) federatedUser(ctx context.Context, log *slog.Logger, id oidc.Identity) (u models.User, created, linked bool, err error) {
+	if id.Email == "" {
+		log.Error("provider didn't return email")
+		return models.User{}, false, false, status.Error(codes.FailedPrecondition, "identity provider didn't share email")
+	}
+
+	email := policy.NormalizeEmail(id.Email)
+
+	u, err = s.storage.FindUserByEmail(ctx, email)
+	if err == nil {
+		if !id.EmailVerified || !u.EmailVerified {
+			log.Error("email used by not linked account", slog.String("user_id", u.ID))
+			return models.User{}, false, false, status.Error(codes.AlreadyExists, "email already used, log in and link identity")
+		}
+
+		if err := s.link(ctx, log, u.ID, id); err != nil {
+			return models.User{}, false, false, err
+		}
+
+		return u, false, true, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		log.Error("error in FindUserByEmail", utils.WrapErr(err))
+		return models.User{}, false, false, status.Error(codes.Internal, "internal error")
+	}
+
+	u = models.User{Email: email, EmailVerified: id.EmailVerified}
+	if len(id.Name) <= maxDisplayNameLen {
+		u.DisplayName = id.Name
+	}
+
+	u, err = s.storage.SaveFederatedUser(ctx, u, identity(u.ID, id))
+	if err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			log.Error("email or identity already used")
+			return models.User{}, false, false, status.Error(codes.AlreadyExists, "email already used")
+		}
+		log.Error("error in SaveFederatedUser", utils.WrapErr(err))
+		return models.User{}, false, false, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("user registered", slog.String("user_id", u.ID))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditRegister,
+		Outcome: models.AuditSuccess,
+		ActorID: u.ID,
+		UserID:  u.ID,
+		Email:   u.Email,
+		Details: "identity provider " + id.Provider,
+	})
+
+	// user can verify email later with ResendVerification
+	if !u.EmailVerified {
+		if err := s.verification.Send(ctx, u); err != nil {
+			log.Error("cant send verification email", utils.WrapErr(err))
+		}
+	}
+
+	return u, true, true, nil
+}
+
+// link links identity to user, linking identity of user again isn't an error.
+// Error is grpc status error.
+func (s *serverAPI) link(ctx context.Context, log *slog.Logger, userID string, id oidc.Identity) error {
+	linked, err := s.storage.FindIdentity(ctx, id.Provider, id.Subject)
+	if err == nil {
+		if linked.UserID != userID {
+			log.Error("identity linked to another user", slog.String("user_id", userID))
+			return status.Error(codes.AlreadyExists, "identity is linked to another account")
+		}
+		return nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		log.Error("error in FindIdentity", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	if err := s.storage.SaveIdentity(ctx, identity(userID, id)); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			log.Error("identity linked to another user", slog.String("user_id", userID))
+			return status.Error(codes.AlreadyExists, "identity is linked to another account")
+		}
+		log.Error("error in SaveIdentity", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("identity linked", slog.String("user_id", userID))
+
+	return nil
+}
+
+func identity(userID string, id oidc.Identity) models.Identity {
+	return models.Identity{
+		UserID:    userID,
+		Provider:  id.Provider,
+		Subject:   id.Subject,
+		Email:     id.Email,
+		CreatedAt: time.Now(),
+	}
+}
+
+// oidcErr converts error of oidc.Manager to grpc status error
+func oidcErr(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		log.Error("unknown provider")
+		return status.Error(codes.InvalidArgument, "unknown identity provider")
+	case errors.Is(err, oidc.ErrInvalidState):
+		log.Error("invalid state")
+		return status.Error(codes.InvalidArgument, "invalid state")
+	case errors.Is(err, oidc.ErrStateExpired):
+		log.Error("state expired")
+		return status.Error(codes.Unauthenticated, "login expired")
+	case errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidIDToken):
+		log.Error("provider rejected login", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "identity provider rejected login")
+	default:
+		log.Error("cant login with identity provider", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
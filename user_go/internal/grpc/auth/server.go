@@ -3,37 +3,160 @@ package auth
 import (
 	"context"
 	"errors"
-	"github.com/golang-jwt/jwt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"lib/jwt"
+	"lib/tokens"
 	"log/slog"
 	"time"
+	"user_service/internal/accesstokens"
 	"user_service/internal/domain/models"
+	"user_service/internal/grpc/clientip"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/internal/oauth"
+	"user_service/internal/oidc"
+	"user_service/internal/passwordreset"
+	"user_service/internal/policy"
 	"user_service/internal/storage"
+	"user_service/internal/verification"
 	"user_service/lib/utils"
 	pb "user_service/pb/auth"
 )
 
 type serverAPI struct {
 	pb.UnimplementedAuthServer
-	storage  Storage
-	l        *slog.Logger
-	secret   string
-	tokenTTL time.Duration
+	storage      Storage
+	l            *slog.Logger
+	tokens       Tokens
+	verification Verification
+	passwords    PasswordReset
+	limiter      Limiter
+	mfa          MFA
+	hasher       models.PasswordHasher
+	policy       Policy
+	accessTokens AccessTokens
+	oauth        OAuth
+	oidc         OIDC
+	audit        Audit
 }
 
 type Storage interface {
 	SaveUser(ctx context.Context, u models.User) (models.User, error)
 	FindUserByEmail(ctx context.Context, email string) (models.User, error)
+	FindUserByID(ctx context.Context, id string) (models.User, error)
+	UpdatePassword(ctx context.Context, userID string, encPassword []byte) error
+	FindAppByID(ctx context.Context, id int32) (models.App, error)
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+	FindIdentity(ctx context.Context, provider, subject string) (models.Identity, error)
+	SaveIdentity(ctx context.Context, id models.Identity) error
+	SaveFederatedUser(ctx context.Context, u models.User, id models.Identity) (models.User, error)
 }
 
-func Register(grpcServer *grpc.Server, storage Storage, logger *slog.Logger, secret string, tokenTTL time.Duration) {
+// Tokens is token lifecycle, it's implemented by tokens.Manager
+// and by client of standalone auth service.
+type Tokens interface {
+	Issue(ctx context.Context, s tokens.Subject) (tokens.Pair, error)
+	Refresh(ctx context.Context, refreshToken string) (tokens.Pair, error)
+	Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error)
+	Revoke(ctx context.Context, c jwt.Claims, refreshToken string) error
+	RevokeAll(ctx context.Context, userID string) error
+	JWKS(ctx context.Context) ([]jwt.JWK, error)
+}
+
+// Verification sends and checks email verification tokens, it's implemented by verification.Manager
+type Verification interface {
+	Send(ctx context.Context, u models.User) error
+	Verify(ctx context.Context, token string) (string, error)
+}
+
+// PasswordReset mails password reset tokens and resets password by them,
+// it's implemented by passwordreset.Manager
+type PasswordReset interface {
+	Request(ctx context.Context, u models.User) error
+	Reset(ctx context.Context, token, password string) (string, error)
+}
+
+// Limiter counts failed logins and locks accounts and client IPs,
+// it's implemented by throttle.Limiter
+type Limiter interface {
+	CheckIP(ctx context.Context, ip string) error
+	CheckAccount(ctx context.Context, userID string) error
+	Failure(ctx context.Context, userID, ip string) error
+	Success(ctx context.Context, userID string) error
+	Unlock(ctx context.Context, userID string) error
+}
+
+// MFA enrolls TOTP authenticators and checks second login step, it's implemented by mfa.Manager
+type MFA interface {
+	Enroll(ctx context.Context, u models.User) (secret, uri string, err error)
+	Confirm(ctx context.Context, userID, code string) ([]string, error)
+	Disable(ctx context.Context, userID, code string) error
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+	CheckCode(ctx context.Context, userID, code string) error
+	NewChallenge(userID string, appID int32) (string, error)
+	ParseChallenge(challenge string) (userID string, appID int32, err error)
+}
+
+// Policy checks emails and new passwords, it's implemented by policy.Policy
+type Policy interface {
+	// Check returns normalized email
+	Check(email, password string) (string, error)
+	CheckPassword(password string) error
+}
+
+// AccessTokens checks personal access tokens, it's implemented by accesstokens.Manager
+type AccessTokens interface {
+	Validate(ctx context.Context, token string) (models.AccessToken, error)
+}
+
+// OAuth checks access tokens issued to OAuth clients, it's implemented by oauth.Manager
+type OAuth interface {
+	Validate(ctx context.Context, token string) (models.OAuthToken, error)
+}
+
+// OIDC runs login with external identity providers, it's implemented by oidc.Manager
+type OIDC interface {
+	AuthURL(ctx context.Context, l oidc.Login) (string, string, error)
+	Exchange(ctx context.Context, state, code string) (oidc.Identity, oidc.Login, error)
+}
+
+// Audit records logins and other security relevant actions, it's implemented by audit.Log
+type Audit interface {
+	RecordOrLog(ctx context.Context, log *slog.Logger, e models.AuditEvent)
+}
+
+func Register(
+	grpcServer *grpc.Server,
+	storage Storage,
+	logger *slog.Logger,
+	tokens Tokens,
+	verification Verification,
+	passwords PasswordReset,
+	limiter Limiter,
+	mfa MFA,
+	hasher models.PasswordHasher,
+	policy Policy,
+	accessTokens AccessTokens,
+	oauth OAuth,
+	oidc OIDC,
+	audit Audit,
+) {
 	pb.RegisterAuthServer(grpcServer, &serverAPI{
-		storage:  storage,
-		l:        logger,
-		secret:   secret,
-		tokenTTL: tokenTTL,
+		storage:      storage,
+		l:            logger,
+		tokens:       tokens,
+		verification: verification,
+		passwords:    passwords,
+		limiter:      limiter,
+		mfa:          mfa,
+		hasher:       hasher,
+		policy:       policy,
+		accessTokens: accessTokens,
+		oauth:        oauth,
+		oidc:         oidc,
+		audit:        audit,
 	})
 }
 
@@ -53,10 +176,34 @@ func (s *serverAPI) Login(
 
 	log.Debug("passed validation")
 
-	u, err := s.storage.FindUserByEmail(ctx, in.Email)
+	ip := clientip.FromContext(ctx)
+
+	if err := s.limiter.CheckIP(ctx, ip); err != nil {
+		return nil, grpcerr.FromLock(log, err)
+	}
+
+	app, err := s.storage.FindAppByID(ctx, in.AppId)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("app not found", slog.Int("app_id", int(in.AppId)))
+			return nil, status.Error(codes.InvalidArgument, "unknown app")
+		}
+		log.Error("error in FindAppByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if origin := originFromContext(ctx); origin != "" && !app.AllowsOrigin(origin) {
+		log.Error("origin not allowed", slog.String("app", app.Name), slog.String("origin", origin))
+		return nil, status.Error(codes.PermissionDenied, "origin not allowed")
+	}
+
+	email := policy.NormalizeEmail(in.Email)
+
+	u, err := s.storage.FindUserByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			log.Error("user not found")
+			s.loginFailed(ctx, log, ip, models.AuditEvent{Email: email, AppID: app.ID, Details: "user not found"})
 			return nil, status.Error(codes.InvalidArgument, "incorrect request")
 		}
 		log.Error("error in FindUserByEmail", utils.WrapErr(err))
@@ -65,56 +212,603 @@ func (s *serverAPI) Login(
 
 	log.Debug("found user")
 
-	if !u.ComparePassword(in.Password) {
+	if err := s.limiter.CheckAccount(ctx, u.ID); err != nil {
+		s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+			Type:    models.AuditLogin,
+			Outcome: models.AuditFailure,
+			UserID:  u.ID,
+			Email:   u.Email,
+			AppID:   app.ID,
+			Details: "account locked",
+		})
+		return nil, grpcerr.FromLock(log, err)
+	}
+
+	ok, rehash := u.ComparePassword(s.hasher, in.Password)
+	if !ok {
 		log.Error("invalid password")
+		s.loginFailed(ctx, log, ip, models.AuditEvent{UserID: u.ID, Email: u.Email, AppID: app.ID, Details: "invalid password"})
 		return nil, status.Error(codes.InvalidArgument, "incorrect request")
 	}
 
+	if rehash {
+		s.rehashPassword(ctx, log, u, in.Password)
+	}
+
+	return s.finishLogin(ctx, log, u, app)
+}
+
+// finishLogin issues tokens to authenticated user or returns MFA challenge if user has MFA.
+// It's called after user is authenticated, so status of account isn't told to anybody who knows email.
+func (s *serverAPI) finishLogin(ctx context.Context, log *slog.Logger, u models.User, app models.App) (*pb.LoginResponse, error) {
+	failed := models.AuditEvent{
+		Type:    models.AuditLogin,
+		Outcome: models.AuditFailure,
+		UserID:  u.ID,
+		Email:   u.Email,
+		AppID:   app.ID,
+	}
+
+	if u.IsDisabled() {
+		log.Error("user disabled", slog.String("user_id", u.ID))
+		failed.Details = "account disabled"
+		s.audit.RecordOrLog(ctx, log, failed)
+		return nil, status.Error(codes.PermissionDenied, "account disabled")
+	}
+
+	if app.RequireVerifiedEmail && !u.EmailVerified {
+		log.Error("email not verified", slog.String("app", app.Name))
+		failed.Details = "email not verified"
+		s.audit.RecordOrLog(ctx, log, failed)
+		return nil, status.Error(codes.FailedPrecondition, "email not verified")
+	}
+
+	mfaEnabled, err := s.mfa.IsEnabled(ctx, u.ID)
+	if err != nil {
+		log.Error("error in IsEnabled", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	// failures are reset only after the last step,
+	// so correct password doesn't give more attempts to guess MFA code
+	if mfaEnabled {
+		challenge, err := s.mfa.NewChallenge(u.ID, app.ID)
+		if err != nil {
+			log.Error("cant create mfa challenge", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		log.Debug("mfa required")
+
+		return &pb.LoginResponse{MfaRequired: true, MfaToken: challenge}, nil
+	}
+
+	return s.issue(ctx, log, u, app)
+}
+
+// issue finishes login, it resets failed logins of user and issues tokens
+func (s *serverAPI) issue(ctx context.Context, log *slog.Logger, u models.User, app models.App) (*pb.LoginResponse, error) {
+	if err := s.limiter.Success(ctx, u.ID); err != nil {
+		log.Error("error in Success", utils.WrapErr(err))
+	}
+
 	log.Debug("creating jwt...")
 
-	payload := jwt.MapClaims{
-		"id":    u.ID,
-		"email": u.Email,
-		"exp":   time.Now().Add(s.tokenTTL).Unix(),
+	pair, err := s.tokens.Issue(ctx, tokens.Subject{UserID: u.ID, Email: u.Email, App: app.TokenApp()})
+	if err != nil {
+		log.Error("cant issue tokens", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditLogin,
+		Outcome: models.AuditSuccess,
+		ActorID: u.ID,
+		UserID:  u.ID,
+		Email:   u.Email,
+		AppID:   app.ID,
+	})
+
+	return &pb.LoginResponse{Token: pair.Token, RefreshToken: pair.RefreshToken}, nil
+}
+
+// rehashPassword upgrades hash of legacy algorithm or lower cost, password is known only on login.
+// Error is only logged, old hash still works.
+func (s *serverAPI) rehashPassword(ctx context.Context, log *slog.Logger, u models.User, password string) {
+	u.Password = password
+	if err := u.EncryptPassword(s.hasher); err != nil {
+		log.Error("cant hash password", utils.WrapErr(err))
+		return
+	}
+
+	if err := s.storage.UpdatePassword(ctx, u.ID, u.EncPassword); err != nil {
+		log.Error("error in UpdatePassword", utils.WrapErr(err))
+		return
+	}
+
+	log.Info("password hash upgraded", slog.String("user_id", u.ID))
+}
 
-	t, err := token.SignedString([]byte(s.secret))
+// loginFailed counts failed login and records it in audit log, user of e is empty if it's unknown.
+// Errors are only logged because response is error anyway.
+func (s *serverAPI) loginFailed(ctx context.Context, log *slog.Logger, ip string, e models.AuditEvent) {
+	if err := s.limiter.Failure(ctx, e.UserID, ip); err != nil {
+		log.Error("error in Failure", utils.WrapErr(err))
+	}
+
+	e.Type, e.Outcome = models.AuditLogin, models.AuditFailure
+	s.audit.RecordOrLog(ctx, log, e)
+}
+
+func (s *serverAPI) UnlockAccount(
+	ctx context.Context,
+	in *pb.UnlockAccountRequest,
+) (*pb.UnlockAccountResponse, error) {
+	const op = "internal/grpc/auth/server/UnlockAccount()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetUserId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	ok, err := s.storage.HasPermission(ctx, c.UserID, models.PermissionUsersUnlock)
 	if err != nil {
-		log.Error("cant create string token", utils.WrapErr(err))
+		log.Error("error in HasPermission", utils.WrapErr(err))
 		return nil, status.Error(codes.Internal, "internal error")
 	}
+	if !ok {
+		log.Error("permission denied", slog.String("user_id", c.UserID))
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
 
-	log.Debug(t)
+	if err := s.limiter.Unlock(ctx, in.UserId); err != nil {
+		log.Error("error in Unlock", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
 
-	return &pb.LoginResponse{Token: t}, nil
+	log.Info("account unlocked", slog.String("user_id", in.UserId), slog.String("by", c.UserID))
 
+	return &pb.UnlockAccountResponse{}, nil
+}
+
+func (s *serverAPI) Refresh(
+	ctx context.Context,
+	in *pb.RefreshRequest,
+) (*pb.RefreshResponse, error) {
+	const op = "internal/grpc/auth/server/Refresh()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetRefreshToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	pair, err := s.tokens.Refresh(ctx, in.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrRefreshTokenExpired):
+			log.Error("refresh token expired")
+			return nil, status.Error(codes.Unauthenticated, "refresh token expired")
+		case errors.Is(err, tokens.ErrRefreshTokenInvalid):
+			// it's also returned when used token is presented again, family is revoked then
+			log.Warn("invalid refresh token")
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		default:
+			log.Error("cant refresh tokens", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	// user could be deleted while refresh token was alive
+	if _, err := s.storage.FindUserByID(ctx, pair.Claims.UserID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found")
+			if err := s.tokens.RevokeAll(ctx, pair.Claims.UserID); err != nil {
+				log.Error("error in RevokeAll", utils.WrapErr(err))
+			}
+			return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+		}
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.RefreshResponse{Token: pair.Token, RefreshToken: pair.RefreshToken}, nil
 }
 
 func (s *serverAPI) Register(
 	ctx context.Context,
 	in *pb.RegisterRequest,
 ) (*pb.RegisterResponse, error) {
-	// TODO: add unique email validation (error)
-	const op = "internal/grpc/auth/server/Login()"
+	const op = "internal/grpc/auth/server/Register()"
 	log := s.l.With(slog.String("op", op))
 
-	if !validateReq(in) {
+	email, err := s.policy.Check(in.GetEmail(), in.GetPassword())
+	if err != nil {
+		return nil, grpcerr.FromPolicy(log, err)
+	}
+
+	var app models.App
+	if in.GetAppId() != 0 {
+		app, err = s.storage.FindAppByID(ctx, in.AppId)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				log.Error("app not found", slog.Int("app_id", int(in.AppId)))
+				return nil, status.Error(codes.InvalidArgument, "unknown app")
+			}
+			log.Error("error in FindAppByID", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	u := models.User{
+		Email:    email,
+		Password: in.Password,
+	}
+	if err := u.EncryptPassword(s.hasher); err != nil {
+		log.Error("cant hash password", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	user, err := s.storage.SaveUser(ctx, u)
+	if err != nil {
+		failed := models.AuditEvent{
+			Type:    models.AuditRegister,
+			Outcome: models.AuditFailure,
+			Email:   email,
+		}
+
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			log.Error("email already used")
+			failed.Details = "email already used"
+			s.audit.RecordOrLog(ctx, log, failed)
+			return nil, status.Error(codes.AlreadyExists, "email already used")
+		}
+
+		s.audit.RecordOrLog(ctx, log, failed)
+		return nil, grpcerr.From(log, err)
+	}
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditRegister,
+		Outcome: models.AuditSuccess,
+		ActorID: user.ID,
+		UserID:  user.ID,
+		Email:   user.Email,
+		AppID:   app.ID,
+	})
+
+	// user is registered anyway, he can ask for another email with ResendVerification
+	if err := s.verification.Send(ctx, user); err != nil {
+		log.Error("cant send verification email", utils.WrapErr(err))
+	}
+
+	// new user hasn't verified email yet, so he can't login to such app right away
+	return &pb.RegisterResponse{UserId: user.ID, VerificationRequired: app.RequireVerifiedEmail}, nil
+}
+
+func (s *serverAPI) VerifyEmail(
+	ctx context.Context,
+	in *pb.VerifyEmailRequest,
+) (*pb.VerifyEmailResponse, error) {
+	const op = "internal/grpc/auth/server/VerifyEmail()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
 		log.Error("haven't passed validation")
 		return nil, status.Error(codes.InvalidArgument, "incorrect request")
 	}
 
-	user, err := s.storage.SaveUser(ctx, models.User{
-		Email:    in.Email,
-		Password: in.Password,
+	userID, err := s.verification.Verify(ctx, in.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, verification.ErrTokenExpired):
+			log.Error("verification token expired")
+			return nil, status.Error(codes.InvalidArgument, "verification token expired")
+		case errors.Is(err, verification.ErrInvalidToken):
+			log.Error("invalid verification token")
+			return nil, status.Error(codes.InvalidArgument, "invalid verification token")
+		default:
+			log.Error("error in Verify", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	log.Info("email verified", slog.String("user_id", userID))
+
+	return &pb.VerifyEmailResponse{UserId: userID}, nil
+}
+
+func (s *serverAPI) ResendVerification(
+	ctx context.Context,
+	in *pb.ResendVerificationRequest,
+) (*pb.ResendVerificationResponse, error) {
+	const op = "internal/grpc/auth/server/ResendVerification()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetEmail() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	// response doesn't tell if user exists, so emails can't be enumerated
+	u, err := s.storage.FindUserByEmail(ctx, policy.NormalizeEmail(in.Email))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Debug("user not found")
+			return &pb.ResendVerificationResponse{}, nil
+		}
+		log.Error("error in FindUserByEmail", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if u.EmailVerified {
+		log.Debug("email already verified")
+		return &pb.ResendVerificationResponse{}, nil
+	}
+
+	if err := s.verification.Send(ctx, u); err != nil {
+		log.Error("cant send verification email", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.ResendVerificationResponse{}, nil
+}
+
+// mailTimeout limits work done in background after RequestPasswordReset returned
+const mailTimeout = 30 * time.Second
+
+func (s *serverAPI) RequestPasswordReset(
+	ctx context.Context,
+	in *pb.RequestPasswordResetRequest,
+) (*pb.RequestPasswordResetResponse, error) {
+	const op = "internal/grpc/auth/server/RequestPasswordReset()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetEmail() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	// response and its timing don't tell if user exists,
+	// so lookup and mailing are done after response is sent
+	go func(email string) {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+
+		u, err := s.storage.FindUserByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				log.Debug("user not found")
+				return
+			}
+			log.Error("error in FindUserByEmail", utils.WrapErr(err))
+			return
+		}
+
+		if err := s.passwords.Request(ctx, u); err != nil {
+			log.Error("cant send password reset email", utils.WrapErr(err))
+			return
+		}
+
+		log.Info("password reset requested", slog.String("user_id", u.ID))
+	}(policy.NormalizeEmail(in.Email))
+
+	return &pb.RequestPasswordResetResponse{}, nil
+}
+
+func (s *serverAPI) ResetPassword(
+	ctx context.Context,
+	in *pb.ResetPasswordRequest,
+) (*pb.ResetPasswordResponse, error) {
+	const op = "internal/grpc/auth/server/ResetPassword()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if err := s.policy.CheckPassword(in.GetPassword()); err != nil {
+		return nil, grpcerr.FromPolicy(log, err)
+	}
+
+	userID, err := s.passwords.Reset(ctx, in.Token, in.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, passwordreset.ErrTokenExpired):
+			log.Error("password reset token expired")
+			return nil, status.Error(codes.InvalidArgument, "password reset token expired")
+		case errors.Is(err, passwordreset.ErrInvalidToken):
+			log.Error("invalid password reset token")
+			return nil, status.Error(codes.InvalidArgument, "invalid password reset token")
+		default:
+			log.Error("error in Reset", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+	}
+
+	// somebody could know the old password, so he is logged out everywhere
+	if err := s.tokens.RevokeAll(ctx, userID); err != nil {
+		log.Error("error in RevokeAll", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("password reset", slog.String("user_id", userID))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditPasswordReset,
+		Outcome: models.AuditSuccess,
+		ActorID: userID,
+		UserID:  userID,
 	})
+
+	return &pb.ResetPasswordResponse{}, nil
+}
+
+func (s *serverAPI) GetID(
+	ctx context.Context,
+	in *pb.GetIDRequest,
+) (*pb.GetIDResponse, error) {
+	const op = "internal/grpc/auth/server/GetID()"
+	log := s.l.With(slog.String("op", op))
+
+	// caller always tells its app, so token of one app can't be accepted by another one
+	if in.GetToken() == "" || in.GetAppId() == 0 {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	// personal access tokens and OAuth tokens aren't issued for apps, they are accepted by all of them
+	if accesstokens.Is(in.Token) {
+		t, err := s.accessTokens.Validate(ctx, in.Token)
+		if err != nil {
+			return nil, grpcerr.FromToken(log, err)
+		}
+		return s.scopedTokenID(ctx, log, t.UserID, t.Scopes)
+	}
+
+	if oauth.Is(in.Token) {
+		t, err := s.oauth.Validate(ctx, in.Token)
+		if err != nil {
+			return nil, grpcerr.FromToken(log, err)
+		}
+		return s.scopedTokenID(ctx, log, t.UserID, t.Scopes)
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	return &pb.GetIDResponse{UserId: c.UserID}, nil
+}
+
+// scopedTokenID returns owner and scopes of personal access token or OAuth token,
+// token of deleted or disabled user is rejected
+func (s *serverAPI) scopedTokenID(ctx context.Context, log *slog.Logger, userID string, scopes []string) (*pb.GetIDResponse, error) {
+	u, err := s.storage.FindUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found", slog.String("user_id", userID))
+			return nil, status.Error(codes.Unauthenticated, "user deleted")
+		}
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if u.IsDisabled() {
+		log.Error("account disabled", slog.String("user_id", u.ID))
+		return nil, status.Error(codes.PermissionDenied, "account disabled")
+	}
+
+	return &pb.GetIDResponse{UserId: userID, AccessToken: true, Scopes: scopes}, nil
+}
+
+func (s *serverAPI) Logout(
+	ctx context.Context,
+	in *pb.LogoutRequest,
+) (*pb.LogoutResponse, error) {
+	const op = "internal/grpc/auth/server/Logout()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
 	if err != nil {
-		log.Error("error in SaveUser", utils.WrapErr(err))
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	if err := s.tokens.Revoke(ctx, c, in.GetRefreshToken()); err != nil {
+		log.Error("error in Revoke", utils.WrapErr(err))
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	return &pb.RegisterResponse{UserId: user.ID}, nil
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditTokenRevoke,
+		Outcome: models.AuditSuccess,
+		ActorID: c.UserID,
+		UserID:  c.UserID,
+		AppID:   c.AppID,
+		Details: "logout of session " + c.SessionID,
+	})
+
+	return &pb.LogoutResponse{}, nil
+}
+
+func (s *serverAPI) RevokeAllSessions(
+	ctx context.Context,
+	in *pb.RevokeAllSessionsRequest,
+) (*pb.RevokeAllSessionsResponse, error) {
+	const op = "internal/grpc/auth/server/RevokeAllSessions()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	if err := s.tokens.RevokeAll(ctx, c.UserID); err != nil {
+		log.Error("error in RevokeAll", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("all sessions revoked", slog.String("user_id", c.UserID))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditTokenRevoke,
+		Outcome: models.AuditSuccess,
+		ActorID: c.UserID,
+		UserID:  c.UserID,
+		AppID:   c.AppID,
+		Details: "all sessions",
+	})
+
+	return &pb.RevokeAllSessionsResponse{}, nil
+}
+
+func (s *serverAPI) GetJWKS(
+	ctx context.Context,
+	in *pb.GetJWKSRequest,
+) (*pb.GetJWKSResponse, error) {
+	const op = "internal/grpc/auth/server/GetJWKS()"
+	log := s.l.With(slog.String("op", op))
+
+	jwks, err := s.tokens.JWKS(ctx)
+	if err != nil {
+		log.Error("error in JWKS", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	var keys []*pb.JWK
+
+	for _, k := range jwks {
+		keys = append(keys, &pb.JWK{
+			Kty: k.Kty,
+			Kid: k.Kid,
+			Alg: k.Alg,
+			Use: k.Use,
+			N:   k.N,
+			E:   k.E,
+			Crv: k.Crv,
+			X:   k.X,
+		})
+	}
+
+	return &pb.GetJWKSResponse{Keys: keys}, nil
 }
 
 // validateLogin returns true if all data is correct
@@ -130,15 +824,17 @@ func validateLogin(request *pb.LoginRequest) bool {
 	return true
 }
 
-// validateReq returns true if all data is correct
-func validateReq(request *pb.RegisterRequest) bool {
-	if request == nil {
-		return false
+// originFromContext returns Origin header of browser request forwarded by gateway
+// in origin metadata, it's empty for other clients
+func originFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
 	}
 
-	if len(request.Password) < 3 || len(request.Email) < 3 {
-		return false
+	if v := md.Get("origin"); len(v) > 0 {
+		return v[0]
 	}
 
-	return true
+	return ""
 }
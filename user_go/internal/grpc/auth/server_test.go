@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"lib/jwt"
+	"lib/tokens"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/mfa"
+	"user_service/internal/sessions"
+	"user_service/internal/storage"
+	"user_service/internal/storage/memory"
+	"user_service/internal/throttle"
+	"user_service/lib/passhash"
+	pb "user_service/pb/auth"
+)
+
+const (
+	password = "password-1"
+	appID    = 1
+	mfaCode  = "123456"
+)
+
+// fakeMFA has challenges "userID:appID" and accepts only mfaCode
+type fakeMFA struct {
+	MFA
+	enabled map[string]bool
+}
+
+func (m *fakeMFA) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	return m.enabled[userID], nil
+}
+
+func (m *fakeMFA) NewChallenge(userID string, appID int32) (string, error) {
+	return userID + ":" + strconv.Itoa(int(appID)), nil
+}
+
+func (m *fakeMFA) ParseChallenge(challenge string) (string, int32, error) {
+	userID, app, _ := strings.Cut(challenge, ":")
+	id, err := strconv.Atoi(app)
+	if err != nil {
+		return "", 0, mfa.ErrChallengeExpired
+	}
+	return userID, int32(id), nil
+}
+
+func (m *fakeMFA) CheckCode(ctx context.Context, userID, code string) error {
+	if code != mfaCode {
+		return mfa.ErrInvalidCode
+	}
+	return nil
+}
+
+type fakeAudit struct{}
+
+func (fakeAudit) RecordOrLog(ctx context.Context, log *slog.Logger, e models.AuditEvent) {}
+
+func newServer(t *testing.T) (*serverAPI, *memory.Storage, *fakeMFA) {
+	st := memory.New()
+
+	kr, err := jwt.NewKeyring(jwt.NewHMACKey("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := storage.Tokens(st)
+
+	m := &fakeMFA{enabled: make(map[string]bool)}
+	lockout := throttle.Policy{MaxFailures: 5, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: time.Hour}
+
+	return &serverAPI{
+		storage: st,
+		l:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		tokens:  sessions.New(st, tokens.New(ts, ts, kr, time.Hour, time.Hour), time.Hour),
+		limiter: throttle.New(st, lockout, lockout),
+		mfa:     m,
+		hasher:  passhash.New(passhash.Bcrypt{Cost: 4}),
+		audit:   fakeAudit{},
+	}, st, m
+}
+
+func newUser(t *testing.T, s *serverAPI, st *memory.Storage) models.User {
+	t.Helper()
+
+	u := models.User{Email: uuid.New().String() + "@example.org", Password: password}
+	if err := u.EncryptPassword(s.hasher); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := st.SaveUser(context.Background(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func login(s *serverAPI, u models.User) (*pb.LoginResponse, error) {
+	return s.Login(context.Background(), &pb.LoginRequest{Email: u.Email, Password: password, AppId: appID})
+}
+
+func TestLogin_DisabledUser(t *testing.T) {
+	ctx := context.Background()
+	s, st, _ := newServer(t)
+	u := newUser(t, s, st)
+
+	res, err := login(s, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetToken() == "" {
+		t.Fatal("Login() token is empty")
+	}
+
+	if err := st.DisableUser(ctx, u.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := login(s, u); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Login() of disabled user code = %v, want %v", status.Code(err), codes.PermissionDenied)
+	}
+
+	if err := st.EnableUser(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := login(s, u); err != nil {
+		t.Errorf("Login() of enabled user error = %v", err)
+	}
+}
+
+func TestLogout_AppID(t *testing.T) {
+	ctx := context.Background()
+	s, st, _ := newServer(t)
+	u := newUser(t, s, st)
+
+	res, err := login(s, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// token is valid till the last case, it logs out
+	tests := []struct {
+		name  string
+		appID int32
+		want  codes.Code
+	}{
+		{name: "no app", appID: 0, want: codes.InvalidArgument},
+		{name: "another app", appID: appID + 1, want: codes.PermissionDenied},
+		{name: "same app", appID: appID, want: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Logout(ctx, &pb.LogoutRequest{Token: res.GetToken(), AppId: tt.appID})
+			if status.Code(err) != tt.want {
+				t.Fatalf("Logout() code = %v, want %v", status.Code(err), tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyMFA_DisabledUser(t *testing.T) {
+	ctx := context.Background()
+	s, st, m := newServer(t)
+	u := newUser(t, s, st)
+	m.enabled[u.ID] = true
+
+	res, err := login(s, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.GetMfaRequired() || res.GetToken() != "" {
+		t.Fatalf("Login() = %v, want only mfa token", res)
+	}
+
+	// user is disabled between login steps
+	if err := st.DisableUser(ctx, u.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: res.GetMfaToken(), Code: mfaCode})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("VerifyMFA() of disabled user code = %v, want %v", status.Code(err), codes.PermissionDenied)
+	}
+
+	if err := st.EnableUser(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: res.GetMfaToken(), Code: mfaCode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.GetToken() == "" {
+		t.Error("VerifyMFA() token is empty")
+	}
+}
@@ -0,0 +1,89 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type ctxKey struct{}
+
+// client is what is known about the client of the call
+type client struct {
+	ip        string
+	userAgent string
+}
+
+// UnaryServerInterceptor puts IP and user agent of the client into context.
+// If trustForwarded is set, first address of x-forwarded-for metadata and x-forwarded-user-agent
+// are used, they are set by gateway, so it must be set only if service isn't reachable by clients directly.
+func UnaryServerInterceptor(trustForwarded bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		c := client{
+			ip:        clientIP(ctx, trustForwarded),
+			userAgent: userAgent(ctx, trustForwarded),
+		}
+		return handler(context.WithValue(ctx, ctxKey{}, c), req)
+	}
+}
+
+// FromContext returns IP of the client, empty if it's unknown
+func FromContext(ctx context.Context) string {
+	c, _ := ctx.Value(ctxKey{}).(client)
+	return c.ip
+}
+
+// UserAgent returns user agent of the client, empty if it's unknown
+func UserAgent(ctx context.Context) string {
+	c, _ := ctx.Value(ctxKey{}).(client)
+	return c.userAgent
+}
+
+func clientIP(ctx context.Context, trustForwarded bool) string {
+	if trustForwarded {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get("x-forwarded-for"); len(v) > 0 {
+				first, _, _ := strings.Cut(v[0], ",")
+				if ip := strings.TrimSpace(first); ip != "" {
+					return ip
+				}
+			}
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// userAgent returns user agent forwarded by gateway or user agent of gRPC client itself
+func userAgent(ctx context.Context, trustForwarded bool) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if trustForwarded {
+		if v := md.Get("x-forwarded-user-agent"); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+
+	if v := md.Get("user-agent"); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
@@ -8,9 +8,12 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"log/slog"
+	"user_service/internal/grpc/clientip"
 )
 
-func CreateGrpcServer(log *slog.Logger) *grpc.Server {
+// CreateGrpcServer creates server with logging and recovery interceptors,
+// trustForwardedFor tells if client IP is taken from x-forwarded-for set by gateway
+func CreateGrpcServer(log *slog.Logger, trustForwardedFor bool) *grpc.Server {
 	loggingOpts := []logging.Option{
 		logging.WithLogOnEvents(
 			//logging.StartCall, logging.FinishCall,
@@ -30,6 +33,7 @@ func CreateGrpcServer(log *slog.Logger) *grpc.Server {
 	return grpc.NewServer(grpc.ChainUnaryInterceptor(
 		recovery.UnaryServerInterceptor(recoveryOpts...),
 		logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
+		clientip.UnaryServerInterceptor(trustForwardedFor),
 	))
 }
 
@@ -0,0 +1,187 @@
+package grpcerr
+
+import (
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"lib/errs"
+	"lib/jwt"
+	"lib/tokens"
+	"log/slog"
+	"user_service/internal/accesstokens"
+	"user_service/internal/oauth"
+	"user_service/internal/policy"
+	"user_service/internal/throttle"
+	"user_service/lib/utils"
+)
+
+// Code returns status code of kind of domain error, codes.Internal if error has no kind
+func Code(err error) codes.Code {
+	switch errs.Kind(err) {
+	case errs.ErrInvalid:
+		return codes.InvalidArgument
+	case errs.ErrUnauthenticated:
+		return codes.Unauthenticated
+	case errs.ErrPermissionDenied:
+		return codes.PermissionDenied
+	case errs.ErrNotFound:
+		return codes.NotFound
+	case errs.ErrAlreadyExists:
+		return codes.AlreadyExists
+	case errs.ErrFailedPrecondition:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+// From converts domain error to grpc status error with code of its kind and its message.
+// Errors without kind aren't told to client, they are internal errors.
+// Errors with kind are expected errors of client, so they aren't logged as errors.
+func From(log *slog.Logger, err error) error {
+	code := Code(err)
+	if code == codes.Internal {
+		log.Error("internal error", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info(err.Error(), slog.String("code", code.String()))
+
+	return status.Error(code, err.Error())
+}
+
+// FromToken converts error of token validation to grpc status error
+func FromToken(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		log.Debug("invalid token", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "token expired")
+	case errors.Is(err, tokens.ErrTokenRevoked):
+		log.Debug("invalid token", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "token revoked")
+	case errors.Is(err, jwt.ErrInvalidSignature):
+		log.Debug("invalid token", utils.WrapErr(err))
+		return status.Error(codes.PermissionDenied, "invalid token signature")
+	case errors.Is(err, tokens.ErrWrongApp):
+		log.Debug("invalid token", utils.WrapErr(err))
+		return status.Error(codes.PermissionDenied, "token issued for another app")
+	case errors.Is(err, accesstokens.ErrTokenExpired):
+		log.Debug("invalid access token", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "token expired")
+	case errors.Is(err, accesstokens.ErrTokenRevoked):
+		log.Debug("invalid access token", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "token revoked")
+	case errors.Is(err, accesstokens.ErrInvalidToken):
+		log.Debug("invalid access token", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, oauth.ErrTokenExpired):
+		log.Debug("invalid oauth token", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "token expired")
+	case errors.Is(err, oauth.ErrInvalidToken):
+		log.Debug("invalid oauth token", utils.WrapErr(err))
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		log.Debug("invalid token", utils.WrapErr(err))
+		return status.Error(codes.InvalidArgument, "malformed token")
+	case errs.Kind(err) != nil:
+		return From(log, err)
+	default:
+		log.Error("cant validate token", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// Reasons of ErrorInfo details of login lockout errors
+const (
+	ReasonAccountLocked   = "ACCOUNT_LOCKED"
+	ReasonTooManyAttempts = "TOO_MANY_ATTEMPTS"
+)
+
+// FromLock converts error of login throttling to ResourceExhausted status error,
+// ErrorInfo detail tells if account or client IP is locked and RetryInfo when to retry
+func FromLock(log *slog.Logger, err error) error {
+	var lErr *throttle.LockedError
+	if !errors.As(err, &lErr) {
+		log.Error("cant check login lock", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	reason := ReasonTooManyAttempts
+	if errors.Is(lErr, throttle.ErrAccountLocked) {
+		reason = ReasonAccountLocked
+	}
+
+	log.Warn("login locked", slog.String("reason", reason), slog.Duration("retry_after", lErr.RetryAfter))
+
+	st, dErr := status.New(codes.ResourceExhausted, lErr.Err.Error()).WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: "user_service"},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(lErr.RetryAfter)},
+	)
+	if dErr != nil {
+		return status.Error(codes.ResourceExhausted, lErr.Err.Error())
+	}
+
+	return st.Err()
+}
+
+// FromPolicy converts policy error to InvalidArgument status error
+// with BadRequest detail that has violation of every field
+func FromPolicy(log *slog.Logger, err error) error {
+	var pErr *policy.Error
+	if !errors.As(err, &pErr) {
+		log.Error("cant check policy", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	log.Error("haven't passed policy", utils.WrapErr(err))
+
+	br := &errdetails.BadRequest{}
+	for _, v := range pErr.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	st, dErr := status.New(codes.InvalidArgument, "incorrect request").WithDetails(br)
+	if dErr != nil {
+		return status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	return st.Err()
+}
+
+// DomainOAuth is domain of ErrorInfo details of OAuth errors
+const DomainOAuth = "oauth"
+
+// FromOAuth converts error of OAuth request to status error with ErrorInfo detail,
+// its reason is OAuth error code, e.g. invalid_grant
+func FromOAuth(log *slog.Logger, err error) error {
+	reason := oauth.Code(err)
+
+	var code codes.Code
+	switch reason {
+	case "server_error":
+		log.Error("cant handle oauth request", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	case "invalid_client":
+		code = codes.Unauthenticated
+	case "access_denied":
+		code = codes.PermissionDenied
+	default:
+		code = codes.InvalidArgument
+	}
+
+	log.Error("oauth request rejected", slog.String("reason", reason), utils.WrapErr(err))
+
+	st, dErr := status.New(code, err.Error()).WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: DomainOAuth},
+	)
+	if dErr != nil {
+		return status.Error(code, err.Error())
+	}
+
+	return st.Err()
+}
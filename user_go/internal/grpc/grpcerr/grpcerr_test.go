@@ -0,0 +1,63 @@
+package grpcerr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"user_service/internal/mfa"
+	"user_service/internal/sessions"
+	"user_service/internal/storage"
+)
+
+func TestFrom(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+		msg  string
+	}{
+		{"not found", fmt.Errorf("revoke: %w", sessions.ErrNotFound), codes.NotFound, "revoke: session not found"},
+		{"already exists", storage.ErrAlreadyExists, codes.AlreadyExists, "already exists"},
+		{"failed precondition", mfa.ErrNotEnrolled, codes.FailedPrecondition, "mfa not enrolled"},
+		{"invalid", storage.ErrEmptyFields, codes.InvalidArgument, "empty fields"},
+		{"internal", errors.New("connection refused"), codes.Internal, "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(From(log, tt.err))
+			if st.Code() != tt.code || st.Message() != tt.msg {
+				t.Errorf("got %s %q, want %s %q", st.Code(), st.Message(), tt.code, tt.msg)
+			}
+		})
+	}
+}
+
+func TestFrom_LogLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"expected error of client", sessions.ErrNotFound, "level=INFO"},
+		{"internal", errors.New("connection refused"), "level=ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			From(slog.New(slog.NewTextHandler(&buf, nil)), tt.err)
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("logged %q, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
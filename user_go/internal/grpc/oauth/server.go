@@ -0,0 +1,234 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lib/jwt"
+	"log/slog"
+	"strings"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/internal/oauth"
+	"user_service/internal/storage"
+	"user_service/lib/utils"
+	pb "user_service/pb/oauth"
+)
+
+// Grant types of token request
+const (
+	grantAuthorizationCode = "authorization_code"
+	grantRefreshToken      = "refresh_token"
+)
+
+// maxClientNameLen is length of name column of oauth_clients
+const maxClientNameLen = 255
+
+type serverAPI struct {
+	pb.UnimplementedOAuthServer
+	storage Storage
+	l       *slog.Logger
+	tokens  Tokens
+	oauth   OAuth
+}
+
+type Storage interface {
+	FindUserByID(ctx context.Context, id string) (models.User, error)
+}
+
+type Tokens interface {
+	Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error)
+}
+
+// OAuth is authorization server, it's implemented by oauth.Manager
+type OAuth interface {
+	RegisterClient(ctx context.Context, ownerID, name string, redirectURIs []string, confidential bool) (string, models.OAuthClient, error)
+	CheckRequest(ctx context.Context, clientID, redirectURI, scope string) (models.OAuthClient, []string, error)
+	Authorize(ctx context.Context, userID string, r oauth.AuthorizeRequest) (string, []string, error)
+	Exchange(ctx context.Context, clientID, clientSecret, code, redirectURI, codeVerifier string) (oauth.Tokens, error)
+	Refresh(ctx context.Context, clientID, clientSecret, refreshToken string) (oauth.Tokens, error)
+	Revoke(ctx context.Context, clientID, clientSecret, token string) error
+}
+
+func Register(grpcServer *grpc.Server, storage Storage, logger *slog.Logger, tokens Tokens, oauth OAuth) {
+	pb.RegisterOAuthServer(grpcServer, &serverAPI{
+		storage: storage,
+		l:       logger,
+		tokens:  tokens,
+		oauth:   oauth,
+	})
+}
+
+func (s *serverAPI) RegisterClient(
+	ctx context.Context,
+	in *pb.RegisterClientRequest,
+) (*pb.RegisterClientResponse, error) {
+	const op = "internal/grpc/oauth/server/RegisterClient()"
+	log := s.l.With(slog.String("op", op))
+
+	name := strings.TrimSpace(in.GetName())
+	if name == "" || len(in.GetRedirectUris()) == 0 {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if len(name) > maxClientNameLen {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "name is too long")
+	}
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	secret, c, err := s.oauth.RegisterClient(ctx, u.ID, name, in.RedirectUris, in.GetConfidential())
+	if err != nil {
+		return nil, grpcerr.FromOAuth(log, err)
+	}
+
+	log.Info("oauth client registered", slog.String("user_id", u.ID), slog.String("client_id", c.ID))
+
+	return &pb.RegisterClientResponse{ClientId: c.ID, ClientSecret: secret}, nil
+}
+
+func (s *serverAPI) GetConsent(
+	ctx context.Context,
+	in *pb.GetConsentRequest,
+) (*pb.GetConsentResponse, error) {
+	const op = "internal/grpc/oauth/server/GetConsent()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetClientId() == "" || in.GetRedirectUri() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, scopes, err := s.oauth.CheckRequest(ctx, in.ClientId, in.RedirectUri, in.GetScope())
+	if err != nil {
+		return nil, grpcerr.FromOAuth(log, err)
+	}
+
+	return &pb.GetConsentResponse{ClientName: c.Name, Scopes: scopes}, nil
+}
+
+func (s *serverAPI) Authorize(
+	ctx context.Context,
+	in *pb.AuthorizeRequest,
+) (*pb.AuthorizeResponse, error) {
+	const op = "internal/grpc/oauth/server/Authorize()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetClientId() == "" || in.GetRedirectUri() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	code, scopes, err := s.oauth.Authorize(ctx, u.ID, oauth.AuthorizeRequest{
+		ClientID:            in.ClientId,
+		RedirectURI:         in.RedirectUri,
+		Scope:               in.GetScope(),
+		CodeChallenge:       in.GetCodeChallenge(),
+		CodeChallengeMethod: in.GetCodeChallengeMethod(),
+	})
+	if err != nil {
+		return nil, grpcerr.FromOAuth(log, err)
+	}
+
+	log.Info("oauth client authorized", slog.String("user_id", u.ID), slog.String("client_id", in.ClientId))
+
+	return &pb.AuthorizeResponse{Code: code, Scopes: scopes}, nil
+}
+
+func (s *serverAPI) Token(
+	ctx context.Context,
+	in *pb.TokenRequest,
+) (*pb.TokenResponse, error) {
+	const op = "internal/grpc/oauth/server/Token()"
+	log := s.l.With(slog.String("op", op))
+
+	var t oauth.Tokens
+	var err error
+
+	switch in.GetGrantType() {
+	case grantAuthorizationCode:
+		if in.GetCode() == "" || in.GetRedirectUri() == "" || in.GetCodeVerifier() == "" {
+			return nil, grpcerr.FromOAuth(log, oauth.ErrInvalidRequest)
+		}
+		t, err = s.oauth.Exchange(ctx, in.GetClientId(), in.GetClientSecret(), in.Code, in.RedirectUri, in.CodeVerifier)
+	case grantRefreshToken:
+		if in.GetRefreshToken() == "" {
+			return nil, grpcerr.FromOAuth(log, oauth.ErrInvalidRequest)
+		}
+		t, err = s.oauth.Refresh(ctx, in.GetClientId(), in.GetClientSecret(), in.RefreshToken)
+	default:
+		return nil, grpcerr.FromOAuth(log, oauth.ErrUnsupportedGrantType)
+	}
+	if err != nil {
+		return nil, grpcerr.FromOAuth(log, err)
+	}
+
+	return &pb.TokenResponse{
+		AccessToken:  t.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(t.ExpiresIn.Seconds()),
+		RefreshToken: t.RefreshToken,
+		Scope:        strings.Join(t.Scopes, " "),
+	}, nil
+}
+
+func (s *serverAPI) Revoke(
+	ctx context.Context,
+	in *pb.RevokeRequest,
+) (*pb.RevokeResponse, error) {
+	const op = "internal/grpc/oauth/server/Revoke()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		return nil, grpcerr.FromOAuth(log, oauth.ErrInvalidRequest)
+	}
+
+	if err := s.oauth.Revoke(ctx, in.GetClientId(), in.GetClientSecret(), in.Token); err != nil {
+		return nil, grpcerr.FromOAuth(log, err)
+	}
+
+	return &pb.RevokeResponse{}, nil
+}
+
+// user validates token presented to app and returns its owner, disabled user can't authorize clients.
+// Error is grpc status error.
+func (s *serverAPI) user(ctx context.Context, log *slog.Logger, token string, appID int32) (models.User, error) {
+	if token == "" {
+		log.Error("haven't passed validation")
+		return models.User{}, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, token, appID)
+	if err != nil {
+		return models.User{}, grpcerr.FromToken(log, err)
+	}
+
+	u, err := s.storage.FindUserByID(ctx, c.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found", slog.String("user_id", c.UserID))
+			return models.User{}, status.Error(codes.Unauthenticated, "user deleted")
+		}
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return models.User{}, status.Error(codes.Internal, "internal error")
+	}
+
+	if u.IsDisabled() {
+		log.Error("account disabled", slog.String("user_id", u.ID))
+		return models.User{}, status.Error(codes.PermissionDenied, "account disabled")
+	}
+
+	return u, nil
+}
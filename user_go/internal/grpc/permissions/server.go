@@ -0,0 +1,275 @@
+package permissions
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"lib/jwt"
+	"log/slog"
+	"strings"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/internal/storage"
+	"user_service/lib/utils"
+	pb "user_service/pb/permissions"
+)
+
+type serverAPI struct {
+	pb.UnimplementedPermissionsServer
+	storage  Storage
+	l        *slog.Logger
+	tokens   Validator
+	services map[string]string
+}
+
+type Storage interface {
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID string) ([]models.Permission, error)
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	AssignRole(ctx context.Context, userID, role string) error
+	RevokeRole(ctx context.Context, userID, role string) error
+}
+
+type Validator interface {
+	Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error)
+}
+
+// Register registers permissions server, services are credentials of services by their names,
+// services may check permissions of any user
+func Register(grpcServer *grpc.Server, storage Storage, logger *slog.Logger, tokens Validator, services map[string]string) {
+	pb.RegisterPermissionsServer(grpcServer, &serverAPI{
+		storage:  storage,
+		l:        logger,
+		tokens:   tokens,
+		services: services,
+	})
+}
+
+func (s *serverAPI) GetUserPermissions(
+	ctx context.Context,
+	in *pb.GetUserPermissionsRequest,
+) (*pb.UserPermissions, error) {
+	const op = "internal/grpc/permissions/server/GetUserPermissions()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	roles, err := s.storage.GetUserRoles(ctx, c.UserID)
+	if err != nil {
+		log.Error("error in GetUserRoles", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	permissions, err := s.storage.GetUserPermissions(ctx, c.UserID)
+	if err != nil {
+		log.Error("error in GetUserPermissions", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	var permissionsPb []*pb.Permission
+	for _, p := range permissions {
+		permissionsPb = append(permissionsPb, PermissionToPb(p))
+	}
+
+	return &pb.UserPermissions{
+		UserId:      c.UserID,
+		Roles:       roles,
+		Permissions: permissionsPb,
+	}, nil
+}
+
+func (s *serverAPI) CheckPermission(
+	ctx context.Context,
+	in *pb.CheckPermissionRequest,
+) (*pb.CheckPermissionResponse, error) {
+	const op = "internal/grpc/permissions/server/CheckPermission()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetUserId() == "" || in.GetPermission() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	// services check permissions of users they act for, users may check only their own permissions
+	if _, ok := s.service(ctx); !ok {
+		if in.GetToken() == "" {
+			log.Error("neither service credential nor token passed")
+			return nil, status.Error(codes.Unauthenticated, "you need to pass token or service credential")
+		}
+
+		c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+		if err != nil {
+			return nil, grpcerr.FromToken(log, err)
+		}
+
+		if c.UserID != in.UserId {
+			if err := s.require(ctx, log, c.UserID, models.PermissionRolesManage); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ok, err := s.storage.HasPermission(ctx, in.UserId, in.Permission)
+	if err != nil {
+		log.Error("error in HasPermission", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.CheckPermissionResponse{Allowed: ok}, nil
+}
+
+func (s *serverAPI) ListRoles(
+	ctx context.Context,
+	in *pb.ListRolesRequest,
+) (*pb.ListRolesResponse, error) {
+	const op = "internal/grpc/permissions/server/ListRoles()"
+	log := s.l.With(slog.String("op", op))
+
+	roles, err := s.storage.ListRoles(ctx)
+	if err != nil {
+		log.Error("error in ListRoles", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	var rolesPb []*pb.Role
+	for _, r := range roles {
+		rolesPb = append(rolesPb, &pb.Role{
+			Name:        r.Name,
+			Description: r.Description,
+			Permissions: r.Permissions,
+		})
+	}
+
+	return &pb.ListRolesResponse{Roles: rolesPb}, nil
+}
+
+func (s *serverAPI) AssignRole(
+	ctx context.Context,
+	in *pb.AssignRoleRequest,
+) (*pb.AssignRoleResponse, error) {
+	const op = "internal/grpc/permissions/server/AssignRole()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetUserId() == "" || in.GetRole() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionRolesManage); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.AssignRole(ctx, in.UserId, in.Role); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user or role not found")
+			return nil, status.Error(codes.NotFound, "user or role not found")
+		}
+		log.Error("error in AssignRole", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("role assigned", slog.String("user_id", in.UserId), slog.String("role", in.Role))
+
+	return &pb.AssignRoleResponse{}, nil
+}
+
+func (s *serverAPI) RevokeRole(
+	ctx context.Context,
+	in *pb.RevokeRoleRequest,
+) (*pb.RevokeRoleResponse, error) {
+	const op = "internal/grpc/permissions/server/RevokeRole()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetUserId() == "" || in.GetRole() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if err := s.authorize(ctx, log, in.Token, in.GetAppId(), models.PermissionRolesManage); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.RevokeRole(ctx, in.UserId, in.Role); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user doesn't have role")
+			return nil, status.Error(codes.NotFound, "user doesn't have role")
+		}
+		log.Error("error in RevokeRole", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("role revoked", slog.String("user_id", in.UserId), slog.String("role", in.Role))
+
+	return &pb.RevokeRoleResponse{}, nil
+}
+
+// authorize returns grpc status error if token is invalid or its owner doesn't have permission
+func (s *serverAPI) authorize(ctx context.Context, log *slog.Logger, token string, appID int32, permission string) error {
+	c, err := s.tokens.Validate(ctx, token, appID)
+	if err != nil {
+		return grpcerr.FromToken(log, err)
+	}
+
+	return s.require(ctx, log, c.UserID, permission)
+}
+
+// require returns grpc status error if user doesn't have permission
+func (s *serverAPI) require(ctx context.Context, log *slog.Logger, userID, permission string) error {
+	ok, err := s.storage.HasPermission(ctx, userID, permission)
+	if err != nil {
+		log.Error("error in HasPermission", utils.WrapErr(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	if !ok {
+		log.Error("permission denied", slog.String("user_id", userID), slog.String("permission", permission))
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	return nil
+}
+
+// service returns name of service which passed `authorization: Service <credential>` metadata,
+// all credentials are compared in constant time, so timing doesn't tell which one is close
+func (s *serverAPI) service(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return "", false
+	}
+
+	scheme, credential, _ := strings.Cut(vals[0], " ")
+	if !strings.EqualFold(scheme, "service") || credential == "" {
+		return "", false
+	}
+
+	var res string
+	for name, c := range s.services {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(credential)) == 1 {
+			res = name
+		}
+	}
+
+	return res, res != ""
+}
+
+func PermissionToPb(p models.Permission) *pb.Permission {
+	return &pb.Permission{
+		Name:     p.Name,
+		Resource: p.Resource(),
+		Action:   p.Action(),
+	}
+}
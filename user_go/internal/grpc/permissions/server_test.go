@@ -0,0 +1,193 @@
+package permissions
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"lib/jwt"
+	"log/slog"
+	"testing"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage/memory"
+	pb "user_service/pb/permissions"
+)
+
+// fakeTokens accepts tokens equal to user IDs
+type fakeTokens struct{}
+
+func (fakeTokens) Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error) {
+	if token == "invalid" {
+		return jwt.Claims{}, jwt.ErrInvalidSignature
+	}
+	return jwt.Claims{UserID: token}, nil
+}
+
+func newUser(t *testing.T, s *memory.Storage, roles ...string) string {
+	t.Helper()
+
+	u, err := s.SaveUser(context.Background(), models.User{
+		Email:       uuid.New().String() + "@example.org",
+		EncPassword: []byte("hash"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range roles {
+		if err := s.AssignRole(context.Background(), u.ID, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return u.ID
+}
+
+func TestCheckPermission(t *testing.T) {
+	s := memory.New()
+	srv := &serverAPI{
+		storage:  s,
+		l:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		tokens:   fakeTokens{},
+		services: map[string]string{"files": "files-credential"},
+	}
+
+	user := newUser(t, s)
+	admin := newUser(t, s, models.RoleAdmin)
+
+	tests := []struct {
+		name        string
+		credential  string
+		token       string
+		userID      string
+		permission  string
+		wantAllowed bool
+		wantCode    codes.Code
+	}{
+		{
+			name:       "unauthenticated",
+			userID:     user,
+			permission: models.PermissionFilesRead,
+			wantCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "unknown service credential",
+			credential: "Service other-credential",
+			userID:     user,
+			permission: models.PermissionFilesRead,
+			wantCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "invalid token",
+			token:      "invalid",
+			userID:     user,
+			permission: models.PermissionFilesRead,
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:        "service, permission of default role",
+			credential:  "Service files-credential",
+			userID:      user,
+			permission:  models.PermissionFilesRead,
+			wantAllowed: true,
+		},
+		{
+			name:       "service, permission of other role",
+			credential: "Service files-credential",
+			userID:     user,
+			permission: models.PermissionFilesReadAny,
+		},
+		{
+			name:        "service, permission of assigned role",
+			credential:  "Service files-credential",
+			userID:      admin,
+			permission:  models.PermissionFilesReadAny,
+			wantAllowed: true,
+		},
+		{
+			name:       "service, unknown user",
+			credential: "Service files-credential",
+			userID:     "unknown",
+			permission: models.PermissionFilesRead,
+		},
+		{
+			name:        "own permission",
+			token:       user,
+			userID:      user,
+			permission:  models.PermissionFilesRead,
+			wantAllowed: true,
+		},
+		{
+			name:       "own missing permission",
+			token:      user,
+			userID:     user,
+			permission: models.PermissionRolesManage,
+		},
+		{
+			name:       "permission of other user",
+			token:      user,
+			userID:     admin,
+			permission: models.PermissionFilesReadAny,
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:       "permission of other user by roles manager",
+			token:      admin,
+			userID:     user,
+			permission: models.PermissionFilesReadAny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.credential != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.credential))
+			}
+
+			res, err := srv.CheckPermission(ctx, &pb.CheckPermissionRequest{
+				UserId:     tt.userID,
+				Permission: tt.permission,
+				Token:      tt.token,
+			})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("CheckPermission() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+			if err == nil && res.GetAllowed() != tt.wantAllowed {
+				t.Errorf("CheckPermission() allowed = %v, want %v", res.GetAllowed(), tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestGetUserPermissions(t *testing.T) {
+	s := memory.New()
+	srv := &serverAPI{storage: s, l: slog.New(slog.NewTextHandler(io.Discard, nil)), tokens: fakeTokens{}}
+
+	admin := newUser(t, s, models.RoleAdmin)
+
+	res, err := srv.GetUserPermissions(context.Background(), &pb.GetUserPermissionsRequest{Token: admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.GetUserId() != admin {
+		t.Errorf("GetUserPermissions() user_id = %v, want %v", res.GetUserId(), admin)
+	}
+	if len(res.GetRoles()) != 2 {
+		t.Errorf("GetUserPermissions() roles = %v, want user and admin", res.GetRoles())
+	}
+
+	// permissions of all roles are resolved, each once
+	got := make(map[string]int)
+	for _, p := range res.GetPermissions() {
+		got[p.GetName()]++
+	}
+	for _, name := range []string{models.PermissionFilesRead, models.PermissionFilesReadAny, models.PermissionRolesManage} {
+		if got[name] != 1 {
+			t.Errorf("GetUserPermissions() has %s %d times, want once", name, got[name])
+		}
+	}
+}
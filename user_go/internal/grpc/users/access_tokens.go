@@ -0,0 +1,144 @@
+package users
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/lib/utils"
+	pb "user_service/pb/users"
+)
+
+// maxAccessTokenNameLen is length of name column of access_tokens
+const maxAccessTokenNameLen = 255
+
+func (s *serverAPI) CreateAccessToken(
+	ctx context.Context,
+	in *pb.CreateAccessTokenRequest,
+) (*pb.CreateAccessTokenResponse, error) {
+	const op = "internal/grpc/users/access_tokens/CreateAccessToken()"
+	log := s.l.With(slog.String("op", op))
+
+	name := strings.TrimSpace(in.GetName())
+	if in.GetToken() == "" || name == "" || len(in.GetScopes()) == 0 {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if len(name) > maxAccessTokenNameLen {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "name is too long")
+	}
+
+	var expiresAt time.Time
+	if in.GetExpiresAt() != 0 {
+		expiresAt = time.Unix(in.ExpiresAt, 0)
+		if !expiresAt.After(time.Now()) {
+			log.Error("haven't passed validation")
+			return nil, status.Error(codes.InvalidArgument, "expiration time is in the past")
+		}
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	token, t, err := s.accessTokens.Create(ctx, c.UserID, name, in.Scopes, expiresAt)
+	if err != nil {
+		return nil, grpcerr.From(log, err)
+	}
+
+	log.Info("access token created", slog.String("user_id", c.UserID), slog.String("access_token_id", t.ID))
+
+	return &pb.CreateAccessTokenResponse{AccessToken: token, Details: AccessTokenToPb(t)}, nil
+}
+
+func (s *serverAPI) ListAccessTokens(
+	ctx context.Context,
+	in *pb.ListAccessTokensRequest,
+) (*pb.ListAccessTokensResponse, error) {
+	const op = "internal/grpc/users/access_tokens/ListAccessTokens()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	list, err := s.accessTokens.List(ctx, c.UserID)
+	if err != nil {
+		log.Error("error in List", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	res := &pb.ListAccessTokensResponse{}
+	for _, t := range list {
+		res.AccessTokens = append(res.AccessTokens, AccessTokenToPb(t))
+	}
+
+	return res, nil
+}
+
+func (s *serverAPI) RevokeAccessToken(
+	ctx context.Context,
+	in *pb.RevokeAccessTokenRequest,
+) (*pb.RevokeAccessTokenResponse, error) {
+	const op = "internal/grpc/users/access_tokens/RevokeAccessToken()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetAccessTokenId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	if err := s.accessTokens.Revoke(ctx, c.UserID, in.AccessTokenId); err != nil {
+		return nil, grpcerr.From(log.With(slog.String("access_token_id", in.AccessTokenId)), err)
+	}
+
+	log.Info("access token revoked", slog.String("user_id", c.UserID), slog.String("access_token_id", in.AccessTokenId))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditTokenRevoke,
+		Outcome: models.AuditSuccess,
+		ActorID: c.UserID,
+		UserID:  c.UserID,
+		AppID:   c.AppID,
+		Details: "access token " + in.AccessTokenId,
+	})
+
+	return &pb.RevokeAccessTokenResponse{}, nil
+}
+
+func AccessTokenToPb(t models.AccessToken) *pb.AccessToken {
+	res := &pb.AccessToken{
+		Id:        t.ID,
+		Name:      t.Name,
+		Scopes:    t.Scopes,
+		Hint:      t.Hint,
+		CreatedAt: t.CreatedAt.Unix(),
+	}
+
+	if !t.ExpiresAt.IsZero() {
+		res.ExpiresAt = t.ExpiresAt.Unix()
+	}
+	if !t.LastUsedAt.IsZero() {
+		res.LastUsedAt = t.LastUsedAt.Unix()
+	}
+
+	return res
+}
@@ -0,0 +1,93 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+	"user_service/lib/utils"
+	pb "user_service/pb/users"
+)
+
+func (s *serverAPI) ListIdentities(
+	ctx context.Context,
+	in *pb.ListIdentitiesRequest,
+) (*pb.ListIdentitiesResponse, error) {
+	const op = "internal/grpc/users/identities/ListIdentities()"
+	log := s.l.With(slog.String("op", op))
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.storage.UserIdentities(ctx, u.ID)
+	if err != nil {
+		log.Error("error in UserIdentities", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	res := &pb.ListIdentitiesResponse{}
+	for _, id := range list {
+		res.Identities = append(res.Identities, IdentityToPb(id))
+	}
+
+	return res, nil
+}
+
+func (s *serverAPI) UnlinkIdentity(
+	ctx context.Context,
+	in *pb.UnlinkIdentityRequest,
+) (*pb.UnlinkIdentityResponse, error) {
+	const op = "internal/grpc/users/identities/UnlinkIdentity()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetIdentityId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	// user without password could log in only with identities
+	if !u.HasPassword() {
+		list, err := s.storage.UserIdentities(ctx, u.ID)
+		if err != nil {
+			log.Error("error in UserIdentities", utils.WrapErr(err))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		if len(list) == 1 && list[0].ID == in.IdentityId {
+			log.Error("last identity of user without password", slog.String("user_id", u.ID))
+			return nil, status.Error(codes.FailedPrecondition, "set password before unlinking the last identity")
+		}
+	}
+
+	if err := s.storage.DeleteIdentity(ctx, u.ID, in.IdentityId); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("identity not found", slog.String("identity_id", in.IdentityId))
+			return nil, status.Error(codes.NotFound, "identity not found")
+		}
+		log.Error("error in DeleteIdentity", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("identity unlinked", slog.String("user_id", u.ID), slog.String("identity_id", in.IdentityId))
+
+	return &pb.UnlinkIdentityResponse{}, nil
+}
+
+func IdentityToPb(id models.Identity) *pb.Identity {
+	return &pb.Identity{
+		Id:        id.ID,
+		Provider:  id.Provider,
+		Email:     id.Email,
+		CreatedAt: id.CreatedAt.Unix(),
+	}
+}
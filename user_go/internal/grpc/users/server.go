@@ -0,0 +1,381 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lib/jwt"
+	"log/slog"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/clientip"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/internal/storage"
+	"user_service/lib/utils"
+	pb "user_service/pb/users"
+)
+
+// maxDisplayNameLen is length of display_name column
+const maxDisplayNameLen = 255
+
+type serverAPI struct {
+	pb.UnimplementedUsersServer
+	storage      Storage
+	l            *slog.Logger
+	tokens       Tokens
+	sessions     Sessions
+	accessTokens AccessTokens
+	verification Verification
+	hasher       models.PasswordHasher
+	policy       Policy
+	audit        Audit
+	limiter      Limiter
+}
+
+type Storage interface {
+	FindUserByID(ctx context.Context, id string) (models.User, error)
+	UpdateProfile(ctx context.Context, userID, displayName string) error
+	ChangeEmail(ctx context.Context, userID, email string) error
+	UpdatePassword(ctx context.Context, userID string, encPassword []byte) error
+	DeleteUser(ctx context.Context, userID string, at time.Time) error
+	UserIdentities(ctx context.Context, userID string) ([]models.Identity, error)
+	DeleteIdentity(ctx context.Context, userID, id string) error
+}
+
+type Tokens interface {
+	Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error)
+	// RevokeAll revokes sessions of user with his personal access and OAuth tokens
+	RevokeAll(ctx context.Context, userID string) error
+}
+
+// Sessions lists and revokes logins of user, it's implemented by sessions.Manager
+type Sessions interface {
+	List(ctx context.Context, userID string) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, id string) error
+}
+
+// AccessTokens manages personal access tokens, it's implemented by accesstokens.Manager
+type AccessTokens interface {
+	Create(ctx context.Context, userID, name string, scopes []string, expiresAt time.Time) (string, models.AccessToken, error)
+	List(ctx context.Context, userID string) ([]models.AccessToken, error)
+	Revoke(ctx context.Context, userID, id string) error
+}
+
+// Verification sends email verification tokens, it's implemented by verification.Manager
+type Verification interface {
+	Send(ctx context.Context, u models.User) error
+}
+
+// Policy checks new emails and passwords, it's implemented by policy.Policy
+type Policy interface {
+	CheckEmail(email string) (string, error)
+	CheckPassword(password string) error
+}
+
+// Audit records password changes and revocations, it's implemented by audit.Log
+type Audit interface {
+	RecordOrLog(ctx context.Context, log *slog.Logger, e models.AuditEvent)
+}
+
+// Limiter throttles password guessing, it's implemented by throttle.Limiter.
+// Password checks of logged in user share failures with Login, so stolen token
+// doesn't give more attempts to guess the password.
+type Limiter interface {
+	CheckIP(ctx context.Context, ip string) error
+	CheckAccount(ctx context.Context, userID string) error
+	Failure(ctx context.Context, userID, ip string) error
+	Success(ctx context.Context, userID string) error
+}
+
+func Register(
+	grpcServer *grpc.Server,
+	storage Storage,
+	logger *slog.Logger,
+	tokens Tokens,
+	sessions Sessions,
+	accessTokens AccessTokens,
+	verification Verification,
+	hasher models.PasswordHasher,
+	policy Policy,
+	audit Audit,
+	limiter Limiter,
+) {
+	pb.RegisterUsersServer(grpcServer, &serverAPI{
+		storage:      storage,
+		l:            logger,
+		tokens:       tokens,
+		sessions:     sessions,
+		accessTokens: accessTokens,
+		verification: verification,
+		hasher:       hasher,
+		policy:       policy,
+		audit:        audit,
+		limiter:      limiter,
+	})
+}
+
+func (s *serverAPI) GetMe(
+	ctx context.Context,
+	in *pb.GetMeRequest,
+) (*pb.Profile, error) {
+	const op = "internal/grpc/users/server/GetMe()"
+	log := s.l.With(slog.String("op", op))
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	return ProfileToPb(u), nil
+}
+
+func (s *serverAPI) UpdateProfile(
+	ctx context.Context,
+	in *pb.UpdateProfileRequest,
+) (*pb.Profile, error) {
+	const op = "internal/grpc/users/server/UpdateProfile()"
+	log := s.l.With(slog.String("op", op))
+
+	displayName := strings.TrimSpace(in.GetDisplayName())
+	if len(displayName) > maxDisplayNameLen {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "display name is too long")
+	}
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.UpdateProfile(ctx, u.ID, displayName); err != nil {
+		log.Error("error in UpdateProfile", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	u.DisplayName = displayName
+
+	return ProfileToPb(u), nil
+}
+
+func (s *serverAPI) ChangeEmail(
+	ctx context.Context,
+	in *pb.ChangeEmailRequest,
+) (*pb.ChangeEmailResponse, error) {
+	const op = "internal/grpc/users/server/ChangeEmail()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetPassword() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	email, err := s.policy.CheckEmail(in.GetEmail())
+	if err != nil {
+		return nil, grpcerr.FromPolicy(log, err)
+	}
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPassword(ctx, log, u, in.Password); err != nil {
+		return nil, err
+	}
+
+	if email == u.Email {
+		return &pb.ChangeEmailResponse{}, nil
+	}
+
+	if err := s.storage.ChangeEmail(ctx, u.ID, email); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			log.Error("email already used")
+			return nil, status.Error(codes.AlreadyExists, "email already used")
+		}
+		log.Error("error in ChangeEmail", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("email changed", slog.String("user_id", u.ID))
+
+	// email is changed anyway, user can ask for another email with ResendVerification
+	u.Email, u.EmailVerified = email, false
+	if err := s.verification.Send(ctx, u); err != nil {
+		log.Error("cant send verification email", utils.WrapErr(err))
+	}
+
+	return &pb.ChangeEmailResponse{}, nil
+}
+
+func (s *serverAPI) ChangePassword(
+	ctx context.Context,
+	in *pb.ChangePasswordRequest,
+) (*pb.ChangePasswordResponse, error) {
+	const op = "internal/grpc/users/server/ChangePassword()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetOldPassword() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	if err := s.policy.CheckPassword(in.GetNewPassword()); err != nil {
+		return nil, grpcerr.FromPolicy(log, err)
+	}
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPassword(ctx, log, u, in.OldPassword); err != nil {
+		if status.Code(err) == codes.PermissionDenied {
+			s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+				Type:    models.AuditPasswordChange,
+				Outcome: models.AuditFailure,
+				ActorID: u.ID,
+				UserID:  u.ID,
+				Details: "invalid password",
+			})
+		}
+		return nil, err
+	}
+
+	u.Password = in.NewPassword
+	if err := u.EncryptPassword(s.hasher); err != nil {
+		log.Error("cant hash password", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if err := s.storage.UpdatePassword(ctx, u.ID, u.EncPassword); err != nil {
+		log.Error("error in UpdatePassword", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("password changed", slog.String("user_id", u.ID))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditPasswordChange,
+		Outcome: models.AuditSuccess,
+		ActorID: u.ID,
+		UserID:  u.ID,
+	})
+
+	// sessions could be opened with old password by someone else
+	if err := s.tokens.RevokeAll(ctx, u.ID); err != nil {
+		log.Error("cant revoke sessions", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.ChangePasswordResponse{}, nil
+}
+
+func (s *serverAPI) DeleteAccount(
+	ctx context.Context,
+	in *pb.DeleteAccountRequest,
+) (*pb.DeleteAccountResponse, error) {
+	const op = "internal/grpc/users/server/DeleteAccount()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetPassword() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	u, err := s.user(ctx, log, in.GetToken(), in.GetAppId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPassword(ctx, log, u, in.Password); err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.DeleteUser(ctx, u.ID, time.Now()); err != nil {
+		log.Error("error in DeleteUser", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Info("account deleted", slog.String("user_id", u.ID))
+
+	if err := s.tokens.RevokeAll(ctx, u.ID); err != nil {
+		log.Error("cant revoke sessions", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.DeleteAccountResponse{}, nil
+}
+
+// checkPassword compares password of logged in user with the same throttling as Login:
+// locked account or IP is rejected before comparing and invalid password counts as failed login.
+// Error is grpc status error, PermissionDenied for invalid password.
+// User without password, e.g. created by login with identity provider, gets FailedPrecondition,
+// it isn't failed login: there is nothing to guess.
+func (s *serverAPI) checkPassword(ctx context.Context, log *slog.Logger, u models.User, password string) error {
+	if !u.HasPassword() {
+		log.Error("user has no password", slog.String("user_id", u.ID))
+		return status.Error(codes.FailedPrecondition, "user has no password, set it with password reset")
+	}
+
+	ip := clientip.FromContext(ctx)
+
+	if err := s.limiter.CheckIP(ctx, ip); err != nil {
+		return grpcerr.FromLock(log, err)
+	}
+
+	if err := s.limiter.CheckAccount(ctx, u.ID); err != nil {
+		return grpcerr.FromLock(log, err)
+	}
+
+	if ok, _ := u.ComparePassword(s.hasher, password); !ok {
+		log.Error("invalid password")
+		if err := s.limiter.Failure(ctx, u.ID, ip); err != nil {
+			log.Error("error in Failure", utils.WrapErr(err))
+		}
+		return status.Error(codes.PermissionDenied, "invalid password")
+	}
+
+	if err := s.limiter.Success(ctx, u.ID); err != nil {
+		log.Error("error in Success", utils.WrapErr(err))
+	}
+
+	return nil
+}
+
+// user validates token presented to app and returns its owner, error is grpc status error
+func (s *serverAPI) user(ctx context.Context, log *slog.Logger, token string, appID int32) (models.User, error) {
+	if token == "" {
+		log.Error("haven't passed validation")
+		return models.User{}, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, token, appID)
+	if err != nil {
+		return models.User{}, grpcerr.FromToken(log, err)
+	}
+
+	u, err := s.storage.FindUserByID(ctx, c.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Error("user not found", slog.String("user_id", c.UserID))
+			return models.User{}, status.Error(codes.Unauthenticated, "user deleted")
+		}
+		log.Error("error in FindUserByID", utils.WrapErr(err))
+		return models.User{}, status.Error(codes.Internal, "internal error")
+	}
+
+	return u, nil
+}
+
+func ProfileToPb(u models.User) *pb.Profile {
+	return &pb.Profile{
+		UserId:        u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		DisplayName:   u.DisplayName,
+		CreatedAt:     u.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,198 @@
+package users
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"lib/jwt"
+	"log/slog"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/policy"
+	"user_service/internal/storage/memory"
+	"user_service/internal/throttle"
+	"user_service/lib/passhash"
+	pb "user_service/pb/users"
+)
+
+const password = "password-1"
+
+var accountPolicy = throttle.Policy{MaxFailures: 3, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: time.Hour}
+
+// fakeTokens accepts tokens equal to user IDs
+type fakeTokens struct{}
+
+func (fakeTokens) Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error) {
+	return jwt.Claims{UserID: token}, nil
+}
+
+func (fakeTokens) RevokeAll(ctx context.Context, userID string) error {
+	return nil
+}
+
+type fakeVerification struct{}
+
+func (fakeVerification) Send(ctx context.Context, u models.User) error {
+	return nil
+}
+
+type fakeAudit struct{}
+
+func (fakeAudit) RecordOrLog(ctx context.Context, log *slog.Logger, e models.AuditEvent) {}
+
+func newServer(t *testing.T) (*serverAPI, *memory.Storage) {
+	st := memory.New()
+
+	return &serverAPI{
+		storage:      st,
+		l:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		tokens:       fakeTokens{},
+		verification: fakeVerification{},
+		hasher:       passhash.New(passhash.Bcrypt{Cost: 4}),
+		policy:       policy.New(policy.Password{MinLength: 8}, nil),
+		audit:        fakeAudit{},
+		limiter:      throttle.New(st, accountPolicy, throttle.Policy{}),
+	}, st
+}
+
+func newUser(t *testing.T, s *serverAPI, st *memory.Storage) string {
+	t.Helper()
+
+	u := models.User{Email: uuid.New().String() + "@example.org", Password: password}
+	if err := u.EncryptPassword(s.hasher); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := st.SaveUser(context.Background(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u.ID
+}
+
+// passwordChecks are calls which ask logged in user for password
+var passwordChecks = []struct {
+	name string
+	call func(s *serverAPI, token, password string) error
+}{
+	{
+		name: "ChangeEmail",
+		call: func(s *serverAPI, token, password string) error {
+			_, err := s.ChangeEmail(context.Background(), &pb.ChangeEmailRequest{
+				Token: token, Password: password, Email: uuid.New().String() + "@example.org",
+			})
+			return err
+		},
+	},
+	{
+		name: "ChangePassword",
+		call: func(s *serverAPI, token, password string) error {
+			_, err := s.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+				Token: token, OldPassword: password, NewPassword: password,
+			})
+			return err
+		},
+	},
+	{
+		name: "DeleteAccount",
+		call: func(s *serverAPI, token, password string) error {
+			_, err := s.DeleteAccount(context.Background(), &pb.DeleteAccountRequest{Token: token, Password: password})
+			return err
+		},
+	},
+}
+
+func TestPasswordCheck_Throttled(t *testing.T) {
+	for _, tt := range passwordChecks {
+		t.Run(tt.name, func(t *testing.T) {
+			s, st := newServer(t)
+			userID := newUser(t, s, st)
+
+			for i := 0; i < accountPolicy.MaxFailures; i++ {
+				if err := tt.call(s, userID, "wrong-password"); status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("%s() with wrong password code = %v, want %v", tt.name, status.Code(err), codes.PermissionDenied)
+				}
+			}
+
+			// password can't be guessed any more, even the right one is rejected
+			if err := tt.call(s, userID, password); status.Code(err) != codes.ResourceExhausted {
+				t.Fatalf("%s() of locked account code = %v, want %v", tt.name, status.Code(err), codes.ResourceExhausted)
+			}
+
+			// and Login is locked too
+			if err := s.limiter.CheckAccount(context.Background(), userID); err == nil {
+				t.Errorf("CheckAccount() error = nil, want account locked")
+			}
+		})
+	}
+}
+
+func TestPasswordCheck_SharesFailuresWithLogin(t *testing.T) {
+	for _, tt := range passwordChecks {
+		t.Run(tt.name, func(t *testing.T) {
+			s, st := newServer(t)
+			userID := newUser(t, s, st)
+
+			// failed logins lock password checks of logged in user
+			for i := 0; i < accountPolicy.MaxFailures; i++ {
+				if err := s.limiter.Failure(context.Background(), userID, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := tt.call(s, userID, password); status.Code(err) != codes.ResourceExhausted {
+				t.Errorf("%s() after failed logins code = %v, want %v", tt.name, status.Code(err), codes.ResourceExhausted)
+			}
+		})
+	}
+}
+
+func TestPasswordCheck_SuccessResetsFailures(t *testing.T) {
+	s, st := newServer(t)
+	userID := newUser(t, s, st)
+
+	changePassword := passwordChecks[1].call
+
+	for round := 0; round < 2; round++ {
+		for i := 0; i < accountPolicy.MaxFailures-1; i++ {
+			if err := changePassword(s, userID, "wrong-password"); status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("ChangePassword() with wrong password code = %v, want %v", status.Code(err), codes.PermissionDenied)
+			}
+		}
+
+		if err := changePassword(s, userID, password); err != nil {
+			t.Fatalf("ChangePassword() round %d error = %v, want nil", round, err)
+		}
+	}
+}
+
+func TestPasswordCheck_NoPassword(t *testing.T) {
+	for _, tt := range passwordChecks {
+		t.Run(tt.name, func(t *testing.T) {
+			s, st := newServer(t)
+
+			// user created by login with identity provider
+			email := uuid.New().String() + "@example.org"
+			u, err := st.SaveFederatedUser(context.Background(), models.User{Email: email, EmailVerified: true},
+				models.Identity{Provider: "google", Subject: uuid.New().String(), Email: email})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < accountPolicy.MaxFailures; i++ {
+				if err := tt.call(s, u.ID, "any-password"); status.Code(err) != codes.FailedPrecondition {
+					t.Fatalf("%s() of user without password code = %v, want %v", tt.name, status.Code(err), codes.FailedPrecondition)
+				}
+			}
+
+			// it isn't failed login, account isn't locked
+			if err := s.limiter.CheckAccount(context.Background(), u.ID); err != nil {
+				t.Errorf("CheckAccount() error = %v, want nil", err)
+			}
+		})
+	}
+}
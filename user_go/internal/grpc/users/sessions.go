@@ -0,0 +1,91 @@
+package users
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/grpcerr"
+	"user_service/lib/utils"
+	pb "user_service/pb/users"
+)
+
+func (s *serverAPI) ListSessions(
+	ctx context.Context,
+	in *pb.ListSessionsRequest,
+) (*pb.ListSessionsResponse, error) {
+	const op = "internal/grpc/users/sessions/ListSessions()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	list, err := s.sessions.List(ctx, c.UserID)
+	if err != nil {
+		log.Error("error in List", utils.WrapErr(err))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	res := &pb.ListSessionsResponse{}
+	for _, sess := range list {
+		res.Sessions = append(res.Sessions, SessionToPb(sess, c.SessionID))
+	}
+
+	return res, nil
+}
+
+func (s *serverAPI) RevokeSession(
+	ctx context.Context,
+	in *pb.RevokeSessionRequest,
+) (*pb.RevokeSessionResponse, error) {
+	const op = "internal/grpc/users/sessions/RevokeSession()"
+	log := s.l.With(slog.String("op", op))
+
+	if in.GetToken() == "" || in.GetSessionId() == "" {
+		log.Error("haven't passed validation")
+		return nil, status.Error(codes.InvalidArgument, "incorrect request")
+	}
+
+	c, err := s.tokens.Validate(ctx, in.Token, in.GetAppId())
+	if err != nil {
+		return nil, grpcerr.FromToken(log, err)
+	}
+
+	if err := s.sessions.RevokeSession(ctx, c.UserID, in.SessionId); err != nil {
+		return nil, grpcerr.From(log.With(slog.String("session_id", in.SessionId)), err)
+	}
+
+	log.Info("session revoked", slog.String("user_id", c.UserID), slog.String("session_id", in.SessionId))
+
+	s.audit.RecordOrLog(ctx, log, models.AuditEvent{
+		Type:    models.AuditTokenRevoke,
+		Outcome: models.AuditSuccess,
+		ActorID: c.UserID,
+		UserID:  c.UserID,
+		AppID:   c.AppID,
+		Details: "session " + in.SessionId,
+	})
+
+	return &pb.RevokeSessionResponse{}, nil
+}
+
+// SessionToPb converts session, current is ID of session of the request
+func SessionToPb(sess models.Session, current string) *pb.Session {
+	return &pb.Session{
+		SessionId:  sess.ID,
+		AppId:      sess.AppID,
+		UserAgent:  sess.UserAgent,
+		Ip:         sess.IP,
+		CreatedAt:  sess.CreatedAt.Unix(),
+		LastUsedAt: sess.LastUsedAt.Unix(),
+		Current:    sess.ID == current,
+	}
+}
@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox writes emails to directory as .eml files instead of sending them,
+// it's used for local development
+type Outbox struct {
+	dir  string
+	from string
+}
+
+func NewOutbox(dir, from string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Outbox{dir: dir, from: from}, nil
+}
+
+func (o *Outbox) Send(ctx context.Context, m Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102150405"), uuid.New().String())
+
+	return os.WriteFile(filepath.Join(o.dir, name), format(o.from, m), 0o644)
+}
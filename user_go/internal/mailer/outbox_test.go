@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutbox_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+
+	o, err := NewOutbox(dir, "no-reply@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = o.Send(context.Background(), Message{
+		To:      "user1@example.org",
+		Subject: "Confirm your email",
+		Body:    "line 1\nline 2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Send() wrote %d files, want 1", len(files))
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"From: no-reply@example.org\r\n",
+		"To: user1@example.org\r\n",
+		"Subject: Confirm your email\r\n",
+		"\r\n\r\nline 1\r\nline 2",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Send() message = %q, want it to contain %q", data, want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"lib/errs"
+	"lib/securetoken"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+	"user_service/lib/totp"
+)
+
+var (
+	ErrNotEnrolled      = errs.New(errs.ErrFailedPrecondition, "mfa not enrolled")
+	ErrAlreadyEnabled   = errs.New(errs.ErrFailedPrecondition, "mfa already enabled")
+	ErrInvalidCode      = errs.New(errs.ErrInvalid, "invalid mfa code")
+	ErrInvalidChallenge = errs.New(errs.ErrInvalid, "invalid mfa challenge")
+	ErrChallengeExpired = errs.New(errs.ErrUnauthenticated, "mfa challenge expired")
+)
+
+const (
+	// skew is number of time steps codes are accepted before and after current one
+	skew = 1
+
+	recoveryCodesCount = 10
+	recoveryCodeLen    = 10
+	// recoveryCodeAlphabet has no look-alike characters, e.g. 0 and o
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+type Storage interface {
+	GetMFA(ctx context.Context, userID string) (models.MFA, error)
+	// SaveMFA saves pending authenticator, it replaces pending one but not enabled
+	SaveMFA(ctx context.Context, m models.MFA) error
+	// EnableMFA enables authenticator and saves hashes of recovery codes in one transaction
+	EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	// UseTOTPStep sets last used step, storage.ErrAlreadyExists is returned if it isn't after the last one
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	// UseRecoveryCode marks code as used, storage.ErrNotFound is returned if there is no unused code
+	UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) error
+	DeleteMFA(ctx context.Context, userID string) error
+}
+
+// Manager enrolls TOTP authenticators and checks codes of the second login step.
+// Login with MFA returns challenge instead of tokens, challenge is signed, short-lived
+// and is exchanged for tokens with code of authenticator or recovery code.
+type Manager struct {
+	storage      Storage
+	issuer       string
+	secret       []byte
+	challengeTTL time.Duration
+}
+
+func New(storage Storage, issuer, challengeSecret string, challengeTTL time.Duration) *Manager {
+	return &Manager{
+		storage:      storage,
+		issuer:       issuer,
+		secret:       []byte(challengeSecret),
+		challengeTTL: challengeTTL,
+	}
+}
+
+// Enroll creates pending authenticator of user, returns its secret and otpauth URI.
+// Authenticator isn't used for login until it's confirmed.
+func (m *Manager) Enroll(ctx context.Context, u models.User) (secret, uri string, err error) {
+	current, err := m.storage.GetMFA(ctx, u.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return "", "", err
+	}
+	if current.Enabled {
+		return "", "", ErrAlreadyEnabled
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = m.storage.SaveMFA(ctx, models.MFA{UserID: u.ID, Secret: secret, CreatedAt: time.Now()})
+	if err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			// enabled concurrently
+			return "", "", ErrAlreadyEnabled
+		}
+		return "", "", err
+	}
+
+	return secret, totp.ProvisioningURI(m.issuer, u.Email, secret), nil
+}
+
+// Confirm enables pending authenticator with its first code and returns recovery codes,
+// they are shown to user once, only their hashes are stored.
+func (m *Manager) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	current, err := m.get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(current.Secret, code, time.Now(), skew)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, securetoken.Hash(c))
+	}
+
+	if err := m.storage.EnableMFA(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, ErrAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable removes authenticator and recovery codes, code of authenticator or recovery code is required
+func (m *Manager) Disable(ctx context.Context, userID, code string) error {
+	if err := m.CheckCode(ctx, userID, code); err != nil {
+		return err
+	}
+
+	return m.storage.DeleteMFA(ctx, userID)
+}
+
+// IsEnabled returns true if user has confirmed authenticator
+func (m *Manager) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	current, err := m.storage.GetMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return current.Enabled, nil
+}
+
+// CheckCode accepts code of enabled authenticator or unused recovery code.
+// Each code is accepted once.
+func (m *Manager) CheckCode(ctx context.Context, userID, code string) error {
+	current, err := m.get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !current.Enabled {
+		return ErrNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(current.Secret, code, time.Now(), skew); ok {
+		if err := m.storage.UseTOTPStep(ctx, userID, step); err != nil {
+			if errors.Is(err, storage.ErrAlreadyExists) {
+				return ErrInvalidCode
+			}
+			return err
+		}
+		return nil
+	}
+
+	err = m.storage.UseRecoveryCode(ctx, userID, securetoken.Hash(strings.ToLower(code)), time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrInvalidCode
+		}
+		return err
+	}
+
+	return nil
+}
+
+// NewChallenge returns challenge of second login step of user to app
+func (m *Manager) NewChallenge(userID string, appID int32) (string, error) {
+	nonce, _, err := securetoken.New()
+	if err != nil {
+		return "", err
+	}
+
+	exp := time.Now().Add(m.challengeTTL).Unix()
+
+	return securetoken.Sign(m.secret, fmt.Sprintf("%s.%d.%d.%s", userID, appID, exp, nonce)), nil
+}
+
+// ParseChallenge checks challenge signature and expiration time and returns user and app of the login
+func (m *Manager) ParseChallenge(challenge string) (userID string, appID int32, err error) {
+	payload, err := securetoken.Verify(m.secret, challenge)
+	if err != nil {
+		return "", 0, ErrInvalidChallenge
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 {
+		return "", 0, ErrInvalidChallenge
+	}
+
+	app, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil {
+		return "", 0, ErrInvalidChallenge
+	}
+
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidChallenge
+	}
+
+	if !time.Now().Before(time.Unix(exp, 0)) {
+		return "", 0, ErrChallengeExpired
+	}
+
+	return parts[0], int32(app), nil
+}
+
+func (m *Manager) get(ctx context.Context, userID string) (models.MFA, error) {
+	current, err := m.storage.GetMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.MFA{}, ErrNotEnrolled
+		}
+		return models.MFA{}, err
+	}
+
+	return current, nil
+}
+
+// newRecoveryCode returns random code of recoveryCodeAlphabet
+func newRecoveryCode() (string, error) {
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	b := make([]byte, recoveryCodeLen)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = recoveryCodeAlphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
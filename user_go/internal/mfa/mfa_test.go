@@ -0,0 +1,184 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+	"user_service/lib/totp"
+)
+
+// fakeStorage keeps authenticators by user ID and recovery codes by hash in maps
+type fakeStorage struct {
+	mfa       map[string]models.MFA
+	recovery  map[string]string // hash -> user ID
+	usedCodes map[string]bool
+}
+
+func (f *fakeStorage) GetMFA(_ context.Context, userID string) (models.MFA, error) {
+	m, ok := f.mfa[userID]
+	if !ok {
+		return models.MFA{}, storage.ErrNotFound
+	}
+	return m, nil
+}
+
+func (f *fakeStorage) SaveMFA(_ context.Context, m models.MFA) error {
+	if f.mfa[m.UserID].Enabled {
+		return storage.ErrAlreadyExists
+	}
+	f.mfa[m.UserID] = m
+	return nil
+}
+
+func (f *fakeStorage) EnableMFA(_ context.Context, userID string, step int64, hashes []string) error {
+	m := f.mfa[userID]
+	if m.Enabled {
+		return storage.ErrAlreadyExists
+	}
+	m.Enabled = true
+	m.LastUsedStep = step
+	f.mfa[userID] = m
+	for _, h := range hashes {
+		f.recovery[h] = userID
+	}
+	return nil
+}
+
+func (f *fakeStorage) UseTOTPStep(_ context.Context, userID string, step int64) error {
+	m := f.mfa[userID]
+	if step <= m.LastUsedStep {
+		return storage.ErrAlreadyExists
+	}
+	m.LastUsedStep = step
+	f.mfa[userID] = m
+	return nil
+}
+
+func (f *fakeStorage) UseRecoveryCode(_ context.Context, userID, codeHash string, _ time.Time) error {
+	if f.recovery[codeHash] != userID || f.usedCodes[codeHash] {
+		return storage.ErrNotFound
+	}
+	f.usedCodes[codeHash] = true
+	return nil
+}
+
+func (f *fakeStorage) DeleteMFA(_ context.Context, userID string) error {
+	delete(f.mfa, userID)
+	for h, id := range f.recovery {
+		if id == userID {
+			delete(f.recovery, h)
+		}
+	}
+	return nil
+}
+
+var user = models.User{ID: "user-id", Email: "user1@example.org"}
+
+func newManager(challengeTTL time.Duration) *Manager {
+	return New(&fakeStorage{
+		mfa:       map[string]models.MFA{},
+		recovery:  map[string]string{},
+		usedCodes: map[string]bool{},
+	}, "test", "challenge-secret", challengeTTL)
+}
+
+// code returns code of secret for current time step plus offset
+func code(t *testing.T, secret string, offset int64) string {
+	c, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestManager_Enroll(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(time.Minute)
+
+	secret, _, err := m.Enroll(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if enabled, _ := m.IsEnabled(ctx, user.ID); enabled {
+		t.Error("IsEnabled() = true before confirmation")
+	}
+
+	if _, err := m.Confirm(ctx, user.ID, "000000x"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Confirm() error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	recoveryCodes, err := m.Confirm(ctx, user.ID, code(t, secret, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recoveryCodes) != recoveryCodesCount {
+		t.Errorf("Confirm() got %d recovery codes, want %d", len(recoveryCodes), recoveryCodesCount)
+	}
+
+	if enabled, _ := m.IsEnabled(ctx, user.ID); !enabled {
+		t.Error("IsEnabled() = false after confirmation")
+	}
+
+	if _, _, err := m.Enroll(ctx, user); !errors.Is(err, ErrAlreadyEnabled) {
+		t.Errorf("Enroll() error = %v, want %v", err, ErrAlreadyEnabled)
+	}
+
+	// code used for confirmation can't be replayed
+	if err := m.CheckCode(ctx, user.ID, code(t, secret, 0)); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("CheckCode() error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	if err := m.CheckCode(ctx, user.ID, code(t, secret, 1)); err != nil {
+		t.Errorf("CheckCode() error = %v, want nil", err)
+	}
+
+	if err := m.CheckCode(ctx, user.ID, recoveryCodes[0]); err != nil {
+		t.Errorf("CheckCode() error = %v, want nil", err)
+	}
+
+	if err := m.CheckCode(ctx, user.ID, recoveryCodes[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("CheckCode() error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	if err := m.Disable(ctx, user.ID, recoveryCodes[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if enabled, _ := m.IsEnabled(ctx, user.ID); enabled {
+		t.Error("IsEnabled() = true after Disable()")
+	}
+}
+
+func TestManager_Challenge(t *testing.T) {
+	m := newManager(time.Minute)
+
+	challenge, err := m.NewChallenge(user.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, appID, err := m.ParseChallenge(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != user.ID || appID != 2 {
+		t.Errorf("ParseChallenge() = %v %v, want %v %v", userID, appID, user.ID, 2)
+	}
+
+	other := New(nil, "test", "other-secret", time.Minute)
+	if _, _, err := other.ParseChallenge(challenge); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("ParseChallenge() error = %v, want %v", err, ErrInvalidChallenge)
+	}
+
+	expired, err := newManager(-time.Minute).NewChallenge(user.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.ParseChallenge(expired); !errors.Is(err, ErrChallengeExpired) {
+		t.Errorf("ParseChallenge() error = %v, want %v", err, ErrChallengeExpired)
+	}
+}
@@ -0,0 +1,530 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"lib/securetoken"
+	"net/url"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// Errors of RFC 6749, Code returns their error codes. They may be wrapped with description.
+var (
+	ErrInvalidRequest       = errors.New("invalid request")
+	ErrInvalidClient        = errors.New("client authentication failed")
+	ErrInvalidGrant         = errors.New("invalid grant")
+	ErrInvalidScope         = errors.New("invalid scope")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrUnsupportedGrantType = errors.New("unsupported grant type")
+)
+
+// Errors of Validate
+var (
+	ErrInvalidToken = errors.New("invalid oauth token")
+	ErrTokenExpired = errors.New("oauth token expired")
+)
+
+// Scopes are permissions of user that can be delegated to clients
+var Scopes = []string{models.PermissionFilesRead, models.PermissionFilesWrite}
+
+// CodeChallengeS256 is the only supported PKCE method, plain isn't allowed
+const CodeChallengeS256 = "S256"
+
+type Storage interface {
+	SaveOAuthClient(ctx context.Context, c models.OAuthClient) error
+	FindOAuthClient(ctx context.Context, id string) (models.OAuthClient, error)
+
+	SaveOAuthCode(ctx context.Context, c models.OAuthCode) error
+	FindOAuthCode(ctx context.Context, codeHash string) (models.OAuthCode, error)
+	// UseOAuthCode returns storage.ErrAlreadyExists if code was used before
+	UseOAuthCode(ctx context.Context, codeHash string, at time.Time) error
+
+	SaveOAuthTokens(ctx context.Context, tokens ...models.OAuthToken) error
+	FindOAuthToken(ctx context.Context, tokenHash string) (models.OAuthToken, error)
+	// RotateOAuthRefreshToken returns storage.ErrAlreadyExists if old token was used before
+	RotateOAuthRefreshToken(ctx context.Context, oldID string, at time.Time, next ...models.OAuthToken) error
+	RevokeOAuthGrant(ctx context.Context, grantID string, at time.Time) error
+
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
+// Manager is OAuth 2.0 authorization server with authorization code flow and PKCE.
+// Codes and tokens are random, only their hashes are stored. Access tokens are opaque
+// like personal access tokens, so they are accepted only where scopes are checked
+// and can't be used to manage account of user.
+type Manager struct {
+	storage    Storage
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	codeTTL    time.Duration
+}
+
+func New(storage Storage, accessTTL, refreshTTL, codeTTL time.Duration) *Manager {
+	return &Manager{
+		storage:    storage,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		codeTTL:    codeTTL,
+	}
+}
+
+// AuthorizeRequest is authorization request of client, see RFC 6749 4.1.1 and RFC 7636 4.3
+type AuthorizeRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scope               string // space separated scopes
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Tokens are tokens of token response
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+	Scopes       []string
+}
+
+// Is reports whether token is OAuth access token
+func Is(token string) bool {
+	return strings.HasPrefix(token, models.OAuthAccessTokenPrefix)
+}
+
+// Code returns error code of RFC 6749 for error of Manager, e.g. invalid_grant
+func Code(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidClient):
+		return "invalid_client"
+	case errors.Is(err, ErrInvalidGrant):
+		return "invalid_grant"
+	case errors.Is(err, ErrInvalidScope):
+		return "invalid_scope"
+	case errors.Is(err, ErrAccessDenied):
+		return "access_denied"
+	case errors.Is(err, ErrUnsupportedGrantType):
+		return "unsupported_grant_type"
+	case errors.Is(err, ErrInvalidRequest):
+		return "invalid_request"
+	default:
+		return "server_error"
+	}
+}
+
+// RegisterClient registers client of user. Confidential client gets secret,
+// it's returned only once. Redirect URIs must be https, http is allowed only for localhost.
+func (m *Manager) RegisterClient(
+	ctx context.Context,
+	ownerID, name string,
+	redirectURIs []string,
+	confidential bool,
+) (string, models.OAuthClient, error) {
+	if len(redirectURIs) == 0 {
+		return "", models.OAuthClient{}, fmt.Errorf("%w: redirect_uris are required", ErrInvalidRequest)
+	}
+
+	for _, u := range redirectURIs {
+		if err := checkRedirectURI(u); err != nil {
+			return "", models.OAuthClient{}, err
+		}
+	}
+
+	c := models.OAuthClient{
+		ID:           uuid.New().String(),
+		Name:         name,
+		OwnerID:      ownerID,
+		RedirectURIs: redirectURIs,
+		CreatedAt:    time.Now(),
+	}
+
+	var secret string
+	if confidential {
+		var err error
+		secret, c.SecretHash, err = securetoken.New()
+		if err != nil {
+			return "", models.OAuthClient{}, err
+		}
+	}
+
+	if err := m.storage.SaveOAuthClient(ctx, c); err != nil {
+		return "", models.OAuthClient{}, err
+	}
+
+	return secret, c, nil
+}
+
+// CheckRequest checks client, redirect URI and scopes of authorization request, it's done
+// before consent screen is shown. Errors other than ErrInvalidScope must not be redirected to client.
+func (m *Manager) CheckRequest(ctx context.Context, clientID, redirectURI, scope string) (models.OAuthClient, []string, error) {
+	c, err := m.storage.FindOAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.OAuthClient{}, nil, fmt.Errorf("%w: unknown client_id", ErrInvalidRequest)
+		}
+		return models.OAuthClient{}, nil, err
+	}
+
+	if !c.AllowsRedirect(redirectURI) {
+		return models.OAuthClient{}, nil, fmt.Errorf("%w: redirect_uri isn't registered", ErrInvalidRequest)
+	}
+
+	scopes, err := parseScope(scope)
+	if err != nil {
+		return c, nil, err
+	}
+
+	return c, scopes, nil
+}
+
+// Authorize issues authorization code after user consented. Only scopes user has
+// are granted, ErrAccessDenied is returned if he has none of them.
+func (m *Manager) Authorize(ctx context.Context, userID string, r AuthorizeRequest) (string, []string, error) {
+	_, scopes, err := m.CheckRequest(ctx, r.ClientID, r.RedirectURI, r.Scope)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if r.CodeChallengeMethod != CodeChallengeS256 || len(r.CodeChallenge) != 43 {
+		return "", nil, fmt.Errorf("%w: S256 code_challenge is required", ErrInvalidRequest)
+	}
+
+	var granted []string
+	for _, scope := range scopes {
+		ok, err := m.storage.HasPermission(ctx, userID, scope)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) == 0 {
+		return "", nil, fmt.Errorf("%w: user has none of requested scopes", ErrAccessDenied)
+	}
+
+	code, hash, err := securetoken.New()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+
+	err = m.storage.SaveOAuthCode(ctx, models.OAuthCode{
+		CodeHash:      hash,
+		GrantID:       uuid.New().String(),
+		ClientID:      r.ClientID,
+		UserID:        userID,
+		RedirectURI:   r.RedirectURI,
+		Scopes:        granted,
+		CodeChallenge: r.CodeChallenge,
+		ExpiresAt:     now.Add(m.codeTTL),
+		CreatedAt:     now,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return code, granted, nil
+}
+
+// Exchange exchanges authorization code for tokens, code can be used only once.
+// If used code is presented again, tokens issued by it are revoked.
+func (m *Manager) Exchange(ctx context.Context, clientID, clientSecret, code, redirectURI, codeVerifier string) (Tokens, error) {
+	if _, err := m.authenticate(ctx, clientID, clientSecret); err != nil {
+		return Tokens{}, err
+	}
+
+	c, err := m.storage.FindOAuthCode(ctx, securetoken.Hash(code))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return Tokens{}, fmt.Errorf("%w: unknown code", ErrInvalidGrant)
+		}
+		return Tokens{}, err
+	}
+
+	now := time.Now()
+
+	if c.ClientID != clientID || c.RedirectURI != redirectURI {
+		return Tokens{}, fmt.Errorf("%w: code was issued for another client or redirect_uri", ErrInvalidGrant)
+	}
+
+	if c.IsExpired(now) {
+		return Tokens{}, fmt.Errorf("%w: code expired", ErrInvalidGrant)
+	}
+
+	if !verifyCodeChallenge(c.CodeChallenge, codeVerifier) {
+		return Tokens{}, fmt.Errorf("%w: code_verifier doesn't match code_challenge", ErrInvalidGrant)
+	}
+
+	if err := m.storage.UseOAuthCode(ctx, c.CodeHash, now); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			// code could be intercepted, tokens issued by it are revoked
+			if err := m.storage.RevokeOAuthGrant(ctx, c.GrantID, now); err != nil {
+				return Tokens{}, err
+			}
+			return Tokens{}, fmt.Errorf("%w: code was already used", ErrInvalidGrant)
+		}
+		return Tokens{}, err
+	}
+
+	t, access, refresh, err := m.newTokens(c.GrantID, c.ClientID, c.UserID, c.Scopes)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	if err := m.storage.SaveOAuthTokens(ctx, access, refresh); err != nil {
+		return Tokens{}, err
+	}
+
+	return t, nil
+}
+
+// Refresh exchanges refresh token for new tokens of the same grant, refresh token can be used
+// only once. If used token is presented again, it could be stolen, so whole grant is revoked.
+func (m *Manager) Refresh(ctx context.Context, clientID, clientSecret, refreshToken string) (Tokens, error) {
+	if _, err := m.authenticate(ctx, clientID, clientSecret); err != nil {
+		return Tokens{}, err
+	}
+
+	old, err := m.storage.FindOAuthToken(ctx, securetoken.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return Tokens{}, fmt.Errorf("%w: unknown refresh token", ErrInvalidGrant)
+		}
+		return Tokens{}, err
+	}
+
+	now := time.Now()
+
+	if old.Kind != models.OAuthTokenRefresh || old.ClientID != clientID || old.IsRevoked() {
+		return Tokens{}, fmt.Errorf("%w: invalid refresh token", ErrInvalidGrant)
+	}
+
+	if old.IsUsed() {
+		return Tokens{}, m.reused(ctx, old, now)
+	}
+
+	if old.IsExpired(now) {
+		return Tokens{}, fmt.Errorf("%w: refresh token expired", ErrInvalidGrant)
+	}
+
+	t, access, refresh, err := m.newTokens(old.GrantID, old.ClientID, old.UserID, old.Scopes)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	if err := m.storage.RotateOAuthRefreshToken(ctx, old.ID, now, access, refresh); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			// lost race with another refresh of the same token, that's reuse too
+			return Tokens{}, m.reused(ctx, old, now)
+		}
+		return Tokens{}, err
+	}
+
+	return t, nil
+}
+
+// Revoke revokes grant of access or refresh token of the client, see RFC 7009.
+// Unknown tokens and tokens of other clients are ignored.
+func (m *Manager) Revoke(ctx context.Context, clientID, clientSecret, token string) error {
+	if _, err := m.authenticate(ctx, clientID, clientSecret); err != nil {
+		return err
+	}
+
+	t, err := m.storage.FindOAuthToken(ctx, securetoken.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if t.ClientID != clientID {
+		return nil
+	}
+
+	return m.storage.RevokeOAuthGrant(ctx, t.GrantID, time.Now())
+}
+
+// Validate returns access token by its value. Scopes of returned token are only
+// those its owner still has, permissions taken from user are taken from token too.
+func (m *Manager) Validate(ctx context.Context, token string) (models.OAuthToken, error) {
+	if !Is(token) {
+		return models.OAuthToken{}, ErrInvalidToken
+	}
+
+	t, err := m.storage.FindOAuthToken(ctx, securetoken.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.OAuthToken{}, ErrInvalidToken
+		}
+		return models.OAuthToken{}, err
+	}
+
+	if t.Kind != models.OAuthTokenAccess || t.IsRevoked() {
+		return models.OAuthToken{}, ErrInvalidToken
+	}
+
+	if t.IsExpired(time.Now()) {
+		return models.OAuthToken{}, ErrTokenExpired
+	}
+
+	var scopes []string
+	for _, scope := range t.Scopes {
+		ok, err := m.storage.HasPermission(ctx, t.UserID, scope)
+		if err != nil {
+			return models.OAuthToken{}, err
+		}
+		if ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	t.Scopes = scopes
+
+	return t, nil
+}
+
+// authenticate returns client, confidential clients must present their secret
+func (m *Manager) authenticate(ctx context.Context, clientID, clientSecret string) (models.OAuthClient, error) {
+	if clientID == "" {
+		return models.OAuthClient{}, ErrInvalidClient
+	}
+
+	c, err := m.storage.FindOAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.OAuthClient{}, ErrInvalidClient
+		}
+		return models.OAuthClient{}, err
+	}
+
+	if c.IsConfidential() {
+		hash := securetoken.Hash(clientSecret)
+		if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(c.SecretHash)) != 1 {
+			return models.OAuthClient{}, ErrInvalidClient
+		}
+	}
+
+	return c, nil
+}
+
+// reused revokes grant of reused refresh token
+func (m *Manager) reused(ctx context.Context, t models.OAuthToken, now time.Time) error {
+	if err := m.storage.RevokeOAuthGrant(ctx, t.GrantID, now); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: refresh token was already used", ErrInvalidGrant)
+}
+
+// newTokens returns token response and stored forms of its access and refresh token
+func (m *Manager) newTokens(grantID, clientID, userID string, scopes []string) (Tokens, models.OAuthToken, models.OAuthToken, error) {
+	accessSecret, _, err := securetoken.New()
+	if err != nil {
+		return Tokens{}, models.OAuthToken{}, models.OAuthToken{}, err
+	}
+
+	refreshSecret, _, err := securetoken.New()
+	if err != nil {
+		return Tokens{}, models.OAuthToken{}, models.OAuthToken{}, err
+	}
+
+	t := Tokens{
+		AccessToken:  models.OAuthAccessTokenPrefix + accessSecret,
+		RefreshToken: models.OAuthRefreshTokenPrefix + refreshSecret,
+		ExpiresIn:    m.accessTTL,
+		Scopes:       scopes,
+	}
+
+	now := time.Now()
+
+	access := models.OAuthToken{
+		ID:        uuid.New().String(),
+		GrantID:   grantID,
+		Kind:      models.OAuthTokenAccess,
+		TokenHash: securetoken.Hash(t.AccessToken),
+		ClientID:  clientID,
+		UserID:    userID,
+		Scopes:    scopes,
+		ExpiresAt: now.Add(m.accessTTL),
+		CreatedAt: now,
+	}
+
+	refresh := access
+	refresh.ID = uuid.New().String()
+	refresh.Kind = models.OAuthTokenRefresh
+	refresh.TokenHash = securetoken.Hash(t.RefreshToken)
+	refresh.ExpiresAt = now.Add(m.refreshTTL)
+
+	return t, access, refresh, nil
+}
+
+// parseScope returns scopes of space separated scope parameter, all of them must be supported
+func parseScope(scope string) ([]string, error) {
+	fields := strings.Fields(scope)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: scope is required", ErrInvalidScope)
+	}
+
+	seen := make(map[string]bool, len(fields))
+	var scopes []string
+	for _, f := range fields {
+		if !isSupported(f) {
+			return nil, fmt.Errorf("%w: %s isn't supported", ErrInvalidScope, f)
+		}
+		if !seen[f] {
+			seen[f] = true
+			scopes = append(scopes, f)
+		}
+	}
+
+	return scopes, nil
+}
+
+func isSupported(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRedirectURI accepts absolute https URIs without fragment, http only for loopback
+func checkRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return fmt.Errorf("%w: invalid redirect_uri %s", ErrInvalidRequest, uri)
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if host := u.Hostname(); host == "localhost" || host == "127.0.0.1" || host == "::1" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: redirect_uri must be https %s", ErrInvalidRequest, uri)
+}
+
+// verifyCodeChallenge checks PKCE code_verifier against S256 code_challenge, see RFC 7636 4.6
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,399 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// fakeStorage keeps clients, codes and tokens in maps
+type fakeStorage struct {
+	clients     map[string]models.OAuthClient
+	codes       map[string]models.OAuthCode
+	tokens      map[string]models.OAuthToken
+	permissions map[string][]string
+}
+
+func (f *fakeStorage) SaveOAuthClient(_ context.Context, c models.OAuthClient) error {
+	f.clients[c.ID] = c
+	return nil
+}
+
+func (f *fakeStorage) FindOAuthClient(_ context.Context, id string) (models.OAuthClient, error) {
+	c, ok := f.clients[id]
+	if !ok {
+		return models.OAuthClient{}, storage.ErrNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeStorage) SaveOAuthCode(_ context.Context, c models.OAuthCode) error {
+	f.codes[c.CodeHash] = c
+	return nil
+}
+
+func (f *fakeStorage) FindOAuthCode(_ context.Context, codeHash string) (models.OAuthCode, error) {
+	c, ok := f.codes[codeHash]
+	if !ok {
+		return models.OAuthCode{}, storage.ErrNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeStorage) UseOAuthCode(_ context.Context, codeHash string, at time.Time) error {
+	c := f.codes[codeHash]
+	if c.IsUsed() {
+		return storage.ErrAlreadyExists
+	}
+	c.UsedAt = at
+	f.codes[codeHash] = c
+	return nil
+}
+
+func (f *fakeStorage) SaveOAuthTokens(_ context.Context, tokens ...models.OAuthToken) error {
+	for _, t := range tokens {
+		f.tokens[t.TokenHash] = t
+	}
+	return nil
+}
+
+func (f *fakeStorage) FindOAuthToken(_ context.Context, tokenHash string) (models.OAuthToken, error) {
+	t, ok := f.tokens[tokenHash]
+	if !ok {
+		return models.OAuthToken{}, storage.ErrNotFound
+	}
+	return t, nil
+}
+
+func (f *fakeStorage) RotateOAuthRefreshToken(ctx context.Context, oldID string, at time.Time, next ...models.OAuthToken) error {
+	for hash, t := range f.tokens {
+		if t.ID == oldID {
+			if t.IsUsed() {
+				return storage.ErrAlreadyExists
+			}
+			t.UsedAt = at
+			f.tokens[hash] = t
+		}
+	}
+	return f.SaveOAuthTokens(ctx, next...)
+}
+
+func (f *fakeStorage) RevokeOAuthGrant(_ context.Context, grantID string, at time.Time) error {
+	for hash, t := range f.tokens {
+		if t.GrantID == grantID && !t.IsRevoked() {
+			t.RevokedAt = at
+			f.tokens[hash] = t
+		}
+	}
+	return nil
+}
+
+func (f *fakeStorage) HasPermission(_ context.Context, userID, permission string) (bool, error) {
+	for _, p := range f.permissions[userID] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+const (
+	userID      = "user-id"
+	redirectURI = "https://client.example/callback"
+	verifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func newManager() (*Manager, *fakeStorage) {
+	st := &fakeStorage{
+		clients: map[string]models.OAuthClient{},
+		codes:   map[string]models.OAuthCode{},
+		tokens:  map[string]models.OAuthToken{},
+		permissions: map[string][]string{
+			userID: {models.PermissionFilesRead, models.PermissionFilesWrite},
+		},
+	}
+
+	return New(st, time.Hour, 24*time.Hour, time.Minute), st
+}
+
+// authorize registers confidential client and returns its secret, client ID and code
+func authorize(t *testing.T, m *Manager, scope string) (string, string, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	secret, c, err := m.RegisterClient(ctx, userID, "client", []string{redirectURI}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, err := m.Authorize(ctx, userID, AuthorizeRequest{
+		ClientID:            c.ID,
+		RedirectURI:         redirectURI,
+		Scope:               scope,
+		CodeChallenge:       challenge(verifier),
+		CodeChallengeMethod: CodeChallengeS256,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return secret, c.ID, code
+}
+
+func TestManager_RegisterClient(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newManager()
+
+	tests := []struct {
+		name    string
+		uris    []string
+		wantErr error
+	}{
+		{name: "https", uris: []string{redirectURI}},
+		{name: "loopback", uris: []string{"http://127.0.0.1:8080/callback"}},
+		{name: "no uris", wantErr: ErrInvalidRequest},
+		{name: "http", uris: []string{"http://client.example/callback"}, wantErr: ErrInvalidRequest},
+		{name: "relative", uris: []string{"/callback"}, wantErr: ErrInvalidRequest},
+		{name: "fragment", uris: []string{redirectURI + "#x"}, wantErr: ErrInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := m.RegisterClient(ctx, userID, "client", tt.uris, false)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RegisterClient() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	secret, c, err := m.RegisterClient(ctx, userID, "client", []string{redirectURI}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != "" || c.IsConfidential() {
+		t.Errorf("RegisterClient() public client got secret")
+	}
+}
+
+func TestManager_Authorize(t *testing.T) {
+	ctx := context.Background()
+	m, st := newManager()
+	st.permissions[userID] = []string{models.PermissionFilesRead}
+
+	_, c, err := m.RegisterClient(ctx, userID, "client", []string{redirectURI}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := AuthorizeRequest{
+		ClientID:            c.ID,
+		RedirectURI:         redirectURI,
+		Scope:               "files:read files:write",
+		CodeChallenge:       challenge(verifier),
+		CodeChallengeMethod: CodeChallengeS256,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *AuthorizeRequest)
+		wantErr error
+	}{
+		{name: "valid", modify: func(r *AuthorizeRequest) {}},
+		{name: "unknown client", modify: func(r *AuthorizeRequest) { r.ClientID = "unknown" }, wantErr: ErrInvalidRequest},
+		{name: "other redirect", modify: func(r *AuthorizeRequest) { r.RedirectURI += "/other" }, wantErr: ErrInvalidRequest},
+		{name: "no scope", modify: func(r *AuthorizeRequest) { r.Scope = "" }, wantErr: ErrInvalidScope},
+		{name: "unknown scope", modify: func(r *AuthorizeRequest) { r.Scope = "users:manage" }, wantErr: ErrInvalidScope},
+		{name: "plain pkce", modify: func(r *AuthorizeRequest) { r.CodeChallengeMethod = "plain" }, wantErr: ErrInvalidRequest},
+		{name: "not granted", modify: func(r *AuthorizeRequest) { r.Scope = "files:write" }, wantErr: ErrAccessDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.modify(&r)
+
+			_, scopes, err := m.Authorize(ctx, userID, r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+			// only scopes user has are granted
+			if err == nil && (len(scopes) != 1 || scopes[0] != models.PermissionFilesRead) {
+				t.Errorf("Authorize() scopes = %v, want [%v]", scopes, models.PermissionFilesRead)
+			}
+		})
+	}
+}
+
+func TestManager_Exchange(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newManager()
+
+	secret, clientID, code := authorize(t, m, "files:read")
+
+	tests := []struct {
+		name         string
+		secret       string
+		redirectURI  string
+		codeVerifier string
+		wantErr      error
+	}{
+		{name: "no secret", redirectURI: redirectURI, codeVerifier: verifier, wantErr: ErrInvalidClient},
+		{name: "wrong secret", secret: "wrong", redirectURI: redirectURI, codeVerifier: verifier, wantErr: ErrInvalidClient},
+		{name: "other redirect", secret: secret, redirectURI: redirectURI + "/other", codeVerifier: verifier, wantErr: ErrInvalidGrant},
+		{name: "wrong verifier", secret: secret, redirectURI: redirectURI, codeVerifier: strings.Repeat("a", 43), wantErr: ErrInvalidGrant},
+		{name: "valid", secret: secret, redirectURI: redirectURI, codeVerifier: verifier},
+		{name: "used code", secret: secret, redirectURI: redirectURI, codeVerifier: verifier, wantErr: ErrInvalidGrant},
+	}
+
+	var tokens Tokens
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Exchange(ctx, clientID, tt.secret, code, tt.redirectURI, tt.codeVerifier)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Exchange() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				tokens = got
+			}
+		})
+	}
+
+	if !Is(tokens.AccessToken) || !strings.HasPrefix(tokens.RefreshToken, models.OAuthRefreshTokenPrefix) {
+		t.Fatalf("Exchange() tokens = %+v, want prefixed tokens", tokens)
+	}
+
+	// tokens of reused code are revoked, it could be intercepted
+	if _, err := m.Validate(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Validate() after code reuse error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestManager_Refresh(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newManager()
+
+	secret, clientID, code := authorize(t, m, "files:read")
+
+	first, err := m.Exchange(ctx, clientID, secret, code, redirectURI, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := m.Refresh(ctx, clientID, secret, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.Validate(ctx, second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != userID || len(got.Scopes) != 1 {
+		t.Errorf("Validate() got %+v, want token of %v with one scope", got, userID)
+	}
+
+	// reuse revokes whole grant
+	if _, err := m.Refresh(ctx, clientID, secret, first.RefreshToken); !errors.Is(err, ErrInvalidGrant) {
+		t.Fatalf("Refresh() reused token error = %v, want %v", err, ErrInvalidGrant)
+	}
+
+	if _, err := m.Refresh(ctx, clientID, secret, second.RefreshToken); !errors.Is(err, ErrInvalidGrant) {
+		t.Errorf("Refresh() after reuse error = %v, want %v", err, ErrInvalidGrant)
+	}
+	if _, err := m.Validate(ctx, second.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Validate() after reuse error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestManager_Validate(t *testing.T) {
+	ctx := context.Background()
+	m, st := newManager()
+
+	secret, clientID, code := authorize(t, m, "files:read files:write")
+
+	tokens, err := m.Exchange(ctx, clientID, secret, code, redirectURI, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: tokens.AccessToken},
+		{name: "refresh token", token: tokens.RefreshToken, wantErr: ErrInvalidToken},
+		{name: "unknown", token: models.OAuthAccessTokenPrefix + "unknown", wantErr: ErrInvalidToken},
+		{name: "personal access token", token: models.AccessTokenPrefix + "unknown", wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Validate(ctx, tt.token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// permission taken from user is taken from his tokens too
+	st.permissions[userID] = []string{models.PermissionFilesRead}
+
+	got, err := m.Validate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Scopes) != 1 || got.Scopes[0] != models.PermissionFilesRead {
+		t.Errorf("Validate() scopes = %v, want [%v]", got.Scopes, models.PermissionFilesRead)
+	}
+}
+
+func TestManager_Revoke(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newManager()
+
+	secret, clientID, code := authorize(t, m, "files:read")
+
+	tokens, err := m.Exchange(ctx, clientID, secret, code, redirectURI, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Revoke(ctx, clientID, "wrong", tokens.RefreshToken); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("Revoke() with wrong secret error = %v, want %v", err, ErrInvalidClient)
+	}
+
+	if err := m.Revoke(ctx, clientID, secret, "unknown"); err != nil {
+		t.Errorf("Revoke() of unknown token error = %v, want nil", err)
+	}
+
+	if err := m.Revoke(ctx, clientID, secret, tokens.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Validate(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Validate() after Revoke() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: ErrInvalidClient, want: "invalid_client"},
+		{err: errors.New("db is down"), want: "server_error"},
+	}
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.want {
+			t.Errorf("Code(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
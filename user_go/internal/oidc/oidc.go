@@ -0,0 +1,454 @@
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lib/errs"
+	"lib/securetoken"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrUnknownProvider = errs.New(errs.ErrInvalid, "unknown identity provider")
+	ErrInvalidState    = errs.New(errs.ErrInvalid, "invalid oidc state")
+	ErrStateExpired    = errs.New(errs.ErrUnauthenticated, "oidc state expired")
+	ErrExchange        = errs.New(errs.ErrUnauthenticated, "cant exchange code")
+	ErrInvalidIDToken  = errs.New(errs.ErrUnauthenticated, "invalid id token")
+)
+
+// jwksRefreshInterval limits refetching of JWKS when token has unknown kid,
+// so forged tokens can't make us flood provider with requests
+const jwksRefreshInterval = time.Minute
+
+// Provider is OpenID Connect provider users can log in with.
+// RedirectURL is callback of the gateway registered at provider.
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // openid is always requested
+}
+
+// Identity is user authenticated by provider, claims are taken from verified ID token
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Login is what login was started for, it's kept in state
+type Login struct {
+	Provider string
+	AppID    int32
+	// LinkUserID is set if identity is linked to logged in user instead of login
+	LinkUserID string
+}
+
+// Manager runs authorization code flow with PKCE against OpenID Connect providers.
+// State is signed and short-lived like MFA challenge, it carries nonce of ID token,
+// PKCE verifier is derived from nonce, so nothing is stored until login is finished.
+type Manager struct {
+	providers map[string]*provider
+	secret    []byte
+	stateTTL  time.Duration
+	client    *http.Client
+}
+
+func New(stateSecret string, stateTTL time.Duration, client *http.Client, providers ...Provider) (*Manager, error) {
+	m := &Manager{
+		providers: make(map[string]*provider, len(providers)),
+		secret:    []byte(stateSecret),
+		stateTTL:  stateTTL,
+		client:    client,
+	}
+
+	if len(providers) != 0 && stateSecret == "" {
+		return nil, errors.New("state secret is required")
+	}
+
+	for _, p := range providers {
+		if p.Name == "" || strings.Contains(p.Name, ".") {
+			return nil, fmt.Errorf("invalid provider name %q", p.Name)
+		}
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			return nil, fmt.Errorf("provider %s: issuer, client_id and redirect_url are required", p.Name)
+		}
+		if _, ok := m.providers[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate provider %s", p.Name)
+		}
+		m.providers[p.Name] = &provider{Provider: p, client: client}
+	}
+
+	return m, nil
+}
+
+// AuthURL returns URL of provider user is redirected to and state of the login.
+// Client should bind state to browser, e.g. with cookie, and pass it to Exchange with code.
+func (m *Manager) AuthURL(ctx context.Context, l Login) (string, string, error) {
+	p, ok := m.providers[l.Provider]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, _, err := securetoken.New()
+	if err != nil {
+		return "", "", err
+	}
+
+	exp := time.Now().Add(m.stateTTL).Unix()
+	state := securetoken.Sign(m.secret, fmt.Sprintf("%s.%d.%d.%s.%s", l.Provider, l.AppID, exp, nonce, l.LinkUserID))
+
+	sum := sha256.Sum256([]byte(m.verifier(nonce)))
+
+	scopes := []string{"openid"}
+	for _, s := range p.Scopes {
+		if s != "openid" {
+			scopes = append(scopes, s)
+		}
+	}
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", err
+	}
+	for k, v := range u.Query() {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), state, nil
+}
+
+// Exchange checks state, exchanges code for ID token and returns verified identity
+func (m *Manager) Exchange(ctx context.Context, state, code string) (Identity, Login, error) {
+	l, nonce, err := m.parseState(state)
+	if err != nil {
+		return Identity{}, Login{}, err
+	}
+
+	p, ok := m.providers[l.Provider]
+	if !ok {
+		return Identity{}, Login{}, ErrUnknownProvider
+	}
+
+	rawIDToken, err := p.exchange(ctx, code, m.verifier(nonce))
+	if err != nil {
+		return Identity{}, Login{}, err
+	}
+
+	id, err := p.verify(ctx, rawIDToken, nonce)
+	if err != nil {
+		return Identity{}, Login{}, err
+	}
+
+	return id, l, nil
+}
+
+// verifier returns PKCE code verifier of login with nonce
+func (m *Manager) verifier(nonce string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte("pkce." + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (m *Manager) parseState(state string) (Login, string, error) {
+	payload, err := securetoken.Verify(m.secret, state)
+	if err != nil {
+		return Login{}, "", ErrInvalidState
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 5 {
+		return Login{}, "", ErrInvalidState
+	}
+
+	app, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil {
+		return Login{}, "", ErrInvalidState
+	}
+
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return Login{}, "", ErrInvalidState
+	}
+
+	if !time.Now().Before(time.Unix(exp, 0)) {
+		return Login{}, "", ErrStateExpired
+	}
+
+	return Login{Provider: parts[0], AppID: int32(app), LinkUserID: parts[4]}, parts[3], nil
+}
+
+// discovery is part of provider metadata we use, see OpenID Connect Discovery 1.0
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// provider caches metadata and keys of provider, they are fetched on first use
+type provider struct {
+	Provider
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *discovery
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+func (p *provider) discover(ctx context.Context) (discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return *p.meta, nil
+	}
+
+	var d discovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return discovery{}, fmt.Errorf("cant discover %s: %w", p.Name, err)
+	}
+
+	// metadata of another issuer could be served by compromised host, see 4.3 of Discovery
+	if d.Issuer != p.Issuer {
+		return discovery{}, fmt.Errorf("%s: issuer %q doesn't match configured one", p.Name, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return discovery{}, fmt.Errorf("%s: incomplete provider metadata", p.Name)
+	}
+
+	p.meta = &d
+
+	return d, nil
+}
+
+// exchange returns ID token of token response
+func (p *provider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic, credentials are form encoded, see RFC 6749 2.3.1
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	var tr struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrExchange, res.Status)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s %s", ErrExchange, tr.Error, tr.ErrorDescription)
+	}
+	if tr.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token", ErrExchange)
+	}
+
+	return tr.IDToken, nil
+}
+
+// verify checks signature and claims of ID token, see 3.1.3.7 of OpenID Connect Core 1.0
+func (p *provider) verify(ctx context.Context, rawIDToken, nonce string) (Identity, error) {
+	token, err := jwt.Parse(rawIDToken, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		key, err := p.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// algorithm must match type of key, so public key can't be used as HMAC secret
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+			return key, nil
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+	})
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Identity{}, ErrInvalidIDToken
+	}
+
+	if !claims.VerifyIssuer(p.Issuer, true) {
+		return Identity{}, fmt.Errorf("%w: wrong issuer", ErrInvalidIDToken)
+	}
+
+	if !claims.VerifyAudience(p.ClientID, true) {
+		return Identity{}, fmt.Errorf("%w: wrong audience", ErrInvalidIDToken)
+	}
+
+	// token without exp would never expire
+	if _, ok := claims["exp"].(float64); !ok {
+		return Identity{}, fmt.Errorf("%w: no exp", ErrInvalidIDToken)
+	}
+
+	if n, _ := claims["nonce"].(string); !hmac.Equal([]byte(n), []byte(nonce)) {
+		return Identity{}, fmt.Errorf("%w: wrong nonce", ErrInvalidIDToken)
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return Identity{}, fmt.Errorf("%w: no sub", ErrInvalidIDToken)
+	}
+
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+
+	return Identity{
+		Provider:      p.Name,
+		Subject:       sub,
+		Email:         email,
+		EmailVerified: emailVerified(claims["email_verified"]),
+		Name:          name,
+	}, nil
+}
+
+// emailVerified parses email_verified claim, some providers send it as string
+func emailVerified(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
+// key returns public key of provider with kid, keys are refetched
+// when kid is unknown, provider could rotate them
+func (p *provider) key(ctx context.Context, kid string) (interface{}, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("cant fetch jwks of %s: %w", p.Name, err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			// keys of unsupported types are skipped
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	k, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return k, nil
+}
+
+func (p *provider) getJSON(ctx context.Context, uri string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", uri, res.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
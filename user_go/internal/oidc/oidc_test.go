@@ -0,0 +1,253 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	clientID     = "client-id"
+	clientSecret = "client-secret"
+	redirectURL  = "https://gateway.example/users/oidc/stub/callback"
+)
+
+// stubIssuer is OpenID Connect provider that issues ID token for any code
+// with nonce and code challenge of the last authorization request
+type stubIssuer struct {
+	srv       *httptest.Server
+	key       *rsa.PrivateKey
+	nonce     string
+	challenge string
+	// claims override claims of issued ID token, nil values are removed
+	claims jwt.MapClaims
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &stubIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.srv.URL,
+			"authorization_endpoint": s.srv.URL + "/authorize",
+			"token_endpoint":         s.srv.URL + "/token",
+			"jwks_uri":               s.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != clientID || secret != clientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != s.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": s.idToken(t)})
+	})
+
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+
+	return s
+}
+
+func (s *stubIssuer) idToken(t *testing.T) string {
+	claims := jwt.MapClaims{
+		"iss":            s.srv.URL,
+		"aud":            clientID,
+		"sub":            "subject",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"nonce":          s.nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+	for k, v := range s.claims {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "stub"
+
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// authorize starts login and remembers its nonce and challenge like provider does
+func (s *stubIssuer) authorize(t *testing.T, m *Manager, l Login) string {
+	t.Helper()
+
+	authURL, state, err := m.AuthURL(context.Background(), l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+
+	if q.Get("state") != state || q.Get("client_id") != clientID || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("AuthURL() = %v, want authorization request of %v", authURL, clientID)
+	}
+
+	s.nonce = q.Get("nonce")
+	s.challenge = q.Get("code_challenge")
+
+	return state
+}
+
+func newManager(t *testing.T, s *stubIssuer) *Manager {
+	t.Helper()
+
+	m, err := New("state-secret", time.Minute, s.srv.Client(), Provider{
+		Name:         "stub",
+		Issuer:       s.srv.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"email"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestManager_Exchange(t *testing.T) {
+	ctx := context.Background()
+	s := newStubIssuer(t)
+	m := newManager(t, s)
+
+	state := s.authorize(t, m, Login{Provider: "stub", AppID: 1, LinkUserID: "user-id"})
+
+	id, l, err := m.Exchange(ctx, state, "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Identity{Provider: "stub", Subject: "subject", Email: "user@example.com", EmailVerified: true}
+	if id != want {
+		t.Errorf("Exchange() identity = %+v, want %+v", id, want)
+	}
+	if l.AppID != 1 || l.LinkUserID != "user-id" {
+		t.Errorf("Exchange() login = %+v, want login of app 1 linking user-id", l)
+	}
+}
+
+func TestManager_Exchange_InvalidIDToken(t *testing.T) {
+	ctx := context.Background()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		sign   func(s *stubIssuer)
+	}{
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://other.example"}},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "other-client"}},
+		{name: "wrong nonce", claims: jwt.MapClaims{"nonce": "other"}},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+		{name: "no exp", claims: jwt.MapClaims{"exp": nil}},
+		{name: "no sub", claims: jwt.MapClaims{"sub": nil}},
+		{name: "forged", sign: func(s *stubIssuer) { s.key = otherKey }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStubIssuer(t)
+			m := newManager(t, s)
+
+			state := s.authorize(t, m, Login{Provider: "stub"})
+			s.claims = tt.claims
+			if tt.sign != nil {
+				tt.sign(s)
+			}
+
+			if _, _, err := m.Exchange(ctx, state, "code"); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Exchange() error = %v, want %v", err, ErrInvalidIDToken)
+			}
+		})
+	}
+}
+
+func TestManager_Exchange_InvalidState(t *testing.T) {
+	ctx := context.Background()
+	s := newStubIssuer(t)
+	m := newManager(t, s)
+
+	state := s.authorize(t, m, Login{Provider: "stub"})
+
+	if _, _, err := m.Exchange(ctx, state+"x", "code"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Exchange() with forged state error = %v, want %v", err, ErrInvalidState)
+	}
+
+	other, err := New("other-secret", time.Minute, s.srv.Client(), Provider{
+		Name: "stub", Issuer: s.srv.URL, ClientID: clientID, ClientSecret: clientSecret, RedirectURL: redirectURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := other.Exchange(ctx, state, "code"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Exchange() with state of other secret error = %v, want %v", err, ErrInvalidState)
+	}
+
+	// verifier is derived from state, so code of another login can't be exchanged with it
+	s.authorize(t, m, Login{Provider: "stub"})
+	if _, _, err := m.Exchange(ctx, state, "code"); !errors.Is(err, ErrExchange) {
+		t.Errorf("Exchange() with state of another login error = %v, want %v", err, ErrExchange)
+	}
+
+	if _, _, err := m.AuthURL(ctx, Login{Provider: "unknown"}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("AuthURL() of unknown provider error = %v, want %v", err, ErrUnknownProvider)
+	}
+}
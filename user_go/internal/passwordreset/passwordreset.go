@@ -0,0 +1,111 @@
+package passwordreset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lib/errs"
+	"lib/securetoken"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/mailer"
+	"user_service/internal/storage"
+)
+
+var (
+	ErrInvalidToken = errs.New(errs.ErrInvalid, "invalid password reset token")
+	ErrTokenExpired = errs.New(errs.ErrInvalid, "password reset token expired")
+)
+
+type Storage interface {
+	SavePasswordReset(ctx context.Context, r models.PasswordReset) error
+	FindPasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error)
+	ResetPassword(ctx context.Context, tokenHash string, encPassword []byte, usedAt time.Time) error
+}
+
+// Manager mails password reset tokens and changes password by them.
+// Tokens are random, only their hashes are stored, and can be used once.
+type Manager struct {
+	storage Storage
+	mailer  mailer.Mailer
+	hasher  models.PasswordHasher
+	ttl     time.Duration
+	link    string // fmt format of reset link with token as the only argument
+}
+
+func New(storage Storage, mailer mailer.Mailer, hasher models.PasswordHasher, ttl time.Duration, link string) *Manager {
+	return &Manager{
+		storage: storage,
+		mailer:  mailer,
+		hasher:  hasher,
+		ttl:     ttl,
+		link:    link,
+	}
+}
+
+// Request creates reset token for user and mails link with it.
+// Tokens requested before are invalidated.
+func (m *Manager) Request(ctx context.Context, u models.User) error {
+	token, hash, err := securetoken.New()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = m.storage.SavePasswordReset(ctx, models.PasswordReset{
+		TokenHash: hash,
+		UserID:    u.ID,
+		ExpiresAt: now.Add(m.ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Follow the link to set a new password:\n\n%s\n\n"+
+				"The link expires in %s. If you didn't ask to reset password, ignore this email.\n",
+			fmt.Sprintf(m.link, token), m.ttl,
+		),
+	})
+}
+
+// Reset sets new password of token owner and returns his ID
+func (m *Manager) Reset(ctx context.Context, token, password string) (string, error) {
+	hash := securetoken.Hash(token)
+
+	r, err := m.storage.FindPasswordReset(ctx, hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+
+	if r.IsUsed() {
+		return "", ErrInvalidToken
+	}
+
+	now := time.Now()
+	if r.IsExpired(now) {
+		return "", ErrTokenExpired
+	}
+
+	u := models.User{Password: password}
+	if err := u.EncryptPassword(m.hasher); err != nil {
+		return "", err
+	}
+
+	if err := m.storage.ResetPassword(ctx, hash, u.EncPassword, now); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+
+	return r.UserID, nil
+}
@@ -0,0 +1,130 @@
+package passwordreset
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/mailer"
+	"user_service/internal/storage"
+	"user_service/lib/passhash"
+)
+
+// fakeStorage keeps resets by hash and passwords by user ID in maps
+type fakeStorage struct {
+	resets    map[string]models.PasswordReset
+	passwords map[string][]byte
+}
+
+func (f *fakeStorage) SavePasswordReset(_ context.Context, r models.PasswordReset) error {
+	for hash, old := range f.resets {
+		if old.UserID == r.UserID && !old.IsUsed() {
+			delete(f.resets, hash)
+		}
+	}
+	f.resets[r.TokenHash] = r
+	return nil
+}
+
+func (f *fakeStorage) FindPasswordReset(_ context.Context, tokenHash string) (models.PasswordReset, error) {
+	r, ok := f.resets[tokenHash]
+	if !ok {
+		return models.PasswordReset{}, storage.ErrNotFound
+	}
+	return r, nil
+}
+
+func (f *fakeStorage) ResetPassword(_ context.Context, tokenHash string, encPassword []byte, usedAt time.Time) error {
+	r := f.resets[tokenHash]
+	if r.IsUsed() {
+		return storage.ErrAlreadyExists
+	}
+	r.UsedAt = usedAt
+	f.resets[tokenHash] = r
+	f.passwords[r.UserID] = encPassword
+	return nil
+}
+
+var (
+	user   = models.User{ID: "user-id", Email: "user1@example.org"}
+	hasher = passhash.New(passhash.Bcrypt{Cost: bcrypt.MinCost})
+)
+
+func newManager(ttl time.Duration) (*Manager, *fakeStorage, *mailer.Memory) {
+	st := &fakeStorage{
+		resets:    map[string]models.PasswordReset{},
+		passwords: map[string][]byte{},
+	}
+	m := mailer.NewMemory()
+
+	return New(st, m, hasher, ttl, "http://localhost/reset?token=%s"), st, m
+}
+
+// sentToken returns token from the last sent email
+func sentToken(t *testing.T, m *mailer.Memory) string {
+	messages := m.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no email sent")
+	}
+
+	_, rest, ok := strings.Cut(messages[len(messages)-1].Body, "token=")
+	if !ok {
+		t.Fatal("no token in email")
+	}
+
+	token, _, _ := strings.Cut(rest, "\n")
+
+	return token
+}
+
+func TestManager_Reset(t *testing.T) {
+	ctx := context.Background()
+	m, st, outbox := newManager(time.Hour)
+
+	if err := m.Request(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	first := sentToken(t, outbox)
+
+	if err := m.Request(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	token := sentToken(t, outbox)
+
+	if _, err := m.Reset(ctx, first, "new-password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Reset() with replaced token error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	userID, err := m.Reset(ctx, token, "new-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != user.ID {
+		t.Errorf("Reset() user = %v, want %v", userID, user.ID)
+	}
+
+	u := models.User{EncPassword: st.passwords[user.ID]}
+	if ok, _ := u.ComparePassword(hasher, "new-password"); !ok {
+		t.Error("Reset() password wasn't changed")
+	}
+
+	if _, err := m.Reset(ctx, token, "other-password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Reset() second time error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestManager_ResetExpired(t *testing.T) {
+	ctx := context.Background()
+	m, _, outbox := newManager(-time.Hour)
+
+	if err := m.Request(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Reset(ctx, sentToken(t, outbox), "new-password"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Reset() error = %v, want %v", err, ErrTokenExpired)
+	}
+}
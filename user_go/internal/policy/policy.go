@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"bufio"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Fields of violations, they are names of request fields
+const (
+	FieldEmail    = "email"
+	FieldPassword = "password"
+)
+
+// maxEmailLen is the limit of RFC 5321 for forward path
+const maxEmailLen = 254
+
+// Violation is a reason why value of field isn't accepted
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// Error is returned when email or password doesn't follow policy
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+
+	return "policy violated: " + strings.Join(msgs, "; ")
+}
+
+// Password are rules for new passwords, zero value accepts any non-empty password
+type Password struct {
+	MinLength     int
+	MaxLength     int // 0 means no limit
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Policy checks emails and new passwords
+type Policy struct {
+	password  Password
+	blocklist map[string]struct{}
+}
+
+// New returns policy, passwords of blocklist are rejected regardless of case
+func New(password Password, blocklist []string) *Policy {
+	p := &Policy{
+		password:  password,
+		blocklist: make(map[string]struct{}, len(blocklist)),
+	}
+
+	for _, b := range blocklist {
+		p.blocklist[strings.ToLower(b)] = struct{}{}
+	}
+
+	return p
+}
+
+// LoadBlocklist reads common passwords from file, one per line.
+// Empty lines and lines starting with # are skipped.
+func LoadBlocklist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []string
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res = append(res, line)
+	}
+
+	return res, sc.Err()
+}
+
+// Check checks email and password of new user, it returns normalized email.
+// Error is *Error with violations of both fields.
+func (p *Policy) Check(email, password string) (string, error) {
+	normalized, violations := p.checkEmail(email)
+	violations = append(violations, p.checkPassword(password)...)
+
+	if len(violations) > 0 {
+		return "", &Error{Violations: violations}
+	}
+
+	return normalized, nil
+}
+
+// CheckEmail checks email and returns it normalized, error is *Error
+func (p *Policy) CheckEmail(email string) (string, error) {
+	normalized, violations := p.checkEmail(email)
+	if len(violations) > 0 {
+		return "", &Error{Violations: violations}
+	}
+
+	return normalized, nil
+}
+
+// CheckPassword checks new password, error is *Error
+func (p *Policy) CheckPassword(password string) error {
+	if violations := p.checkPassword(password); len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+
+	return nil
+}
+
+// NormalizeEmail returns email in the form it's stored in,
+// emails are compared case-insensitively
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (p *Policy) checkEmail(email string) (string, []Violation) {
+	violation := func(d string) (string, []Violation) {
+		return "", []Violation{{Field: FieldEmail, Description: d}}
+	}
+
+	email = NormalizeEmail(email)
+
+	if email == "" {
+		return violation("must not be empty")
+	}
+
+	if len(email) > maxEmailLen {
+		return violation("must be at most 254 characters long")
+	}
+
+	// address without display name and comments, "John <john@example.org>" is rejected
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return violation("must be a valid email address")
+	}
+
+	return email, nil
+}
+
+func (p *Policy) checkPassword(password string) []Violation {
+	var res []Violation
+
+	violation := func(d string) {
+		res = append(res, Violation{Field: FieldPassword, Description: d})
+	}
+
+	if password == "" {
+		violation("must not be empty")
+		return res
+	}
+
+	n := utf8.RuneCountInString(password)
+	if n < p.password.MinLength {
+		violation(fmt.Sprintf("must be at least %d characters long", p.password.MinLength))
+	}
+	if p.password.MaxLength > 0 && n > p.password.MaxLength {
+		violation(fmt.Sprintf("must be at most %d characters long", p.password.MaxLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.password.RequireLower && !lower {
+		violation("must contain a lowercase letter")
+	}
+	if p.password.RequireUpper && !upper {
+		violation("must contain an uppercase letter")
+	}
+	if p.password.RequireDigit && !digit {
+		violation("must contain a digit")
+	}
+	if p.password.RequireSymbol && !symbol {
+		violation("must contain a symbol")
+	}
+
+	if _, ok := p.blocklist[strings.ToLower(password)]; ok {
+		violation("is too common")
+	}
+
+	return res
+}
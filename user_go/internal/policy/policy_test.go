@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newPolicy() *Policy {
+	return New(Password{
+		MinLength:    8,
+		MaxLength:    64,
+		RequireLower: true,
+		RequireUpper: true,
+		RequireDigit: true,
+	}, []string{"Password123"})
+}
+
+func TestPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     string
+		wantErr  []Violation
+	}{
+		{
+			name:     "ok",
+			email:    " User1@Example.ORG ",
+			password: "correctHorse1",
+			want:     "user1@example.org",
+		},
+		{
+			name:     "short password without classes",
+			email:    "user1@example.org",
+			password: "abc",
+			wantErr: []Violation{
+				{Field: FieldPassword, Description: "must be at least 8 characters long"},
+				{Field: FieldPassword, Description: "must contain an uppercase letter"},
+				{Field: FieldPassword, Description: "must contain a digit"},
+			},
+		},
+		{
+			name:     "common password",
+			email:    "user1@example.org",
+			password: "PASSWORD123",
+			wantErr: []Violation{
+				{Field: FieldPassword, Description: "must contain a lowercase letter"},
+				{Field: FieldPassword, Description: "is too common"},
+			},
+		},
+		{
+			name:     "invalid email and empty password",
+			email:    "John <john@example.org>",
+			password: "",
+			wantErr: []Violation{
+				{Field: FieldEmail, Description: "must be a valid email address"},
+				{Field: FieldPassword, Description: "must not be empty"},
+			},
+		},
+		{
+			name:     "email without domain",
+			email:    "user1",
+			password: "correctHorse1",
+			wantErr: []Violation{
+				{Field: FieldEmail, Description: "must be a valid email address"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newPolicy().Check(tt.email, tt.password)
+
+			var pErr *Error
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+			} else if !errors.As(err, &pErr) || !reflect.DeepEqual(pErr.Violations, tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("# common passwords\n\nqwerty\n 123456 \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadBlocklist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"qwerty", "123456"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadBlocklist() = %v, want %v", got, want)
+	}
+}
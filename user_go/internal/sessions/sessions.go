@@ -0,0 +1,214 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"lib/errs"
+	"lib/jwt"
+	"lib/tokens"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/grpc/clientip"
+	"user_service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = errs.New(errs.ErrNotFound, "session not found")
+	ErrNoApp    = errs.New(errs.ErrInvalid, "app_id is required")
+)
+
+type Storage interface {
+	SaveSession(ctx context.Context, s models.Session) error
+	// TouchSession records use of session, unknown session isn't an error
+	TouchSession(ctx context.Context, id, ip string, at time.Time) error
+	UserSessions(ctx context.Context, userID string, activeAfter time.Time) ([]models.Session, error)
+	// RevokeSession returns storage.ErrNotFound if user has no such active session
+	RevokeSession(ctx context.Context, userID, id string, at time.Time) error
+	RevokeUserSessions(ctx context.Context, userID string, at time.Time) error
+	IsSessionRevoked(ctx context.Context, id string) (bool, error)
+	RevokeUserAccessTokens(ctx context.Context, userID string, at time.Time) error
+	RevokeUserOAuthTokens(ctx context.Context, userID string, at time.Time) error
+}
+
+// Tokens is token lifecycle wrapped by Manager, it's implemented by tokens.Manager
+// and by client of standalone auth service.
+type Tokens interface {
+	Issue(ctx context.Context, s tokens.Subject) (tokens.Pair, error)
+	Refresh(ctx context.Context, refreshToken string) (tokens.Pair, error)
+	Family(ctx context.Context, refreshToken string) (string, error)
+	Validate(ctx context.Context, token string) (jwt.Claims, error)
+	Revoke(ctx context.Context, c jwt.Claims, refreshToken string) error
+	RevokeAll(ctx context.Context, userID string) error
+	JWKS(ctx context.Context) ([]jwt.JWK, error)
+}
+
+// Manager records every login as session and lets user revoke them one by one.
+// It wraps token lifecycle: session ID is refresh token family and sid claim of tokens,
+// so tokens of revoked session are rejected by Validate and Refresh right away,
+// whichever service issued them. Session expires when its refresh token does.
+type Manager struct {
+	storage Storage
+	tokens  Tokens
+	ttl     time.Duration // TTL of refresh tokens
+}
+
+func New(storage Storage, tokens Tokens, refreshTTL time.Duration) *Manager {
+	return &Manager{
+		storage: storage,
+		tokens:  tokens,
+		ttl:     refreshTTL,
+	}
+}
+
+// Issue issues tokens of new session, client is taken from context
+func (m *Manager) Issue(ctx context.Context, s tokens.Subject) (tokens.Pair, error) {
+	s.SessionID = uuid.New().String()
+
+	pair, err := m.tokens.Issue(ctx, s)
+	if err != nil {
+		return tokens.Pair{}, err
+	}
+
+	now := time.Now()
+
+	err = m.storage.SaveSession(ctx, models.Session{
+		ID:         s.SessionID,
+		UserID:     s.UserID,
+		AppID:      s.App.ID,
+		UserAgent:  clientip.UserAgent(ctx),
+		IP:         clientip.FromContext(ctx),
+		CreatedAt:  now,
+		LastUsedAt: now,
+	})
+	if err != nil {
+		return tokens.Pair{}, err
+	}
+
+	return pair, nil
+}
+
+// Refresh refreshes tokens and records use of their session.
+// Refresh token of revoked session is rejected before it's rotated, so no tokens are issued for it.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (tokens.Pair, error) {
+	sid, err := m.tokens.Family(ctx, refreshToken)
+	if err != nil {
+		return tokens.Pair{}, err
+	}
+
+	revoked, err := m.storage.IsSessionRevoked(ctx, sid)
+	if err != nil {
+		return tokens.Pair{}, err
+	}
+
+	if revoked {
+		return tokens.Pair{}, tokens.ErrRefreshTokenInvalid
+	}
+
+	pair, err := m.tokens.Refresh(ctx, refreshToken)
+	if err != nil {
+		return tokens.Pair{}, err
+	}
+
+	if err := m.storage.TouchSession(ctx, sid, clientip.FromContext(ctx), time.Now()); err != nil {
+		return tokens.Pair{}, err
+	}
+
+	return pair, nil
+}
+
+// Validate validates token presented to app, it's the only check of user tokens of all RPCs.
+// Token of another app is rejected with tokens.ErrWrongApp, zero appID with ErrNoApp,
+// tokens of revoked session are rejected with tokens.ErrTokenRevoked.
+func (m *Manager) Validate(ctx context.Context, token string, appID int32) (jwt.Claims, error) {
+	if appID == 0 {
+		return jwt.Claims{}, ErrNoApp
+	}
+
+	c, err := m.tokens.Validate(ctx, token)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	if err := tokens.CheckApp(c, appID); err != nil {
+		return jwt.Claims{}, err
+	}
+
+	// tokens issued before sessions were added have no sid
+	if c.SessionID == "" {
+		return c, nil
+	}
+
+	revoked, err := m.storage.IsSessionRevoked(ctx, c.SessionID)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+
+	if revoked {
+		return jwt.Claims{}, tokens.ErrTokenRevoked
+	}
+
+	return c, nil
+}
+
+// Revoke revokes token and ends its session, it's logout
+func (m *Manager) Revoke(ctx context.Context, c jwt.Claims, refreshToken string) error {
+	if err := m.tokens.Revoke(ctx, c, refreshToken); err != nil {
+		return err
+	}
+
+	if c.SessionID == "" {
+		return nil
+	}
+
+	err := m.storage.RevokeSession(ctx, c.UserID, c.SessionID, time.Now())
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeAll revokes all tokens and sessions of user. Personal access tokens and
+// tokens given to OAuth clients are revoked too, they don't outlive password of user.
+func (m *Manager) RevokeAll(ctx context.Context, userID string) error {
+	if err := m.tokens.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if err := m.storage.RevokeUserSessions(ctx, userID, now); err != nil {
+		return err
+	}
+
+	if err := m.storage.RevokeUserAccessTokens(ctx, userID, now); err != nil {
+		return err
+	}
+
+	return m.storage.RevokeUserOAuthTokens(ctx, userID, now)
+}
+
+func (m *Manager) JWKS(ctx context.Context) ([]jwt.JWK, error) {
+	return m.tokens.JWKS(ctx)
+}
+
+// List returns active sessions of user, the last used first
+func (m *Manager) List(ctx context.Context, userID string) ([]models.Session, error) {
+	return m.storage.UserSessions(ctx, userID, time.Now().Add(-m.ttl))
+}
+
+// RevokeSession revokes session of user, its tokens are rejected right away.
+// ErrNotFound is returned if user has no such active session.
+func (m *Manager) RevokeSession(ctx context.Context, userID, id string) error {
+	err := m.storage.RevokeSession(ctx, userID, id, time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"lib/jwt"
+	"lib/tokens"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// fakeStorage keeps sessions by ID in map and remembers users whose
+// access and OAuth tokens were revoked
+type fakeStorage struct {
+	sessions      map[string]models.Session
+	revokedAccess map[string]bool
+	revokedOAuth  map[string]bool
+}
+
+func (f *fakeStorage) SaveSession(_ context.Context, s models.Session) error {
+	f.sessions[s.ID] = s
+	return nil
+}
+
+func (f *fakeStorage) TouchSession(_ context.Context, id, ip string, at time.Time) error {
+	s, ok := f.sessions[id]
+	if !ok {
+		return nil
+	}
+	s.IP = ip
+	s.LastUsedAt = at
+	f.sessions[id] = s
+	return nil
+}
+
+func (f *fakeStorage) UserSessions(_ context.Context, userID string, activeAfter time.Time) ([]models.Session, error) {
+	var res []models.Session
+	for _, s := range f.sessions {
+		if s.UserID == userID && !s.IsRevoked() && s.LastUsedAt.After(activeAfter) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStorage) RevokeSession(_ context.Context, userID, id string, at time.Time) error {
+	s, ok := f.sessions[id]
+	if !ok || s.UserID != userID || s.IsRevoked() {
+		return storage.ErrNotFound
+	}
+	s.RevokedAt = at
+	f.sessions[id] = s
+	return nil
+}
+
+func (f *fakeStorage) RevokeUserSessions(_ context.Context, userID string, at time.Time) error {
+	for id, s := range f.sessions {
+		if s.UserID == userID && !s.IsRevoked() {
+			s.RevokedAt = at
+			f.sessions[id] = s
+		}
+	}
+	return nil
+}
+
+func (f *fakeStorage) IsSessionRevoked(_ context.Context, id string) (bool, error) {
+	return f.sessions[id].IsRevoked(), nil
+}
+
+func (f *fakeStorage) RevokeUserAccessTokens(_ context.Context, userID string, at time.Time) error {
+	f.revokedAccess[userID] = true
+	return nil
+}
+
+func (f *fakeStorage) RevokeUserOAuthTokens(_ context.Context, userID string, at time.Time) error {
+	f.revokedOAuth[userID] = true
+	return nil
+}
+
+// fakeTokens issues opaque tokens, claims of auth and refresh tokens are kept in maps
+type fakeTokens struct {
+	auth    map[string]jwt.Claims
+	refresh map[string]jwt.Claims
+	rotated int
+}
+
+func (f *fakeTokens) Issue(_ context.Context, s tokens.Subject) (tokens.Pair, error) {
+	return f.pair(jwt.Claims{UserID: s.UserID, Email: s.Email, AppID: s.App.ID, SessionID: s.SessionID}), nil
+}
+
+func (f *fakeTokens) Refresh(_ context.Context, refreshToken string) (tokens.Pair, error) {
+	c, ok := f.refresh[refreshToken]
+	if !ok {
+		return tokens.Pair{}, tokens.ErrRefreshTokenInvalid
+	}
+	delete(f.refresh, refreshToken)
+	f.rotated++
+	return f.pair(c), nil
+}
+
+func (f *fakeTokens) Family(_ context.Context, refreshToken string) (string, error) {
+	c, ok := f.refresh[refreshToken]
+	if !ok {
+		return "", tokens.ErrRefreshTokenInvalid
+	}
+	return c.SessionID, nil
+}
+
+func (f *fakeTokens) Validate(_ context.Context, token string) (jwt.Claims, error) {
+	c, ok := f.auth[token]
+	if !ok {
+		return jwt.Claims{}, tokens.ErrTokenRevoked
+	}
+	return c, nil
+}
+
+func (f *fakeTokens) Revoke(_ context.Context, c jwt.Claims, refreshToken string) error {
+	for t, tc := range f.auth {
+		if tc.JTI == c.JTI {
+			delete(f.auth, t)
+		}
+	}
+	delete(f.refresh, refreshToken)
+	return nil
+}
+
+func (f *fakeTokens) RevokeAll(_ context.Context, userID string) error {
+	for t, c := range f.auth {
+		if c.UserID == userID {
+			delete(f.auth, t)
+		}
+	}
+	for t, c := range f.refresh {
+		if c.UserID == userID {
+			delete(f.refresh, t)
+		}
+	}
+	return nil
+}
+
+func (f *fakeTokens) JWKS(context.Context) ([]jwt.JWK, error) {
+	return nil, nil
+}
+
+func (f *fakeTokens) pair(c jwt.Claims) tokens.Pair {
+	c.JTI = uuid.New().String()
+	p := tokens.Pair{Token: uuid.New().String(), RefreshToken: uuid.New().String(), Claims: c}
+	f.auth[p.Token] = c
+	f.refresh[p.RefreshToken] = c
+	return p
+}
+
+var subject = tokens.Subject{UserID: "user-id", Email: "user1@example.org", App: tokens.App{ID: 1}}
+
+func newManager() (*Manager, *fakeTokens) {
+	ft := &fakeTokens{
+		auth:    map[string]jwt.Claims{},
+		refresh: map[string]jwt.Claims{},
+	}
+
+	st := &fakeStorage{
+		sessions:      map[string]models.Session{},
+		revokedAccess: map[string]bool{},
+		revokedOAuth:  map[string]bool{},
+	}
+
+	return New(st, ft, time.Hour), ft
+}
+
+func TestManager_RevokeSession(t *testing.T) {
+	ctx := context.Background()
+	m, ft := newManager()
+
+	laptop, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	phone, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := m.List(ctx, subject.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("List() got %d sessions, want 2", len(list))
+	}
+
+	if err := m.RevokeSession(ctx, "other-id", laptop.Claims.SessionID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RevokeSession() of other user error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := m.RevokeSession(ctx, subject.UserID, laptop.Claims.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Validate(ctx, laptop.Token, subject.App.ID); !errors.Is(err, tokens.ErrTokenRevoked) {
+		t.Errorf("Validate() error = %v, want %v", err, tokens.ErrTokenRevoked)
+	}
+
+	if _, err := m.Refresh(ctx, laptop.RefreshToken); !errors.Is(err, tokens.ErrRefreshTokenInvalid) {
+		t.Errorf("Refresh() error = %v, want %v", err, tokens.ErrRefreshTokenInvalid)
+	}
+
+	// refresh token of revoked session is rejected before it's rotated
+	if ft.rotated != 0 {
+		t.Errorf("Refresh() rotated refresh token of revoked session")
+	}
+
+	if _, err := m.Validate(ctx, phone.Token, subject.App.ID); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+
+	refreshed, err := m.Refresh(ctx, phone.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Claims.SessionID != phone.Claims.SessionID {
+		t.Errorf("Refresh() sid = %v, want %v", refreshed.Claims.SessionID, phone.Claims.SessionID)
+	}
+
+	list, err = m.List(ctx, subject.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != phone.Claims.SessionID {
+		t.Errorf("List() got = %v, want only session %v", list, phone.Claims.SessionID)
+	}
+}
+
+func TestManager_Validate(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newManager()
+
+	p, err := m.Issue(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		appID int32
+		want  error
+	}{
+		{name: "same app", appID: subject.App.ID},
+		{name: "another app", appID: 2, want: tokens.ErrWrongApp},
+		{name: "no app", appID: 0, want: ErrNoApp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Validate(ctx, p.Token, tt.appID); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_RevokeAll(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newManager()
+
+	if _, err := m.Issue(ctx, subject); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.RevokeAll(ctx, subject.UserID); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := m.List(ctx, subject.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Errorf("List() got %d sessions, want 0", len(list))
+	}
+
+	st := m.storage.(*fakeStorage)
+	if !st.revokedAccess[subject.UserID] {
+		t.Error("RevokeAll() didn't revoke personal access tokens")
+	}
+	if !st.revokedOAuth[subject.UserID] {
+		t.Error("RevokeAll() didn't revoke OAuth tokens")
+	}
+}
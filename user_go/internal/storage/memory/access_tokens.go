@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) SaveAccessToken(ctx context.Context, t models.AccessToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accessTokens[t.ID]; ok {
+		return storage.ErrAlreadyExists
+	}
+	for _, old := range s.accessTokens {
+		if old.TokenHash == t.TokenHash {
+			return storage.ErrAlreadyExists
+		}
+	}
+
+	t.Scopes = cloneStrings(t.Scopes)
+	s.accessTokens[t.ID] = t
+
+	return nil
+}
+
+func (s *Storage) FindAccessToken(ctx context.Context, tokenHash string) (models.AccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.accessTokens {
+		if t.TokenHash == tokenHash {
+			t.Scopes = cloneStrings(t.Scopes)
+			return t, nil
+		}
+	}
+
+	return models.AccessToken{}, storage.ErrNotFound
+}
+
+// UserAccessTokens returns not revoked tokens of user, the newest first
+func (s *Storage) UserAccessTokens(ctx context.Context, userID string) ([]models.AccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []models.AccessToken
+	for _, t := range s.accessTokens {
+		if t.UserID == userID && !t.IsRevoked() {
+			t.Scopes = cloneStrings(t.Scopes)
+			res = append(res, t)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.After(res[j].CreatedAt)
+	})
+
+	return res, nil
+}
+
+func (s *Storage) TouchAccessToken(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.accessTokens[id]; ok {
+		t.LastUsedAt = at
+		s.accessTokens[id] = t
+	}
+
+	return nil
+}
+
+// RevokeAccessToken revokes token of user, storage.ErrNotFound is returned
+// if user has no such token or it's already revoked
+func (s *Storage) RevokeAccessToken(ctx context.Context, userID, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.accessTokens[id]
+	if !ok || t.UserID != userID || t.IsRevoked() {
+		return storage.ErrNotFound
+	}
+
+	t.RevokedAt = at
+	s.accessTokens[id] = t
+
+	return nil
+}
+
+// RevokeUserAccessTokens revokes all not yet revoked tokens of user
+func (s *Storage) RevokeUserAccessTokens(ctx context.Context, userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.accessTokens {
+		if t.UserID == userID && !t.IsRevoked() {
+			t.RevokedAt = at
+			s.accessTokens[id] = t
+		}
+	}
+
+	return nil
+}
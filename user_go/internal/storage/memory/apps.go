@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) FindAppByID(ctx context.Context, id int32) (models.App, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.apps[id]
+	if !ok {
+		return models.App{}, storage.ErrNotFound
+	}
+
+	a.AllowedOrigins = cloneStrings(a.AllowedOrigins)
+
+	return a, nil
+}
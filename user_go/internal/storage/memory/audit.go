@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+)
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastAuditID++
+	e.ID = s.lastAuditID
+	s.auditEvents = append(s.auditEvents, e)
+
+	return nil
+}
+
+// ListAuditEvents returns page of audit events selected by filter, the newest first
+func (s *Storage) ListAuditEvents(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []models.AuditEvent
+	// events are appended with growing ids, so going from the end gives the newest first
+	for i := len(s.auditEvents) - 1; i >= 0 && len(res) < f.Limit; i-- {
+		e := s.auditEvents[i]
+		switch {
+		case f.Type != "" && e.Type != f.Type,
+			f.Outcome != "" && e.Outcome != f.Outcome,
+			f.ActorID != "" && e.ActorID != f.ActorID,
+			f.UserID != "" && e.UserID != f.UserID,
+			f.IP != "" && e.IP != f.IP,
+			!f.After.IsZero() && e.CreatedAt.Before(f.After),
+			!f.Before.IsZero() && !e.CreatedAt.Before(f.Before),
+			f.BeforeID != 0 && e.ID >= f.BeforeID:
+			continue
+		}
+		res = append(res, e)
+	}
+
+	return res, nil
+}
+
+// DeleteAuditEvents deletes events created before time and returns their number
+func (s *Storage) DeleteAuditEvents(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.auditEvents[:0]
+	for _, e := range s.auditEvents {
+		if !e.CreatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	n := int64(len(s.auditEvents) - len(kept))
+	s.auditEvents = kept
+
+	return n, nil
+}
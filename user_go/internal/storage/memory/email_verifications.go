@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// SaveEmailVerification saves token and removes unused tokens sent to user before
+func (s *Storage) SaveEmailVerification(ctx context.Context, v models.EmailVerification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.emailVerifications[v.TokenHash]; ok {
+		return storage.ErrAlreadyExists
+	}
+
+	for hash, old := range s.emailVerifications {
+		if old.UserID == v.UserID && !old.IsUsed() {
+			delete(s.emailVerifications, hash)
+		}
+	}
+
+	s.emailVerifications[v.TokenHash] = v
+
+	return nil
+}
+
+func (s *Storage) FindEmailVerification(ctx context.Context, tokenHash string) (models.EmailVerification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.emailVerifications[tokenHash]
+	if !ok {
+		return models.EmailVerification{}, storage.ErrNotFound
+	}
+
+	return v, nil
+}
+
+// VerifyEmail marks token as used and email of its owner as verified at once.
+// storage.ErrAlreadyExists is returned if token was already used and storage.ErrNotFound
+// if user was deleted or changed email after token was sent.
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.emailVerifications[tokenHash]
+	if !ok || v.IsUsed() {
+		return storage.ErrAlreadyExists
+	}
+
+	u, ok := s.activeUser(v.UserID)
+	if !ok || u.Email != v.Email {
+		return storage.ErrNotFound
+	}
+
+	v.UsedAt = usedAt
+	s.emailVerifications[tokenHash] = v
+
+	u.EmailVerified = true
+	s.users[u.ID] = u
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"sort"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// SaveIdentity links identity to user, storage.ErrAlreadyExists is returned
+// if identity is linked to some user already
+func (s *Storage) SaveIdentity(ctx context.Context, id models.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveIdentity(id)
+}
+
+// SaveFederatedUser creates user without password with his identity at once,
+// storage.ErrAlreadyExists is returned if email or identity is used by another user
+func (s *Storage) SaveFederatedUser(ctx context.Context, u models.User, id models.Identity) (models.User, error) {
+	if len(u.Email) < 3 {
+		return models.User{}, storage.ErrEmptyFields
+	}
+	u.ID = uuid.New().String()
+	u.EncPassword = nil
+	id.UserID = u.ID
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailUsed(u.Email, "") {
+		return models.User{}, storage.ErrAlreadyExists
+	}
+
+	if err := s.saveIdentity(id); err != nil {
+		return models.User{}, err
+	}
+
+	s.saveUser(u)
+
+	return u, nil
+}
+
+// saveIdentity gives identity new id, it's called with locked mutex
+func (s *Storage) saveIdentity(id models.Identity) error {
+	for _, old := range s.identities {
+		if old.Provider == id.Provider && old.Subject == id.Subject {
+			return storage.ErrAlreadyExists
+		}
+	}
+
+	id.ID = uuid.New().String()
+	s.identities[id.ID] = id
+
+	return nil
+}
+
+// FindIdentity returns identity of provider with subject
+func (s *Storage) FindIdentity(ctx context.Context, provider, subject string) (models.Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.identities {
+		if id.Provider == provider && id.Subject == subject {
+			return id, nil
+		}
+	}
+
+	return models.Identity{}, storage.ErrNotFound
+}
+
+// UserIdentities returns identities of user, the oldest first
+func (s *Storage) UserIdentities(ctx context.Context, userID string) ([]models.Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []models.Identity
+	for _, id := range s.identities {
+		if id.UserID == userID {
+			res = append(res, id)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+
+	return res, nil
+}
+
+// DeleteIdentity unlinks identity of user, storage.ErrNotFound is returned if user has no such identity
+func (s *Storage) DeleteIdentity(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := s.identities[id]
+	if !ok || identity.UserID != userID {
+		return storage.ErrNotFound
+	}
+	delete(s.identities, id)
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) GetLoginFailures(ctx context.Context, key string) (models.LoginFailures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.loginFailures[key]
+	if !ok {
+		return models.LoginFailures{}, storage.ErrNotFound
+	}
+
+	return f, nil
+}
+
+// AddLoginFailure increments counter under lock, so concurrent failures are all counted
+func (s *Storage) AddLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (models.LoginFailures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.loginFailures[key]
+	if !ok || f.LastFailureAt.Before(resetBefore) {
+		f.Key, f.Failures = key, 1
+	} else {
+		f.Failures++
+	}
+	f.LastFailureAt = at
+	s.loginFailures[key] = f
+
+	return f, nil
+}
+
+func (s *Storage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.loginFailures[key]; ok {
+		f.LockedUntil = until
+		s.loginFailures[key] = f
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteLoginFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.loginFailures, key)
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// Storage keeps everything in maps guarded by one mutex, so every method is atomic
+// like transaction of postgres.Storage. Data is lost on restart, it's meant for tests
+// and local development.
+type Storage struct {
+	mu sync.Mutex
+
+	users     map[string]models.User // by id
+	apps      map[int32]models.App
+	roles     map[string]models.Role
+	userRoles map[string]map[string]bool // user id -> role names
+
+	revokedTokens   map[string]time.Time // jti -> expiration of token
+	userRevocations map[string]time.Time // user id -> tokens issued not after it are revoked
+	refreshTokens   map[string]models.RefreshToken
+	sessions        map[string]models.Session
+
+	emailVerifications map[string]models.EmailVerification // by token hash
+	passwordResets     map[string]models.PasswordReset     // by token hash
+	loginFailures      map[string]models.LoginFailures
+
+	mfa           map[string]models.MFA
+	recoveryCodes map[string]map[string]time.Time // user id -> code hash -> time it was used
+
+	accessTokens map[string]models.AccessToken
+	oauthClients map[string]models.OAuthClient
+	oauthCodes   map[string]models.OAuthCode // by code hash
+	oauthTokens  map[string]models.OAuthToken
+	identities   map[string]models.Identity
+
+	auditEvents []models.AuditEvent
+	lastAuditID int64
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// New returns storage with roles and apps created by migrations
+func New() *Storage {
+	s := &Storage{
+		users:              make(map[string]models.User),
+		apps:               make(map[int32]models.App),
+		roles:              make(map[string]models.Role),
+		userRoles:          make(map[string]map[string]bool),
+		revokedTokens:      make(map[string]time.Time),
+		userRevocations:    make(map[string]time.Time),
+		refreshTokens:      make(map[string]models.RefreshToken),
+		sessions:           make(map[string]models.Session),
+		emailVerifications: make(map[string]models.EmailVerification),
+		passwordResets:     make(map[string]models.PasswordReset),
+		loginFailures:      make(map[string]models.LoginFailures),
+		mfa:                make(map[string]models.MFA),
+		recoveryCodes:      make(map[string]map[string]time.Time),
+		accessTokens:       make(map[string]models.AccessToken),
+		oauthClients:       make(map[string]models.OAuthClient),
+		oauthCodes:         make(map[string]models.OAuthCode),
+		oauthTokens:        make(map[string]models.OAuthToken),
+		identities:         make(map[string]models.Identity),
+	}
+
+	for id, name := range map[int32]string{1: "web", 2: "cli", 3: "internal"} {
+		s.apps[id] = models.App{ID: id, Name: name}
+	}
+
+	s.roles[models.RoleUser] = models.Role{
+		Name:        models.RoleUser,
+		Description: "Default role of every registered user",
+		Permissions: []string{models.PermissionFilesRead, models.PermissionFilesWrite},
+	}
+	s.roles[models.RoleAdmin] = models.Role{
+		Name:        models.RoleAdmin,
+		Description: "Can read all files and manage roles",
+		Permissions: []string{
+			models.PermissionAuditRead,
+			models.PermissionFilesRead,
+			models.PermissionFilesReadAny,
+			models.PermissionFilesWrite,
+			models.PermissionRolesManage,
+			models.PermissionUsersManage,
+			models.PermissionUsersRead,
+			models.PermissionUsersUnlock,
+		},
+	}
+
+	return s
+}
+
+func (s *Storage) SaveUser(ctx context.Context, u models.User) (models.User, error) {
+	// password is hashed by caller
+	if len(u.EncPassword) == 0 || len(u.Email) < 3 {
+		return models.User{}, storage.ErrEmptyFields
+	}
+	u.ID = uuid.New().String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailUsed(u.Email, "") {
+		return models.User{}, storage.ErrAlreadyExists
+	}
+
+	s.saveUser(u)
+
+	return u, nil
+}
+
+// saveUser saves new user with default role, it's called with locked mutex
+func (s *Storage) saveUser(u models.User) {
+	u.EncPassword = cloneBytes(u.EncPassword)
+	// microseconds like timestamps of postgres, cursors of ListUsers keep only them
+	u.CreatedAt = time.Now().Truncate(time.Microsecond)
+	s.users[u.ID] = u
+
+	// every user gets default role
+	s.userRoles[u.ID] = map[string]bool{models.RoleUser: true}
+}
+
+// emailUsed reports whether not deleted user other than userID has email
+func (s *Storage) emailUsed(email, userID string) bool {
+	for _, u := range s.users {
+		if u.Email == email && u.DeletedAt.IsZero() && u.ID != userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Storage) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email && u.DeletedAt.IsZero() {
+			return cloneUser(u), nil
+		}
+	}
+
+	return models.User{}, storage.ErrNotFound
+}
+
+func (s *Storage) FindUserByID(ctx context.Context, id string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.activeUser(id)
+	if !ok {
+		return models.User{}, storage.ErrNotFound
+	}
+
+	return cloneUser(u), nil
+}
+
+// activeUser returns not deleted user
+func (s *Storage) activeUser(id string) (models.User, bool) {
+	u, ok := s.users[id]
+	if !ok || !u.DeletedAt.IsZero() {
+		return models.User{}, false
+	}
+
+	return u, true
+}
+
+// UpdatePassword replaces password hash of user
+func (s *Storage) UpdatePassword(ctx context.Context, userID string, encPassword []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.activeUser(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	u.EncPassword = cloneBytes(encPassword)
+	s.users[userID] = u
+
+	return nil
+}
+
+func cloneUser(u models.User) models.User {
+	u.EncPassword = cloneBytes(u.EncPassword)
+	return u
+}
+
+func cloneBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+func cloneStrings(s []string) []string {
+	return append([]string(nil), s...)
+}
@@ -0,0 +1,10 @@
+package memory
+
+import (
+	"testing"
+	"user_service/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, New())
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) GetMFA(ctx context.Context, userID string) (models.MFA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.mfa[userID]
+	if !ok {
+		return models.MFA{}, storage.ErrNotFound
+	}
+
+	return m, nil
+}
+
+// SaveMFA saves pending authenticator, enabled one isn't replaced and storage.ErrAlreadyExists is returned
+func (s *Storage) SaveMFA(ctx context.Context, m models.MFA) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mfa[m.UserID].Enabled {
+		return storage.ErrAlreadyExists
+	}
+
+	m.Enabled, m.LastUsedStep = false, 0
+	s.mfa[m.UserID] = m
+
+	return nil
+}
+
+func (s *Storage) EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.mfa[userID]
+	if !ok || m.Enabled {
+		return storage.ErrAlreadyExists
+	}
+
+	m.Enabled, m.LastUsedStep = true, step
+	s.mfa[userID] = m
+
+	codes := make(map[string]time.Time, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = time.Time{}
+	}
+	s.recoveryCodes[userID] = codes
+
+	return nil
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.mfa[userID]
+	if !ok || m.LastUsedStep >= step {
+		return storage.ErrAlreadyExists
+	}
+
+	m.LastUsedStep = step
+	s.mfa[userID] = m
+
+	return nil
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usedAt, ok := s.recoveryCodes[userID][codeHash]
+	if !ok || !usedAt.IsZero() {
+		return storage.ErrNotFound
+	}
+
+	s.recoveryCodes[userID][codeHash] = at
+
+	return nil
+}
+
+func (s *Storage) DeleteMFA(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.recoveryCodes, userID)
+	delete(s.mfa, userID)
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) SaveOAuthClient(ctx context.Context, c models.OAuthClient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.oauthClients[c.ID]; ok {
+		return storage.ErrAlreadyExists
+	}
+
+	c.RedirectURIs = cloneStrings(c.RedirectURIs)
+	s.oauthClients[c.ID] = c
+
+	return nil
+}
+
+func (s *Storage) FindOAuthClient(ctx context.Context, id string) (models.OAuthClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.oauthClients[id]
+	if !ok {
+		return models.OAuthClient{}, storage.ErrNotFound
+	}
+
+	c.RedirectURIs = cloneStrings(c.RedirectURIs)
+
+	return c, nil
+}
+
+func (s *Storage) SaveOAuthCode(ctx context.Context, c models.OAuthCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.oauthCodes[c.CodeHash]; ok {
+		return storage.ErrAlreadyExists
+	}
+
+	c.Scopes = cloneStrings(c.Scopes)
+	s.oauthCodes[c.CodeHash] = c
+
+	return nil
+}
+
+func (s *Storage) FindOAuthCode(ctx context.Context, codeHash string) (models.OAuthCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.oauthCodes[codeHash]
+	if !ok {
+		return models.OAuthCode{}, storage.ErrNotFound
+	}
+
+	c.Scopes = cloneStrings(c.Scopes)
+
+	return c, nil
+}
+
+// UseOAuthCode marks code as used, storage.ErrAlreadyExists is returned if it was used before,
+// so two concurrent exchanges of the same code can't both succeed
+func (s *Storage) UseOAuthCode(ctx context.Context, codeHash string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.oauthCodes[codeHash]
+	if !ok || c.IsUsed() {
+		return storage.ErrAlreadyExists
+	}
+
+	c.UsedAt = at
+	s.oauthCodes[codeHash] = c
+
+	return nil
+}
+
+// SaveOAuthTokens saves access and refresh token of grant at once
+func (s *Storage) SaveOAuthTokens(ctx context.Context, tokens ...models.OAuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveOAuthTokens(tokens)
+}
+
+func (s *Storage) FindOAuthToken(ctx context.Context, tokenHash string) (models.OAuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.oauthTokens {
+		if t.TokenHash == tokenHash {
+			t.Scopes = cloneStrings(t.Scopes)
+			return t, nil
+		}
+	}
+
+	return models.OAuthToken{}, storage.ErrNotFound
+}
+
+// RotateOAuthRefreshToken marks refresh token with oldID as used and saves next tokens
+// at once. If old token was already used storage.ErrAlreadyExists is returned.
+func (s *Storage) RotateOAuthRefreshToken(ctx context.Context, oldID string, at time.Time, next ...models.OAuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.oauthTokens[oldID]
+	if !ok || old.IsUsed() || old.IsRevoked() {
+		return storage.ErrAlreadyExists
+	}
+
+	if err := s.saveOAuthTokens(next); err != nil {
+		return err
+	}
+
+	old.UsedAt = at
+	s.oauthTokens[oldID] = old
+
+	return nil
+}
+
+// RevokeOAuthGrant revokes all not yet revoked tokens of grant
+func (s *Storage) RevokeOAuthGrant(ctx context.Context, grantID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.oauthTokens {
+		if t.GrantID == grantID && !t.IsRevoked() {
+			t.RevokedAt = at
+			s.oauthTokens[id] = t
+		}
+	}
+
+	return nil
+}
+
+// RevokeUserOAuthTokens revokes all not yet revoked tokens which user gave to clients
+func (s *Storage) RevokeUserOAuthTokens(ctx context.Context, userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, t := range s.oauthTokens {
+		if t.UserID == userID && !t.IsRevoked() {
+			t.RevokedAt = at
+			s.oauthTokens[id] = t
+		}
+	}
+
+	return nil
+}
+
+// saveOAuthTokens saves all tokens or none of them, it's called with locked mutex
+func (s *Storage) saveOAuthTokens(tokens []models.OAuthToken) error {
+	for i, t := range tokens {
+		if _, ok := s.oauthTokens[t.ID]; ok {
+			return storage.ErrAlreadyExists
+		}
+		for _, old := range s.oauthTokens {
+			if old.TokenHash == t.TokenHash {
+				return storage.ErrAlreadyExists
+			}
+		}
+		for _, prev := range tokens[:i] {
+			if prev.ID == t.ID || prev.TokenHash == t.TokenHash {
+				return storage.ErrAlreadyExists
+			}
+		}
+	}
+
+	for _, t := range tokens {
+		t.Scopes = cloneStrings(t.Scopes)
+		s.oauthTokens[t.ID] = t
+	}
+
+	return nil
+}
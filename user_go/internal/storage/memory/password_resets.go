@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// SavePasswordReset saves token and removes unused tokens of user requested before
+func (s *Storage) SavePasswordReset(ctx context.Context, r models.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.passwordResets[r.TokenHash]; ok {
+		return storage.ErrAlreadyExists
+	}
+
+	for hash, old := range s.passwordResets {
+		if old.UserID == r.UserID && !old.IsUsed() {
+			delete(s.passwordResets, hash)
+		}
+	}
+
+	s.passwordResets[r.TokenHash] = r
+
+	return nil
+}
+
+func (s *Storage) FindPasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.passwordResets[tokenHash]
+	if !ok {
+		return models.PasswordReset{}, storage.ErrNotFound
+	}
+
+	return r, nil
+}
+
+// ResetPassword marks token as used and sets password of its owner at once.
+// If token was already used storage.ErrAlreadyExists is returned and password isn't changed.
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash string, encPassword []byte, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.passwordResets[tokenHash]
+	if !ok || r.IsUsed() {
+		return storage.ErrAlreadyExists
+	}
+
+	r.UsedAt = usedAt
+	s.passwordResets[tokenHash] = r
+
+	if u, ok := s.users[r.UserID]; ok {
+		u.EncPassword = cloneBytes(encPassword)
+		s.users[u.ID] = u
+	}
+
+	return nil
+}
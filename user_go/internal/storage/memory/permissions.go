@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var roles []string
+	for role := range s.userRoles[userID] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+func (s *Storage) GetUserPermissions(ctx context.Context, userID string) ([]models.Permission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make(map[string]bool)
+	for role := range s.userRoles[userID] {
+		for _, name := range s.roles[role].Permissions {
+			names[name] = true
+		}
+	}
+
+	permissions := make([]models.Permission, 0, len(names))
+	for name := range names {
+		permissions = append(permissions, models.Permission{Name: name})
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
+
+	return permissions, nil
+}
+
+func (s *Storage) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for role := range s.userRoles[userID] {
+		for _, name := range s.roles[role].Permissions {
+			if name == permission {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (s *Storage) ListRoles(ctx context.Context) ([]models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roles := make([]models.Role, 0, len(s.roles))
+	for _, r := range s.roles {
+		r.Permissions = cloneStrings(r.Permissions)
+		sort.Strings(r.Permissions)
+		roles = append(roles, r)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+
+	return roles, nil
+}
+
+// AssignRole gives role to user, assigning role user already has isn't an error.
+// Returns storage.ErrNotFound if user or role doesn't exist.
+func (s *Storage) AssignRole(ctx context.Context, userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return storage.ErrNotFound
+	}
+	if _, ok := s.roles[role]; !ok {
+		return storage.ErrNotFound
+	}
+
+	if s.userRoles[userID] == nil {
+		s.userRoles[userID] = make(map[string]bool)
+	}
+	s.userRoles[userID][role] = true
+
+	return nil
+}
+
+// RevokeRole takes role from user, returns storage.ErrNotFound if user doesn't have it
+func (s *Storage) RevokeRole(ctx context.Context, userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userRoles[userID][role] {
+		return storage.ErrNotFound
+	}
+	delete(s.userRoles[userID], role)
+
+	return nil
+}
+
+// SetUserRoles replaces roles of user.
+// Returns storage.ErrNotFound if user or one of roles doesn't exist.
+func (s *Storage) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.activeUser(userID); !ok {
+		return storage.ErrNotFound
+	}
+
+	set := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		if _, ok := s.roles[role]; !ok {
+			return storage.ErrNotFound
+		}
+		set[role] = true
+	}
+	s.userRoles[userID] = set
+
+	return nil
+}
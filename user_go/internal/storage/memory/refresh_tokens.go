@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, t models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveRefreshToken(t)
+}
+
+// saveRefreshToken checks unique columns like postgres does, it's called with locked mutex
+func (s *Storage) saveRefreshToken(t models.RefreshToken) error {
+	if _, ok := s.refreshTokens[t.ID]; ok {
+		return storage.ErrAlreadyExists
+	}
+	for _, old := range s.refreshTokens {
+		if old.TokenHash == t.TokenHash {
+			return storage.ErrAlreadyExists
+		}
+	}
+
+	s.refreshTokens[t.ID] = t
+
+	return nil
+}
+
+func (s *Storage) FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.refreshTokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+
+	return models.RefreshToken{}, storage.ErrNotFound
+}
+
+// RotateRefreshToken marks token with oldID as used and saves next at once.
+// If old token was already used storage.ErrAlreadyExists is returned and next isn't saved,
+// so two concurrent refreshes with the same token can't both succeed.
+func (s *Storage) RotateRefreshToken(ctx context.Context, oldID string, next models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.refreshTokens[oldID]
+	if !ok || old.IsUsed() || old.IsRevoked() {
+		return storage.ErrAlreadyExists
+	}
+
+	if err := s.saveRefreshToken(next); err != nil {
+		return err
+	}
+
+	old.UsedAt = next.CreatedAt
+	s.refreshTokens[oldID] = old
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes all not yet revoked tokens of the family
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeRefreshTokens(func(t models.RefreshToken) bool {
+		return t.FamilyID == familyID
+	})
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes all not yet revoked tokens of user
+func (s *Storage) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeRefreshTokens(func(t models.RefreshToken) bool {
+		return t.UserID == userID
+	})
+
+	return nil
+}
+
+// revokeRefreshTokens revokes not yet revoked tokens selected by match
+func (s *Storage) revokeRefreshTokens(match func(t models.RefreshToken) bool) {
+	now := time.Now()
+	for id, t := range s.refreshTokens {
+		if match(t) && !t.IsRevoked() {
+			t.RevokedAt = now
+			s.refreshTokens[id] = t
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+func (s *Storage) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// nobody needs revocations of expired tokens, so cleaning them up here
+	now := time.Now()
+	for id, exp := range s.revokedTokens {
+		if exp.Before(now) {
+			delete(s.revokedTokens, id)
+		}
+	}
+
+	if _, ok := s.revokedTokens[jti]; !ok {
+		s.revokedTokens[jti] = expiresAt
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes all user tokens issued before or in the second of before.
+// iat of tokens has seconds only, so cutoff is rounded up to the next second,
+// otherwise token issued earlier in the same second would stay valid.
+func (s *Storage) RevokeUserTokens(ctx context.Context, userID string, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.userRevocations[userID] = before.Truncate(time.Second).Add(time.Second)
+
+	return nil
+}
+
+// IsTokenRevoked returns true if token was revoked by jti
+// or all user tokens were revoked after issuedAt
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.revokedTokens[jti]; ok {
+		return true, nil
+	}
+
+	before, ok := s.userRevocations[userID]
+
+	return ok && before.After(issuedAt), nil
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) SaveSession(ctx context.Context, sess models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[sess.ID]; ok {
+		return storage.ErrAlreadyExists
+	}
+	s.sessions[sess.ID] = sess
+
+	return nil
+}
+
+// TouchSession records use of session from ip, unknown session isn't an error
+// because tokens issued before sessions were added have no session
+func (s *Storage) TouchSession(ctx context.Context, id, ip string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+
+	sess.LastUsedAt, sess.IP = at, ip
+	s.sessions[id] = sess
+
+	return nil
+}
+
+// UserSessions returns not revoked sessions of user used after activeAfter, the last used first
+func (s *Storage) UserSessions(ctx context.Context, userID string, activeAfter time.Time) ([]models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []models.Session
+	for _, sess := range s.sessions {
+		if sess.UserID == userID && !sess.IsRevoked() && sess.LastUsedAt.After(activeAfter) {
+			res = append(res, sess)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastUsedAt.After(res[j].LastUsedAt)
+	})
+
+	return res, nil
+}
+
+// RevokeSession revokes session of user, storage.ErrNotFound is returned
+// if user has no such session or it's already revoked
+func (s *Storage) RevokeSession(ctx context.Context, userID, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || sess.UserID != userID || sess.IsRevoked() {
+		return storage.ErrNotFound
+	}
+
+	sess.RevokedAt = at
+	s.sessions[id] = sess
+
+	return nil
+}
+
+// RevokeUserSessions revokes all not yet revoked sessions of user
+func (s *Storage) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, sess := range s.sessions {
+		if sess.UserID == userID && !sess.IsRevoked() {
+			sess.RevokedAt = at
+			s.sessions[id] = sess
+		}
+	}
+
+	return nil
+}
+
+// IsSessionRevoked returns true if session was revoked, unknown session isn't revoked
+func (s *Storage) IsSessionRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessions[id].IsRevoked(), nil
+}
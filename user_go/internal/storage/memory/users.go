@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// UpdateProfile sets display name of user
+func (s *Storage) UpdateProfile(ctx context.Context, userID, displayName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.activeUser(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	u.DisplayName = displayName
+	s.users[userID] = u
+
+	return nil
+}
+
+// ChangeEmail sets new not verified email of user,
+// storage.ErrAlreadyExists is returned if another user has it
+func (s *Storage) ChangeEmail(ctx context.Context, userID, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailUsed(email, userID) {
+		return storage.ErrAlreadyExists
+	}
+
+	u, ok := s.activeUser(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	u.Email, u.EmailVerified = email, false
+	s.users[userID] = u
+
+	return nil
+}
+
+// DeleteUser marks user as deleted, deleted users aren't found
+// and their email and linked identities can be used by new users
+func (s *Storage) DeleteUser(ctx context.Context, userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.activeUser(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	u.DeletedAt = at
+	s.users[userID] = u
+
+	for id, identity := range s.identities {
+		if identity.UserID == userID {
+			delete(s.identities, id)
+		}
+	}
+
+	return nil
+}
+
+// ListUsers returns page of users selected by filter
+func (s *Storage) ListUsers(ctx context.Context, f models.UserFilter) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []models.User
+	for _, u := range s.users {
+		switch f.Status {
+		case models.UserStatusActive, models.UserStatusDisabled, models.UserStatusDeleted:
+			if u.Status() != f.Status {
+				continue
+			}
+		default:
+			if u.Status() == models.UserStatusDeleted {
+				continue
+			}
+		}
+		if !strings.HasPrefix(u.Email, f.EmailPrefix) {
+			continue
+		}
+		if !f.CreatedAfter.IsZero() && u.CreatedAt.Before(f.CreatedAfter) {
+			continue
+		}
+		if !f.CreatedBefore.IsZero() && !u.CreatedAt.Before(f.CreatedBefore) {
+			continue
+		}
+		if f.AfterID != "" && !userAfter(u, f.AfterCreatedAt, f.AfterID) {
+			continue
+		}
+		res = append(res, cloneUser(u))
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return userAfter(res[j], res[i].CreatedAt, res[i].ID)
+	})
+
+	if len(res) > f.Limit {
+		res = res[:f.Limit]
+	}
+
+	return res, nil
+}
+
+// userAfter reports whether user is after createdAt and id in order of ListUsers
+func userAfter(u models.User, createdAt time.Time, id string) bool {
+	if u.CreatedAt.Equal(createdAt) {
+		return u.ID > id
+	}
+
+	return u.CreatedAt.After(createdAt)
+}
+
+// DisableUser marks user as disabled, disabling disabled user isn't an error
+func (s *Storage) DisableUser(ctx context.Context, userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.activeUser(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	if u.DisabledAt.IsZero() {
+		u.DisabledAt = at
+		s.users[userID] = u
+	}
+
+	return nil
+}
+
+func (s *Storage) EnableUser(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.activeUser(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	u.DisabledAt = time.Time{}
+	s.users[userID] = u
+
+	return nil
+}
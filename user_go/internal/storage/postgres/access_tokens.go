@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+const accessTokenColumns = "id, user_id, name, scopes, token_hash, hint, expires_at, last_used_at, revoked_at, created_at"
+
+func scanAccessToken(row scanner) (models.AccessToken, error) {
+	var t models.AccessToken
+	var scopes pq.StringArray
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.Name, &scopes, &t.TokenHash, &t.Hint, &expiresAt, &lastUsedAt, &revokedAt, &t.CreatedAt,
+	)
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+
+	t.Scopes = scopes
+	t.ExpiresAt = expiresAt.Time
+	t.LastUsedAt = lastUsedAt.Time
+	t.RevokedAt = revokedAt.Time
+
+	return t, nil
+}
+
+func (s *Storage) SaveAccessToken(ctx context.Context, t models.AccessToken) error {
+	query := `INSERT INTO access_tokens(id, user_id, name, scopes, token_hash, hint, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := s.db.ExecContext(ctx, query,
+		t.ID, t.UserID, t.Name, pq.StringArray(t.Scopes), t.TokenHash, t.Hint, nullTime(t.ExpiresAt), t.CreatedAt,
+	)
+
+	return err
+}
+
+func (s *Storage) FindAccessToken(ctx context.Context, tokenHash string) (models.AccessToken, error) {
+	query := "SELECT " + accessTokenColumns + " FROM access_tokens WHERE token_hash = $1"
+
+	t, err := scanAccessToken(s.db.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AccessToken{}, storage.ErrNotFound
+		}
+		return models.AccessToken{}, err
+	}
+
+	return t, nil
+}
+
+// UserAccessTokens returns not revoked tokens of user, the newest first
+func (s *Storage) UserAccessTokens(ctx context.Context, userID string) ([]models.AccessToken, error) {
+	query := "SELECT " + accessTokenColumns + " FROM access_tokens WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.AccessToken
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+func (s *Storage) TouchAccessToken(ctx context.Context, id string, at time.Time) error {
+	query := "UPDATE access_tokens SET last_used_at = $1 WHERE id = $2"
+	_, err := s.db.ExecContext(ctx, query, at, id)
+
+	return err
+}
+
+// RevokeAccessToken revokes token of user, storage.ErrNotFound is returned
+// if user has no such token or it's already revoked
+func (s *Storage) RevokeAccessToken(ctx context.Context, userID, id string, at time.Time) error {
+	query := "UPDATE access_tokens SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, at, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+// RevokeUserAccessTokens revokes all not yet revoked tokens of user
+func (s *Storage) RevokeUserAccessTokens(ctx context.Context, userID string, at time.Time) error {
+	query := "UPDATE access_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, at, userID)
+
+	return err
+}
+
+// nullTime stores zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+
+	"github.com/lib/pq"
+)
+
+func (s *Storage) FindAppByID(ctx context.Context, id int32) (models.App, error) {
+	query := `SELECT id, name, secret, signing_key_id, token_ttl, allowed_origins, require_verified_email
+		FROM apps WHERE id = $1`
+
+	var a models.App
+	var ttl int64
+	var origins pq.StringArray
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&a.ID, &a.Name, &a.Secret, &a.SigningKeyID, &ttl, &origins, &a.RequireVerifiedEmail,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, storage.ErrNotFound
+		}
+		return models.App{}, err
+	}
+
+	a.TokenTTL = time.Duration(ttl) * time.Second
+	a.AllowedOrigins = origins
+
+	return a, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// SaveIdentity links identity to user, storage.ErrAlreadyExists is returned
+// if identity is linked to some user already
+func (s *Storage) SaveIdentity(ctx context.Context, id models.Identity) error {
+	return saveIdentity(ctx, s.db, id)
+}
+
+// SaveFederatedUser creates user without password with his identity in one transaction,
+// storage.ErrAlreadyExists is returned if email or identity is used by another user
+func (s *Storage) SaveFederatedUser(ctx context.Context, u models.User, id models.Identity) (models.User, error) {
+	if len(u.Email) < 3 {
+		return models.User{}, storage.ErrEmptyFields
+	}
+	u.ID = uuid.New().String()
+	id.UserID = u.ID
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	query := "INSERT INTO users(id, email, enc_password, email_verified, display_name) VALUES ($1, $2, '', $3, $4)"
+	_, err = tx.ExecContext(ctx, query, u.ID, u.Email, u.EmailVerified, u.DisplayName)
+	if err != nil {
+		return models.User{}, translate(err)
+	}
+
+	// every user gets default role
+	query = "INSERT INTO user_roles(user_id, role) VALUES ($1, $2)"
+	_, err = tx.ExecContext(ctx, query, u.ID, models.RoleUser)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err := saveIdentity(ctx, tx, id); err != nil {
+		return models.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.User{}, err
+	}
+
+	return u, nil
+}
+
+// FindIdentity returns identity of provider with subject
+func (s *Storage) FindIdentity(ctx context.Context, provider, subject string) (models.Identity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at FROM identities
+		WHERE provider = $1 AND subject = $2`
+
+	var id models.Identity
+	err := s.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Identity{}, storage.ErrNotFound
+		}
+		return models.Identity{}, err
+	}
+
+	return id, nil
+}
+
+// UserIdentities returns identities of user, the oldest first
+func (s *Storage) UserIdentities(ctx context.Context, userID string) ([]models.Identity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at FROM identities
+		WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.Identity
+	for rows.Next() {
+		var id models.Identity
+		if err := rows.Scan(&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+
+	return res, rows.Err()
+}
+
+// DeleteIdentity unlinks identity of user, storage.ErrNotFound is returned if user has no such identity
+func (s *Storage) DeleteIdentity(ctx context.Context, userID, id string) error {
+	query := "DELETE FROM identities WHERE id = $1 AND user_id = $2"
+	res, err := s.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+// execer is *sqlx.DB or *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func saveIdentity(ctx context.Context, db execer, id models.Identity) error {
+	id.ID = uuid.New().String()
+
+	query := `INSERT INTO identities(id, user_id, provider, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := db.ExecContext(ctx, query, id.ID, id.UserID, id.Provider, id.Subject, id.Email, id.CreatedAt)
+	if err != nil {
+		return translate(err)
+	}
+
+	return nil
+}
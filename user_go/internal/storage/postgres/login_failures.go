@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) GetLoginFailures(ctx context.Context, key string) (models.LoginFailures, error) {
+	query := "SELECT key, failures, last_failure_at, locked_until FROM login_failures WHERE key = $1"
+
+	var f models.LoginFailures
+	var lockedUntil sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, key).Scan(&f.Key, &f.Failures, &f.LastFailureAt, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.LoginFailures{}, storage.ErrNotFound
+		}
+		return models.LoginFailures{}, err
+	}
+
+	f.LockedUntil = lockedUntil.Time
+
+	return f, nil
+}
+
+// AddLoginFailure increments counter in one statement, so concurrent failures are all counted
+func (s *Storage) AddLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (models.LoginFailures, error) {
+	query := `INSERT INTO login_failures(key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_failures.last_failure_at < $3 THEN 1 ELSE login_failures.failures + 1 END,
+			last_failure_at = $2
+		RETURNING key, failures, last_failure_at, locked_until`
+
+	var f models.LoginFailures
+	var lockedUntil sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, key, at, resetBefore).Scan(&f.Key, &f.Failures, &f.LastFailureAt, &lockedUntil)
+	if err != nil {
+		return models.LoginFailures{}, err
+	}
+
+	f.LockedUntil = lockedUntil.Time
+
+	return f, nil
+}
+
+func (s *Storage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	query := "UPDATE login_failures SET locked_until = $1 WHERE key = $2"
+	_, err := s.db.ExecContext(ctx, query, until, key)
+
+	return err
+}
+
+func (s *Storage) DeleteLoginFailures(ctx context.Context, key string) error {
+	query := "DELETE FROM login_failures WHERE key = $1"
+	_, err := s.db.ExecContext(ctx, query, key)
+
+	return err
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) GetMFA(ctx context.Context, userID string) (models.MFA, error) {
+	query := "SELECT user_id, secret, enabled, last_used_step, created_at FROM user_mfa WHERE user_id = $1"
+
+	var m models.MFA
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&m.UserID, &m.Secret, &m.Enabled, &m.LastUsedStep, &m.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MFA{}, storage.ErrNotFound
+		}
+		return models.MFA{}, err
+	}
+
+	return m, nil
+}
+
+// SaveMFA saves pending authenticator, enabled one isn't replaced and storage.ErrAlreadyExists is returned
+func (s *Storage) SaveMFA(ctx context.Context, m models.MFA) error {
+	query := `INSERT INTO user_mfa(user_id, secret, enabled, last_used_step, created_at) VALUES ($1, $2, FALSE, 0, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = $2, last_used_step = 0, created_at = $3
+		WHERE user_mfa.enabled = FALSE`
+	res, err := s.db.ExecContext(ctx, query, m.UserID, m.Secret, m.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (s *Storage) EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	query := "UPDATE user_mfa SET enabled = TRUE, last_used_step = $1 WHERE user_id = $2 AND enabled = FALSE"
+	res, err := tx.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	query = "INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES ($1, $2)"
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, query, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	query := "UPDATE user_mfa SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1"
+	res, err := s.db.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) error {
+	query := "UPDATE mfa_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, at, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteMFA(ctx context.Context, userID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_mfa WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -6,15 +6,34 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"user_service/internal/domain/models"
 	"user_service/internal/storage"
 )
 
+// uniqueViolation is postgres error code of unique constraint violation
+const uniqueViolation = "23505"
+
+// translate converts postgres error to storage error: unique constraint violation becomes
+// storage.ErrAlreadyExists and no rows becomes storage.ErrNotFound, other errors are kept
+func translate(err error) error {
+	var pqErr *pq.Error
+	switch {
+	case errors.As(err, &pqErr) && pqErr.Code == uniqueViolation:
+		return storage.ErrAlreadyExists
+	case errors.Is(err, sql.ErrNoRows):
+		return storage.ErrNotFound
+	default:
+		return err
+	}
+}
+
 type Storage struct {
 	db *sqlx.DB
 }
 
+var _ storage.Storage = (*Storage)(nil)
+
 func New(db *sqlx.DB) *Storage {
 	return &Storage{
 		db: db,
@@ -37,12 +56,10 @@ func MustOpenPostgresDB(dbURI string) *sqlx.DB {
 }
 
 func (s *Storage) SaveUser(ctx context.Context, u models.User) (models.User, error) {
-	if len(u.Password) < 3 || len(u.Email) < 3 {
+	// password is hashed by caller
+	if len(u.EncPassword) == 0 || len(u.Email) < 3 {
 		return models.User{}, storage.ErrEmptyFields
 	}
-	if err := u.EncryptPassword(); err != nil {
-		return models.User{}, err
-	}
 	id := uuid.New().String()
 	u.ID = id
 
@@ -54,6 +71,13 @@ func (s *Storage) SaveUser(ctx context.Context, u models.User) (models.User, err
 
 	query := "INSERT INTO users(id, email, enc_password) VALUES ($1, $2, $3)"
 	_, err = tx.ExecContext(ctx, query, u.ID, u.Email, u.EncPassword)
+	if err != nil {
+		return models.User{}, translate(err)
+	}
+
+	// every user gets default role
+	query = "INSERT INTO user_roles(user_id, role) VALUES ($1, $2)"
+	_, err = tx.ExecContext(ctx, query, u.ID, models.RoleUser)
 	if err != nil {
 		return models.User{}, err
 	}
@@ -67,9 +91,43 @@ func (s *Storage) SaveUser(ctx context.Context, u models.User) (models.User, err
 }
 
 func (s *Storage) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
-	query := "SELECT id, enc_password FROM users WHERE email = $1"
-	var id, encPassword string
-	err := s.db.QueryRowContext(ctx, query, email).Scan(&id, &encPassword)
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1 AND deleted_at IS NULL"
+
+	return s.findUser(ctx, query, email)
+}
+
+func (s *Storage) FindUserByID(ctx context.Context, id string) (models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1 AND deleted_at IS NULL"
+
+	return s.findUser(ctx, query, id)
+}
+
+// userColumns are columns scanned by scanUser
+const userColumns = "id, email, enc_password, email_verified, display_name, created_at, disabled_at, deleted_at"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (models.User, error) {
+	var u models.User
+	var disabledAt, deletedAt sql.NullTime
+	err := row.Scan(
+		&u.ID, &u.Email, &u.EncPassword, &u.EmailVerified, &u.DisplayName, &u.CreatedAt, &disabledAt, &deletedAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	u.DisabledAt = disabledAt.Time
+	u.DeletedAt = deletedAt.Time
+
+	return u, nil
+}
+
+// findUser returns the only user selected by query
+func (s *Storage) findUser(ctx context.Context, query string, args ...any) (models.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, storage.ErrNotFound
@@ -77,9 +135,24 @@ func (s *Storage) FindUserByEmail(ctx context.Context, email string) (models.Use
 		return models.User{}, err
 	}
 
-	return models.User{
-		ID:          id,
-		Email:       email,
-		EncPassword: []byte(encPassword),
-	}, nil
+	return u, nil
+}
+
+// UpdatePassword replaces password hash of user
+func (s *Storage) UpdatePassword(ctx context.Context, userID string, encPassword []byte) error {
+	query := "UPDATE users SET enc_password = $1 WHERE id = $2 AND deleted_at IS NULL"
+	res, err := s.db.ExecContext(ctx, query, encPassword, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
 }
@@ -7,16 +7,18 @@ import (
 	"testing"
 	"time"
 	"user_service/internal/domain/models"
+	"user_service/internal/storage/storagetest"
 )
 
 const timeout = 15
 
+// connectToDB skips test if TEST_POSTGRES_DB_URI isn't set, database must be migrated
 func connectToDB(t *testing.T) *sqlx.DB {
 
 	uri := os.Getenv("TEST_POSTGRES_DB_URI")
 
 	if len(uri) < 3 {
-		t.FailNow()
+		t.Skip("TEST_POSTGRES_DB_URI isn't set")
 	}
 
 	t.Log(uri)
@@ -29,6 +31,10 @@ func connectToDB(t *testing.T) *sqlx.DB {
 	return db
 }
 
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, New(connectToDB(t)))
+}
+
 func TestStorage_SaveUser(t *testing.T) {
 	db := connectToDB(t)
 
@@ -57,8 +63,9 @@ func TestStorage_SaveUser(t *testing.T) {
 			args: args{
 				ctx: ctx,
 				u: models.User{
-					Email:    "user1@example.org",
-					Password: "password1",
+					Email:       "user1@example.org",
+					Password:    "password1",
+					EncPassword: []byte("password1-hash"),
 				},
 			},
 			want: models.User{
@@ -87,8 +94,9 @@ func TestStorage_SaveUser(t *testing.T) {
 			args: args{
 				ctx: ctx,
 				u: models.User{
-					Email:    "user1@example.org",
-					Password: "21392390309-21390-321-0",
+					Email:       "user1@example.org",
+					Password:    "21392390309-21390-321-0",
+					EncPassword: []byte("21392390309-21390-321-0-hash"),
 				},
 			},
 			want:    models.User{},
@@ -125,8 +133,9 @@ func TestStorage_FindUserByEmail(t *testing.T) {
 
 	// create user to check if we can find it later
 	_, _ = st.SaveUser(ctx, models.User{
-		Email:    "user1@example.org",
-		Password: "password1",
+		Email:       "user1@example.org",
+		Password:    "password1",
+		EncPassword: []byte("password1-hash"),
 	})
 
 	type fields struct {
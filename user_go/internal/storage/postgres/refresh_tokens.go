@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, t models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens(id, family_id, user_id, email, app_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := s.db.ExecContext(ctx, query, t.ID, t.FamilyID, t.UserID, t.Email, t.AppID, t.TokenHash, t.ExpiresAt, t.CreatedAt)
+
+	return err
+}
+
+func (s *Storage) FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	query := `SELECT id, family_id, user_id, email, app_id, token_hash, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = $1`
+
+	var t models.RefreshToken
+	var usedAt, revokedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&t.ID, &t.FamilyID, &t.UserID, &t.Email, &t.AppID, &t.TokenHash, &t.ExpiresAt, &usedAt, &revokedAt, &t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, storage.ErrNotFound
+		}
+		return models.RefreshToken{}, err
+	}
+
+	t.UsedAt = usedAt.Time
+	t.RevokedAt = revokedAt.Time
+
+	return t, nil
+}
+
+// RotateRefreshToken marks token with oldID as used and saves next in one transaction.
+// If old token was already used storage.ErrAlreadyExists is returned and next isn't saved,
+// so two concurrent refreshes with the same token can't both succeed.
+func (s *Storage) RotateRefreshToken(ctx context.Context, oldID string, next models.RefreshToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	query := "UPDATE refresh_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL AND revoked_at IS NULL"
+	res, err := tx.ExecContext(ctx, query, next.CreatedAt, oldID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	query = `INSERT INTO refresh_tokens(id, family_id, user_id, email, app_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.ExecContext(ctx, query,
+		next.ID, next.FamilyID, next.UserID, next.Email, next.AppID, next.TokenHash, next.ExpiresAt, next.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeRefreshTokenFamily revokes all not yet revoked tokens of the family
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, familyID)
+
+	return err
+}
+
+// RevokeUserRefreshTokens revokes all not yet revoked tokens of user
+func (s *Storage) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL"
+	_, err := s.db.ExecContext(ctx, query, userID)
+
+	return err
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+func (s *Storage) RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error {
+	// nobody needs revocations of expired tokens, so cleaning them up here
+	query := "DELETE FROM revoked_tokens WHERE expires_at < $1"
+	if _, err := s.db.ExecContext(ctx, query, time.Now()); err != nil {
+		return err
+	}
+
+	query = "INSERT INTO revoked_tokens(jti, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING"
+	_, err := s.db.ExecContext(ctx, query, jti, userID, expiresAt)
+
+	return err
+}
+
+// RevokeUserTokens revokes all user tokens issued before or in the second of before.
+// iat of tokens has seconds only, so cutoff is rounded up to the next second,
+// otherwise token issued earlier in the same second would stay valid.
+func (s *Storage) RevokeUserTokens(ctx context.Context, userID string, before time.Time) error {
+	query := `INSERT INTO user_token_revocations(user_id, revoked_before) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before`
+	_, err := s.db.ExecContext(ctx, query, userID, before.Truncate(time.Second).Add(time.Second))
+
+	return err
+}
+
+// IsTokenRevoked returns true if token was revoked by jti
+// or all user tokens were revoked after issuedAt
+func (s *Storage) IsTokenRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)
+		OR EXISTS(SELECT 1 FROM user_token_revocations WHERE user_id = $2 AND revoked_before > $3)`
+
+	var revoked bool
+	err := s.db.QueryRowContext(ctx, query, jti, userID, issuedAt).Scan(&revoked)
+
+	return revoked, err
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+	"user_service/internal/domain/models"
+)
+
+func (s *Storage) FindAppByID(ctx context.Context, id int32) (models.App, error) {
+	q := `SELECT id, name, secret, signing_key_id, token_ttl, allowed_origins, require_verified_email
+		FROM apps WHERE id = ?1`
+
+	var a models.App
+	var ttl int64
+	var origins stringList
+	err := queryRow(ctx, s.db, q, id).Scan(
+		&a.ID, &a.Name, &a.Secret, &a.SigningKeyID, &ttl, &origins, &a.RequireVerifiedEmail,
+	)
+	if err != nil {
+		return models.App{}, translate(err)
+	}
+
+	a.TokenTTL = time.Duration(ttl) * time.Second
+	a.AllowedOrigins = origins
+
+	return a, nil
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+)
+
+const auditColumns = "id, type, outcome, actor_id, user_id, email, app_id, ip, user_agent, details, created_at"
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	q := `INSERT INTO audit_events(type, outcome, actor_id, user_id, email, app_id, ip, user_agent, details, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)`
+	_, err := exec(ctx, s.db, q,
+		e.Type, e.Outcome, e.ActorID, e.UserID, e.Email, e.AppID, e.IP, e.UserAgent, e.Details, e.CreatedAt)
+
+	return err
+}
+
+// ListAuditEvents returns page of audit events selected by filter, the newest first
+func (s *Storage) ListAuditEvents(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, error) {
+	conds := []string{"TRUE"}
+	var args []any
+
+	// where adds condition with the next placeholder for arg
+	where := func(cond string, a any) {
+		args = append(args, a)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.Type != "" {
+		where("type = ?%d", f.Type)
+	}
+	if f.Outcome != "" {
+		where("outcome = ?%d", f.Outcome)
+	}
+	if f.ActorID != "" {
+		where("actor_id = ?%d", f.ActorID)
+	}
+	if f.UserID != "" {
+		where("user_id = ?%d", f.UserID)
+	}
+	if f.IP != "" {
+		where("ip = ?%d", f.IP)
+	}
+	if !f.After.IsZero() {
+		where("created_at >= ?%d", f.After)
+	}
+	if !f.Before.IsZero() {
+		where("created_at < ?%d", f.Before)
+	}
+	if f.BeforeID != 0 {
+		where("id < ?%d", f.BeforeID)
+	}
+
+	q := "SELECT " + auditColumns + " FROM audit_events WHERE " + strings.Join(conds, " AND ") +
+		fmt.Sprintf(" ORDER BY id DESC LIMIT %d", f.Limit)
+
+	rows, err := query(ctx, s.db, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.AuditEvent
+	for rows.Next() {
+		var e models.AuditEvent
+		err := rows.Scan(&e.ID, &e.Type, &e.Outcome, &e.ActorID, &e.UserID, &e.Email,
+			&e.AppID, &e.IP, &e.UserAgent, &e.Details, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+
+	return res, rows.Err()
+}
+
+// DeleteAuditEvents deletes events created before time and returns their number
+func (s *Storage) DeleteAuditEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := exec(ctx, s.db, "DELETE FROM audit_events WHERE created_at < ?1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// SaveEmailVerification saves token and removes unused tokens sent to user before
+func (s *Storage) SaveEmailVerification(ctx context.Context, v models.EmailVerification) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	q := "DELETE FROM email_verifications WHERE user_id = ?1 AND used_at IS NULL"
+	if _, err := exec(ctx, tx, q, v.UserID); err != nil {
+		return err
+	}
+
+	q = `INSERT INTO email_verifications(token_hash, user_id, email, expires_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)`
+	_, err = exec(ctx, tx, q, v.TokenHash, v.UserID, v.Email, v.ExpiresAt, v.CreatedAt)
+	if err != nil {
+		return translate(err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) FindEmailVerification(ctx context.Context, tokenHash string) (models.EmailVerification, error) {
+	q := `SELECT token_hash, user_id, email, expires_at, used_at, created_at
+		FROM email_verifications WHERE token_hash = ?1`
+
+	var v models.EmailVerification
+	var usedAt sql.NullTime
+	err := queryRow(ctx, s.db, q, tokenHash).Scan(
+		&v.TokenHash, &v.UserID, &v.Email, &v.ExpiresAt, &usedAt, &v.CreatedAt,
+	)
+	if err != nil {
+		return models.EmailVerification{}, translate(err)
+	}
+
+	v.UsedAt = usedAt.Time
+
+	return v, nil
+}
+
+// VerifyEmail marks token as used and email of its owner as verified in one transaction.
+// storage.ErrAlreadyExists is returned if token was already used and storage.ErrNotFound
+// if user was deleted or changed email after token was sent.
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, usedAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	var userID, email string
+	q := `UPDATE email_verifications SET used_at = ?1
+		WHERE token_hash = ?2 AND used_at IS NULL RETURNING user_id, email`
+	err = queryRow(ctx, tx, q, usedAt, tokenHash).Scan(&userID, &email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrAlreadyExists
+		}
+		return err
+	}
+
+	q = "UPDATE users SET email_verified = TRUE WHERE id = ?1 AND email = ?2 AND deleted_at IS NULL"
+	res, err := exec(ctx, tx, q, userID, email)
+	if err != nil {
+		return err
+	}
+	if err := oneRowAffected(res.RowsAffected()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
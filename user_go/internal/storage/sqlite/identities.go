@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// SaveIdentity links identity to user, storage.ErrAlreadyExists is returned
+// if identity is linked to some user already
+func (s *Storage) SaveIdentity(ctx context.Context, id models.Identity) error {
+	return saveIdentity(ctx, s.db, id)
+}
+
+// SaveFederatedUser creates user without password with his identity in one transaction,
+// storage.ErrAlreadyExists is returned if email or identity is used by another user
+func (s *Storage) SaveFederatedUser(ctx context.Context, u models.User, id models.Identity) (models.User, error) {
+	if len(u.Email) < 3 {
+		return models.User{}, storage.ErrEmptyFields
+	}
+	u.ID = uuid.New().String()
+	u.EncPassword = []byte{}
+	id.UserID = u.ID
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	if err := saveUser(ctx, tx, u); err != nil {
+		return models.User{}, err
+	}
+
+	if err := saveIdentity(ctx, tx, id); err != nil {
+		return models.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.User{}, err
+	}
+
+	return u, nil
+}
+
+// FindIdentity returns identity of provider with subject
+func (s *Storage) FindIdentity(ctx context.Context, provider, subject string) (models.Identity, error) {
+	q := `SELECT id, user_id, provider, subject, email, created_at FROM identities
+		WHERE provider = ?1 AND subject = ?2`
+
+	var id models.Identity
+	err := queryRow(ctx, s.db, q, provider, subject).Scan(
+		&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt,
+	)
+	if err != nil {
+		return models.Identity{}, translate(err)
+	}
+
+	return id, nil
+}
+
+// UserIdentities returns identities of user, the oldest first
+func (s *Storage) UserIdentities(ctx context.Context, userID string) ([]models.Identity, error) {
+	q := `SELECT id, user_id, provider, subject, email, created_at FROM identities
+		WHERE user_id = ?1 ORDER BY created_at`
+
+	rows, err := query(ctx, s.db, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.Identity
+	for rows.Next() {
+		var id models.Identity
+		if err := rows.Scan(&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+
+	return res, rows.Err()
+}
+
+// DeleteIdentity unlinks identity of user, storage.ErrNotFound is returned if user has no such identity
+func (s *Storage) DeleteIdentity(ctx context.Context, userID, id string) error {
+	q := "DELETE FROM identities WHERE id = ?1 AND user_id = ?2"
+	res, err := exec(ctx, s.db, q, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+func saveIdentity(ctx context.Context, c conn, id models.Identity) error {
+	id.ID = uuid.New().String()
+
+	q := `INSERT INTO identities(id, user_id, provider, subject, email, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)`
+	_, err := exec(ctx, c, q, id.ID, id.UserID, id.Provider, id.Subject, id.Email, id.CreatedAt)
+	if err != nil {
+		return translate(err)
+	}
+
+	return nil
+}
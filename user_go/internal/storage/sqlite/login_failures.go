@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user_service/internal/domain/models"
+)
+
+func (s *Storage) GetLoginFailures(ctx context.Context, key string) (models.LoginFailures, error) {
+	q := "SELECT key, failures, last_failure_at, locked_until FROM login_failures WHERE key = ?1"
+
+	var f models.LoginFailures
+	var lockedUntil sql.NullTime
+	err := queryRow(ctx, s.db, q, key).Scan(&f.Key, &f.Failures, &f.LastFailureAt, &lockedUntil)
+	if err != nil {
+		return models.LoginFailures{}, translate(err)
+	}
+
+	f.LockedUntil = lockedUntil.Time
+
+	return f, nil
+}
+
+// AddLoginFailure increments counter in one statement, so concurrent failures are all counted
+func (s *Storage) AddLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (models.LoginFailures, error) {
+	q := `INSERT INTO login_failures(key, failures, last_failure_at) VALUES (?1, 1, ?2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_failures.last_failure_at < ?3 THEN 1 ELSE login_failures.failures + 1 END,
+			last_failure_at = ?2
+		RETURNING key, failures, last_failure_at, locked_until`
+
+	var f models.LoginFailures
+	var lockedUntil sql.NullTime
+	err := queryRow(ctx, s.db, q, key, at, resetBefore).Scan(&f.Key, &f.Failures, &f.LastFailureAt, &lockedUntil)
+	if err != nil {
+		return models.LoginFailures{}, err
+	}
+
+	f.LockedUntil = lockedUntil.Time
+
+	return f, nil
+}
+
+func (s *Storage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	q := "UPDATE login_failures SET locked_until = ?1 WHERE key = ?2"
+	_, err := exec(ctx, s.db, q, until, key)
+
+	return err
+}
+
+func (s *Storage) DeleteLoginFailures(ctx context.Context, key string) error {
+	q := "DELETE FROM login_failures WHERE key = ?1"
+	_, err := exec(ctx, s.db, q, key)
+
+	return err
+}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) GetMFA(ctx context.Context, userID string) (models.MFA, error) {
+	q := "SELECT user_id, secret, enabled, last_used_step, created_at FROM user_mfa WHERE user_id = ?1"
+
+	var m models.MFA
+	err := queryRow(ctx, s.db, q, userID).Scan(&m.UserID, &m.Secret, &m.Enabled, &m.LastUsedStep, &m.CreatedAt)
+	if err != nil {
+		return models.MFA{}, translate(err)
+	}
+
+	return m, nil
+}
+
+// SaveMFA saves pending authenticator, enabled one isn't replaced and storage.ErrAlreadyExists is returned
+func (s *Storage) SaveMFA(ctx context.Context, m models.MFA) error {
+	q := `INSERT INTO user_mfa(user_id, secret, enabled, last_used_step, created_at) VALUES (?1, ?2, FALSE, 0, ?3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = ?2, last_used_step = 0, created_at = ?3
+		WHERE user_mfa.enabled = FALSE`
+	res, err := exec(ctx, s.db, q, m.UserID, m.Secret, m.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (s *Storage) EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	q := "UPDATE user_mfa SET enabled = TRUE, last_used_step = ?1 WHERE user_id = ?2 AND enabled = FALSE"
+	res, err := exec(ctx, tx, q, step, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	if _, err := exec(ctx, tx, "DELETE FROM mfa_recovery_codes WHERE user_id = ?1", userID); err != nil {
+		return err
+	}
+
+	q = "INSERT INTO mfa_recovery_codes(user_id, code_hash) VALUES (?1, ?2)"
+	for _, hash := range recoveryCodeHashes {
+		if _, err := exec(ctx, tx, q, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	q := "UPDATE user_mfa SET last_used_step = ?1 WHERE user_id = ?2 AND last_used_step < ?1"
+	res, err := exec(ctx, s.db, q, step, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) error {
+	q := "UPDATE mfa_recovery_codes SET used_at = ?1 WHERE user_id = ?2 AND code_hash = ?3 AND used_at IS NULL"
+	res, err := exec(ctx, s.db, q, at, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+func (s *Storage) DeleteMFA(ctx context.Context, userID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	if _, err := exec(ctx, tx, "DELETE FROM mfa_recovery_codes WHERE user_id = ?1", userID); err != nil {
+		return err
+	}
+
+	if _, err := exec(ctx, tx, "DELETE FROM user_mfa WHERE user_id = ?1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) SaveOAuthClient(ctx context.Context, c models.OAuthClient) error {
+	q := `INSERT INTO oauth_clients(id, name, owner_id, secret_hash, redirect_uris, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)`
+	_, err := exec(ctx, s.db, q,
+		c.ID, c.Name, c.OwnerID, c.SecretHash, stringList(c.RedirectURIs), c.CreatedAt,
+	)
+
+	return err
+}
+
+func (s *Storage) FindOAuthClient(ctx context.Context, id string) (models.OAuthClient, error) {
+	q := "SELECT id, name, owner_id, secret_hash, redirect_uris, created_at FROM oauth_clients WHERE id = ?1"
+
+	var c models.OAuthClient
+	var uris stringList
+	err := queryRow(ctx, s.db, q, id).Scan(&c.ID, &c.Name, &c.OwnerID, &c.SecretHash, &uris, &c.CreatedAt)
+	if err != nil {
+		return models.OAuthClient{}, translate(err)
+	}
+
+	c.RedirectURIs = uris
+
+	return c, nil
+}
+
+func (s *Storage) SaveOAuthCode(ctx context.Context, c models.OAuthCode) error {
+	q := `INSERT INTO oauth_codes(code_hash, grant_id, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)`
+	_, err := exec(ctx, s.db, q,
+		c.CodeHash, c.GrantID, c.ClientID, c.UserID, c.RedirectURI, stringList(c.Scopes), c.CodeChallenge, c.ExpiresAt, c.CreatedAt,
+	)
+
+	return err
+}
+
+func (s *Storage) FindOAuthCode(ctx context.Context, codeHash string) (models.OAuthCode, error) {
+	q := `SELECT code_hash, grant_id, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, used_at, created_at
+		FROM oauth_codes WHERE code_hash = ?1`
+
+	var c models.OAuthCode
+	var scopes stringList
+	var usedAt sql.NullTime
+	err := queryRow(ctx, s.db, q, codeHash).Scan(
+		&c.CodeHash, &c.GrantID, &c.ClientID, &c.UserID, &c.RedirectURI, &scopes, &c.CodeChallenge, &c.ExpiresAt, &usedAt, &c.CreatedAt,
+	)
+	if err != nil {
+		return models.OAuthCode{}, translate(err)
+	}
+
+	c.Scopes = scopes
+	c.UsedAt = usedAt.Time
+
+	return c, nil
+}
+
+// UseOAuthCode marks code as used, storage.ErrAlreadyExists is returned if it was used before,
+// so two concurrent exchanges of the same code can't both succeed
+func (s *Storage) UseOAuthCode(ctx context.Context, codeHash string, at time.Time) error {
+	q := "UPDATE oauth_codes SET used_at = ?1 WHERE code_hash = ?2 AND used_at IS NULL"
+	res, err := exec(ctx, s.db, q, at, codeHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// SaveOAuthTokens saves access and refresh token of grant in one transaction
+func (s *Storage) SaveOAuthTokens(ctx context.Context, tokens ...models.OAuthToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	if err := saveOAuthTokens(ctx, tx, tokens); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) FindOAuthToken(ctx context.Context, tokenHash string) (models.OAuthToken, error) {
+	q := `SELECT id, grant_id, kind, token_hash, client_id, user_id, scopes, expires_at, used_at, revoked_at, created_at
+		FROM oauth_tokens WHERE token_hash = ?1`
+
+	var t models.OAuthToken
+	var scopes stringList
+	var usedAt, revokedAt sql.NullTime
+	err := queryRow(ctx, s.db, q, tokenHash).Scan(
+		&t.ID, &t.GrantID, &t.Kind, &t.TokenHash, &t.ClientID, &t.UserID, &scopes, &t.ExpiresAt, &usedAt, &revokedAt, &t.CreatedAt,
+	)
+	if err != nil {
+		return models.OAuthToken{}, translate(err)
+	}
+
+	t.Scopes = scopes
+	t.UsedAt = usedAt.Time
+	t.RevokedAt = revokedAt.Time
+
+	return t, nil
+}
+
+// RotateOAuthRefreshToken marks refresh token with oldID as used and saves next tokens
+// in one transaction. If old token was already used storage.ErrAlreadyExists is returned.
+func (s *Storage) RotateOAuthRefreshToken(ctx context.Context, oldID string, at time.Time, next ...models.OAuthToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	q := "UPDATE oauth_tokens SET used_at = ?1 WHERE id = ?2 AND used_at IS NULL AND revoked_at IS NULL"
+	res, err := exec(ctx, tx, q, at, oldID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	if err := saveOAuthTokens(ctx, tx, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeOAuthGrant revokes all not yet revoked tokens of grant
+func (s *Storage) RevokeOAuthGrant(ctx context.Context, grantID string, at time.Time) error {
+	q := "UPDATE oauth_tokens SET revoked_at = ?1 WHERE grant_id = ?2 AND revoked_at IS NULL"
+	_, err := exec(ctx, s.db, q, at, grantID)
+
+	return err
+}
+
+// RevokeUserOAuthTokens revokes all not yet revoked tokens which user gave to clients
+func (s *Storage) RevokeUserOAuthTokens(ctx context.Context, userID string, at time.Time) error {
+	q := "UPDATE oauth_tokens SET revoked_at = ?1 WHERE user_id = ?2 AND revoked_at IS NULL"
+	_, err := exec(ctx, s.db, q, at, userID)
+
+	return err
+}
+
+func saveOAuthTokens(ctx context.Context, tx *sql.Tx, tokens []models.OAuthToken) error {
+	q := `INSERT INTO oauth_tokens(id, grant_id, kind, token_hash, client_id, user_id, scopes, expires_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)`
+
+	for _, t := range tokens {
+		_, err := exec(ctx, tx, q,
+			t.ID, t.GrantID, t.Kind, t.TokenHash, t.ClientID, t.UserID, stringList(t.Scopes), t.ExpiresAt, t.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// SavePasswordReset saves token and removes unused tokens of user requested before
+func (s *Storage) SavePasswordReset(ctx context.Context, r models.PasswordReset) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	q := "DELETE FROM password_resets WHERE user_id = ?1 AND used_at IS NULL"
+	if _, err := exec(ctx, tx, q, r.UserID); err != nil {
+		return err
+	}
+
+	q = "INSERT INTO password_resets(token_hash, user_id, expires_at, created_at) VALUES (?1, ?2, ?3, ?4)"
+	if _, err := exec(ctx, tx, q, r.TokenHash, r.UserID, r.ExpiresAt, r.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) FindPasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error) {
+	q := "SELECT token_hash, user_id, expires_at, used_at, created_at FROM password_resets WHERE token_hash = ?1"
+
+	var r models.PasswordReset
+	var usedAt sql.NullTime
+	err := queryRow(ctx, s.db, q, tokenHash).Scan(&r.TokenHash, &r.UserID, &r.ExpiresAt, &usedAt, &r.CreatedAt)
+	if err != nil {
+		return models.PasswordReset{}, translate(err)
+	}
+
+	r.UsedAt = usedAt.Time
+
+	return r, nil
+}
+
+// ResetPassword marks token as used and sets password of its owner in one transaction.
+// If token was already used storage.ErrAlreadyExists is returned and password isn't changed.
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash string, encPassword []byte, usedAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	var userID string
+	q := "UPDATE password_resets SET used_at = ?1 WHERE token_hash = ?2 AND used_at IS NULL RETURNING user_id"
+	if err := queryRow(ctx, tx, q, usedAt, tokenHash).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrAlreadyExists
+		}
+		return err
+	}
+
+	q = "UPDATE users SET enc_password = ?1 WHERE id = ?2"
+	if _, err := exec(ctx, tx, q, encPassword, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
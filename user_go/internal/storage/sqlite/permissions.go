@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	q := "SELECT role FROM user_roles WHERE user_id = ?1 ORDER BY role"
+
+	var roles []string
+	if err := s.db.SelectContext(ctx, &roles, q, userID); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (s *Storage) GetUserPermissions(ctx context.Context, userID string) ([]models.Permission, error) {
+	q := `SELECT DISTINCT rp.permission FROM user_roles ur
+		JOIN role_permissions rp ON rp.role = ur.role
+		WHERE ur.user_id = ?1 ORDER BY rp.permission`
+
+	var names []string
+	if err := s.db.SelectContext(ctx, &names, q, userID); err != nil {
+		return nil, err
+	}
+
+	permissions := make([]models.Permission, 0, len(names))
+	for _, name := range names {
+		permissions = append(permissions, models.Permission{Name: name})
+	}
+
+	return permissions, nil
+}
+
+func (s *Storage) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	q := `SELECT EXISTS(SELECT 1 FROM user_roles ur
+		JOIN role_permissions rp ON rp.role = ur.role
+		WHERE ur.user_id = ?1 AND rp.permission = ?2)`
+
+	var ok bool
+	err := queryRow(ctx, s.db, q, userID, permission).Scan(&ok)
+
+	return ok, err
+}
+
+func (s *Storage) ListRoles(ctx context.Context) ([]models.Role, error) {
+	q := `SELECT r.name, r.description, rp.permission FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role = r.name
+		ORDER BY r.name, rp.permission`
+
+	rows, err := query(ctx, s.db, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var name, description string
+		var permission sql.NullString
+		if err := rows.Scan(&name, &description, &permission); err != nil {
+			return nil, err
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, models.Role{Name: name, Description: description})
+		}
+		if permission.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission.String)
+		}
+	}
+
+	return roles, rows.Err()
+}
+
+// AssignRole gives role to user, assigning role user already has isn't an error.
+// Returns storage.ErrNotFound if user or role doesn't exist.
+func (s *Storage) AssignRole(ctx context.Context, userID, role string) error {
+	q := `INSERT INTO user_roles(user_id, role)
+		SELECT u.id, r.name FROM users u, roles r WHERE u.id = ?1 AND r.name = ?2
+		ON CONFLICT DO NOTHING RETURNING user_id`
+
+	var id string
+	err := queryRow(ctx, s.db, q, userID, role).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		// nothing inserted, either user already has role or user or role doesn't exist
+		var exists bool
+		q = `SELECT EXISTS(SELECT 1 FROM user_roles WHERE user_id = ?1 AND role = ?2)`
+		if err := queryRow(ctx, s.db, q, userID, role).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return storage.ErrNotFound
+		}
+		return nil
+	}
+
+	return err
+}
+
+// RevokeRole takes role from user, returns storage.ErrNotFound if user doesn't have it
+func (s *Storage) RevokeRole(ctx context.Context, userID, role string) error {
+	q := "DELETE FROM user_roles WHERE user_id = ?1 AND role = ?2"
+
+	res, err := exec(ctx, s.db, q, userID, role)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+// SetUserRoles replaces roles of user in one transaction.
+// Returns storage.ErrNotFound if user or one of roles doesn't exist.
+func (s *Storage) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	var exists bool
+	q := "SELECT EXISTS(SELECT 1 FROM users WHERE id = ?1 AND deleted_at IS NULL)"
+	if err := queryRow(ctx, tx, q, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrNotFound
+	}
+
+	// sqlite has no arrays, so roles are checked and inserted one by one
+	if _, err := exec(ctx, tx, "DELETE FROM user_roles WHERE user_id = ?1", userID); err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		q = "INSERT INTO user_roles(user_id, role) SELECT ?1, name FROM roles WHERE name = ?2 ON CONFLICT DO NOTHING"
+		if _, err := exec(ctx, tx, q, userID, role); err != nil {
+			return err
+		}
+
+		q = "SELECT EXISTS(SELECT 1 FROM roles WHERE name = ?1)"
+		if err := queryRow(ctx, tx, q, role).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return storage.ErrNotFound
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+func (s *Storage) SaveRefreshToken(ctx context.Context, t models.RefreshToken) error {
+	q := `INSERT INTO refresh_tokens(id, family_id, user_id, email, app_id, token_hash, expires_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`
+	_, err := exec(ctx, s.db, q, t.ID, t.FamilyID, t.UserID, t.Email, t.AppID, t.TokenHash, t.ExpiresAt, t.CreatedAt)
+
+	return err
+}
+
+func (s *Storage) FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	q := `SELECT id, family_id, user_id, email, app_id, token_hash, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = ?1`
+
+	var t models.RefreshToken
+	var usedAt, revokedAt sql.NullTime
+	err := queryRow(ctx, s.db, q, tokenHash).Scan(
+		&t.ID, &t.FamilyID, &t.UserID, &t.Email, &t.AppID, &t.TokenHash, &t.ExpiresAt, &usedAt, &revokedAt, &t.CreatedAt,
+	)
+	if err != nil {
+		return models.RefreshToken{}, translate(err)
+	}
+
+	t.UsedAt = usedAt.Time
+	t.RevokedAt = revokedAt.Time
+
+	return t, nil
+}
+
+// RotateRefreshToken marks token with oldID as used and saves next in one transaction.
+// If old token was already used storage.ErrAlreadyExists is returned and next isn't saved,
+// so two concurrent refreshes with the same token can't both succeed.
+func (s *Storage) RotateRefreshToken(ctx context.Context, oldID string, next models.RefreshToken) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	q := "UPDATE refresh_tokens SET used_at = ?1 WHERE id = ?2 AND used_at IS NULL AND revoked_at IS NULL"
+	res, err := exec(ctx, tx, q, next.CreatedAt, oldID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrAlreadyExists
+	}
+
+	q = `INSERT INTO refresh_tokens(id, family_id, user_id, email, app_id, token_hash, expires_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`
+	_, err = exec(ctx, tx, q,
+		next.ID, next.FamilyID, next.UserID, next.Email, next.AppID, next.TokenHash, next.ExpiresAt, next.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeRefreshTokenFamily revokes all not yet revoked tokens of the family
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	q := "UPDATE refresh_tokens SET revoked_at = ?1 WHERE family_id = ?2 AND revoked_at IS NULL"
+	_, err := exec(ctx, s.db, q, time.Now(), familyID)
+
+	return err
+}
+
+// RevokeUserRefreshTokens revokes all not yet revoked tokens of user
+func (s *Storage) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	q := "UPDATE refresh_tokens SET revoked_at = ?1 WHERE user_id = ?2 AND revoked_at IS NULL"
+	_, err := exec(ctx, s.db, q, time.Now(), userID)
+
+	return err
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user_service/internal/domain/models"
+)
+
+func (s *Storage) SaveSession(ctx context.Context, sess models.Session) error {
+	q := `INSERT INTO sessions(id, user_id, app_id, user_agent, ip, created_at, last_used_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)`
+	_, err := exec(ctx, s.db, q,
+		sess.ID, sess.UserID, sess.AppID, sess.UserAgent, sess.IP, sess.CreatedAt, sess.LastUsedAt,
+	)
+
+	return err
+}
+
+// TouchSession records use of session from ip, unknown session isn't an error
+// because tokens issued before sessions were added have no session
+func (s *Storage) TouchSession(ctx context.Context, id, ip string, at time.Time) error {
+	q := "UPDATE sessions SET last_used_at = ?1, ip = ?2 WHERE id = ?3"
+	_, err := exec(ctx, s.db, q, at, ip, id)
+
+	return err
+}
+
+// UserSessions returns not revoked sessions of user used after activeAfter, the last used first
+func (s *Storage) UserSessions(ctx context.Context, userID string, activeAfter time.Time) ([]models.Session, error) {
+	q := `SELECT id, user_id, app_id, user_agent, ip, created_at, last_used_at, revoked_at FROM sessions
+		WHERE user_id = ?1 AND revoked_at IS NULL AND last_used_at > ?2 ORDER BY last_used_at DESC`
+
+	rows, err := query(ctx, s.db, q, userID, activeAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.Session
+	for rows.Next() {
+		var sess models.Session
+		var revokedAt sql.NullTime
+		err := rows.Scan(
+			&sess.ID, &sess.UserID, &sess.AppID, &sess.UserAgent, &sess.IP, &sess.CreatedAt, &sess.LastUsedAt, &revokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sess.RevokedAt = revokedAt.Time
+		res = append(res, sess)
+	}
+
+	return res, rows.Err()
+}
+
+// RevokeSession revokes session of user, storage.ErrNotFound is returned
+// if user has no such session or it's already revoked
+func (s *Storage) RevokeSession(ctx context.Context, userID, id string, at time.Time) error {
+	q := "UPDATE sessions SET revoked_at = ?1 WHERE id = ?2 AND user_id = ?3 AND revoked_at IS NULL"
+	res, err := exec(ctx, s.db, q, at, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+// RevokeUserSessions revokes all not yet revoked sessions of user
+func (s *Storage) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	q := "UPDATE sessions SET revoked_at = ?1 WHERE user_id = ?2 AND revoked_at IS NULL"
+	_, err := exec(ctx, s.db, q, at, userID)
+
+	return err
+}
+
+// IsSessionRevoked returns true if session was revoked, unknown session isn't revoked
+func (s *Storage) IsSessionRevoked(ctx context.Context, id string) (bool, error) {
+	q := "SELECT EXISTS(SELECT 1 FROM sessions WHERE id = ?1 AND revoked_at IS NOT NULL)"
+
+	var revoked bool
+	err := queryRow(ctx, s.db, q, id).Scan(&revoked)
+
+	return revoked, err
+}
@@ -0,0 +1,239 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// translate converts sqlite error to storage error: unique constraint violation becomes
+// storage.ErrAlreadyExists and no rows becomes storage.ErrNotFound, other errors are kept
+func translate(err error) error {
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY):
+		return storage.ErrAlreadyExists
+	case errors.Is(err, sql.ErrNoRows):
+		return storage.ErrNotFound
+	default:
+		return err
+	}
+}
+
+// Storage is the same as postgres.Storage, but keeps data in one file,
+// so service can run without database server
+type Storage struct {
+	db *sqlx.DB
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+func New(db *sqlx.DB) *Storage {
+	return &Storage{
+		db: db,
+	}
+}
+
+// MustOpenSQLiteDB is helper func to get db, will throw panic when error.
+// dsn is path of database file with optional sqlite:// prefix, file: URI or :memory:.
+func MustOpenSQLiteDB(dsn string) *sqlx.DB {
+	dsn = strings.TrimPrefix(dsn, "sqlite://")
+	dsn = strings.TrimPrefix(dsn, "sqlite:")
+
+	// pragmas are set on every connection, times are written in format sqlite functions understand
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	dsn += sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+
+	db, err := sqlx.Open("sqlite", dsn)
+	if err != nil {
+		panic("cant open db " + err.Error())
+	}
+
+	// sqlite allows one writer anyway, and :memory: database exists only in its connection
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		panic("cant ping db " + err.Error())
+	}
+
+	return db
+}
+
+// conn is *sqlx.DB or *sql.Tx
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// exec, query and queryRow convert times to UTC, times are stored as text
+// and texts of different time zones don't compare as times
+func exec(ctx context.Context, c conn, query string, args ...any) (sql.Result, error) {
+	return c.ExecContext(ctx, query, utc(args)...)
+}
+
+func query(ctx context.Context, c conn, query string, args ...any) (*sql.Rows, error) {
+	return c.QueryContext(ctx, query, utc(args)...)
+}
+
+func queryRow(ctx context.Context, c conn, query string, args ...any) *sql.Row {
+	return c.QueryRowContext(ctx, query, utc(args)...)
+}
+
+func utc(args []any) []any {
+	res := make([]any, len(args))
+	for i, a := range args {
+		switch v := a.(type) {
+		case time.Time:
+			res[i] = v.UTC()
+		case sql.NullTime:
+			res[i] = sql.NullTime{Time: v.Time.UTC(), Valid: v.Valid}
+		default:
+			res[i] = a
+		}
+	}
+
+	return res
+}
+
+// stringList is stored as json array, sqlite has no arrays
+type stringList []string
+
+func (l stringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+func (l *stringList) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	default:
+		return errors.New("stringList: unsupported type")
+	}
+}
+
+func (s *Storage) SaveUser(ctx context.Context, u models.User) (models.User, error) {
+	// password is hashed by caller
+	if len(u.EncPassword) == 0 || len(u.Email) < 3 {
+		return models.User{}, storage.ErrEmptyFields
+	}
+	u.ID = uuid.New().String()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	if err := saveUser(ctx, tx, u); err != nil {
+		return models.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.User{}, err
+	}
+
+	return u, nil
+}
+
+// saveUser inserts user with default role
+func saveUser(ctx context.Context, tx *sql.Tx, u models.User) error {
+	q := `INSERT INTO users(id, email, enc_password, email_verified, display_name, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)`
+	_, err := exec(ctx, tx, q, u.ID, u.Email, u.EncPassword, u.EmailVerified, u.DisplayName, time.Now())
+	if err != nil {
+		return translate(err)
+	}
+
+	// every user gets default role
+	q = "INSERT INTO user_roles(user_id, role) VALUES (?1, ?2)"
+	_, err = exec(ctx, tx, q, u.ID, models.RoleUser)
+
+	return err
+}
+
+func (s *Storage) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
+	q := "SELECT " + userColumns + " FROM users WHERE email = ?1 AND deleted_at IS NULL"
+
+	return s.findUser(ctx, q, email)
+}
+
+func (s *Storage) FindUserByID(ctx context.Context, id string) (models.User, error) {
+	q := "SELECT " + userColumns + " FROM users WHERE id = ?1 AND deleted_at IS NULL"
+
+	return s.findUser(ctx, q, id)
+}
+
+// userColumns are columns scanned by scanUser
+const userColumns = "id, email, enc_password, email_verified, display_name, created_at, disabled_at, deleted_at"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row scanner) (models.User, error) {
+	var u models.User
+	var disabledAt, deletedAt sql.NullTime
+	err := row.Scan(
+		&u.ID, &u.Email, &u.EncPassword, &u.EmailVerified, &u.DisplayName, &u.CreatedAt, &disabledAt, &deletedAt,
+	)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	u.DisabledAt = disabledAt.Time
+	u.DeletedAt = deletedAt.Time
+
+	return u, nil
+}
+
+// findUser returns the only user selected by query
+func (s *Storage) findUser(ctx context.Context, q string, args ...any) (models.User, error) {
+	u, err := scanUser(queryRow(ctx, s.db, q, args...))
+	if err != nil {
+		return models.User{}, translate(err)
+	}
+
+	return u, nil
+}
+
+// UpdatePassword replaces password hash of user
+func (s *Storage) UpdatePassword(ctx context.Context, userID string, encPassword []byte) error {
+	q := "UPDATE users SET enc_password = ?1 WHERE id = ?2 AND deleted_at IS NULL"
+	res, err := exec(ctx, s.db, q, encPassword, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
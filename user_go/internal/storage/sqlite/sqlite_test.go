@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"lib/migrator"
+	"testing"
+	"user_service/internal/storage/storagetest"
+	"user_service/migrations"
+
+	"github.com/pressly/goose/v3"
+)
+
+// openDB opens in-memory database with migrations applied
+func openDB(t *testing.T) *sqlx.DB {
+	db := MustOpenSQLiteDB(":memory:")
+	t.Cleanup(func() { _ = db.Close() })
+
+	m, err := migrator.New(db.DB, goose.DialectSQLite3, migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, New(openDB(t)))
+}
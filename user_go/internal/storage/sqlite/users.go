@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// UpdateProfile sets display name of user
+func (s *Storage) UpdateProfile(ctx context.Context, userID, displayName string) error {
+	q := "UPDATE users SET display_name = ?1 WHERE id = ?2 AND deleted_at IS NULL"
+	res, err := exec(ctx, s.db, q, displayName, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+// ChangeEmail sets new not verified email of user,
+// storage.ErrAlreadyExists is returned if another user has it
+func (s *Storage) ChangeEmail(ctx context.Context, userID, email string) error {
+	q := "UPDATE users SET email = ?1, email_verified = FALSE WHERE id = ?2 AND deleted_at IS NULL"
+	res, err := exec(ctx, s.db, q, email, userID)
+	if err != nil {
+		return translate(err)
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+// DeleteUser marks user as deleted, deleted users aren't found
+// and their email and linked identities can be used by new users
+func (s *Storage) DeleteUser(ctx context.Context, userID string, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Make sure to close transaction if something goes wrong.
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	q := "UPDATE users SET deleted_at = ?1 WHERE id = ?2 AND deleted_at IS NULL"
+	res, err := exec(ctx, tx, q, at, userID)
+	if err != nil {
+		return err
+	}
+	if err := oneRowAffected(res.RowsAffected()); err != nil {
+		return err
+	}
+
+	if _, err := exec(ctx, tx, "DELETE FROM identities WHERE user_id = ?1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// oneRowAffected returns storage.ErrNotFound if no rows were affected by update
+func oneRowAffected(n int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListUsers returns page of users selected by filter
+func (s *Storage) ListUsers(ctx context.Context, f models.UserFilter) ([]models.User, error) {
+	var conds []string
+	var args []any
+
+	// where adds condition with the next placeholder for every arg
+	where := func(cond string, a ...any) {
+		n := make([]any, len(a))
+		for i := range a {
+			args = append(args, a[i])
+			n[i] = len(args)
+		}
+		conds = append(conds, fmt.Sprintf(cond, n...))
+	}
+
+	switch f.Status {
+	case models.UserStatusActive:
+		where("deleted_at IS NULL AND disabled_at IS NULL")
+	case models.UserStatusDisabled:
+		where("deleted_at IS NULL AND disabled_at IS NOT NULL")
+	case models.UserStatusDeleted:
+		where("deleted_at IS NOT NULL")
+	default:
+		where("deleted_at IS NULL")
+	}
+
+	if f.EmailPrefix != "" {
+		// LIKE of sqlite ignores case
+		where("substr(email, 1, length(?%d)) = ?%[1]d", f.EmailPrefix)
+	}
+	if !f.CreatedAfter.IsZero() {
+		where("created_at >= ?%d", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		where("created_at < ?%d", f.CreatedBefore)
+	}
+	if f.AfterID != "" {
+		where("(created_at, id) > (?%d, ?%d)", f.AfterCreatedAt, f.AfterID)
+	}
+
+	q := "SELECT " + userColumns + " FROM users WHERE " + strings.Join(conds, " AND ") +
+		fmt.Sprintf(" ORDER BY created_at, id LIMIT %d", f.Limit)
+
+	rows, err := query(ctx, s.db, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+
+	return res, rows.Err()
+}
+
+// DisableUser marks user as disabled, disabling disabled user isn't an error
+func (s *Storage) DisableUser(ctx context.Context, userID string, at time.Time) error {
+	q := "UPDATE users SET disabled_at = COALESCE(disabled_at, ?1) WHERE id = ?2 AND deleted_at IS NULL"
+	res, err := exec(ctx, s.db, q, at, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
+
+func (s *Storage) EnableUser(ctx context.Context, userID string) error {
+	q := "UPDATE users SET disabled_at = NULL WHERE id = ?1 AND deleted_at IS NULL"
+	res, err := exec(ctx, s.db, q, userID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowAffected(res.RowsAffected())
+}
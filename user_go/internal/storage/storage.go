@@ -1,8 +1,104 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"lib/errs"
+	"time"
+	"user_service/internal/domain/models"
+)
 
 var (
-	ErrEmptyFields = errors.New("empty fields")
-	ErrNotFound    = errors.New("not found")
+	ErrEmptyFields   = errs.New(errs.ErrInvalid, "empty fields")
+	ErrNotFound      = errs.ErrNotFound
+	ErrAlreadyExists = errs.ErrAlreadyExists
 )
+
+// Storage is everything services keep, it's implemented by postgres.Storage and memory.Storage.
+// Both return ErrNotFound and ErrAlreadyExists in the same cases, see storagetest.Run.
+type Storage interface {
+	SaveUser(ctx context.Context, u models.User) (models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (models.User, error)
+	FindUserByID(ctx context.Context, id string) (models.User, error)
+	UpdatePassword(ctx context.Context, userID string, encPassword []byte) error
+	UpdateProfile(ctx context.Context, userID, displayName string) error
+	ChangeEmail(ctx context.Context, userID, email string) error
+	DeleteUser(ctx context.Context, userID string, at time.Time) error
+	ListUsers(ctx context.Context, f models.UserFilter) ([]models.User, error)
+	DisableUser(ctx context.Context, userID string, at time.Time) error
+	EnableUser(ctx context.Context, userID string) error
+
+	FindAppByID(ctx context.Context, id int32) (models.App, error)
+
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID string) ([]models.Permission, error)
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	AssignRole(ctx context.Context, userID, role string) error
+	RevokeRole(ctx context.Context, userID, role string) error
+	SetUserRoles(ctx context.Context, userID string, roles []string) error
+
+	RevokeToken(ctx context.Context, jti, userID string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID string, before time.Time) error
+	IsTokenRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error)
+
+	SaveRefreshToken(ctx context.Context, t models.RefreshToken) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID string, next models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+
+	SaveSession(ctx context.Context, s models.Session) error
+	TouchSession(ctx context.Context, id, ip string, at time.Time) error
+	UserSessions(ctx context.Context, userID string, activeAfter time.Time) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, id string, at time.Time) error
+	RevokeUserSessions(ctx context.Context, userID string, at time.Time) error
+	IsSessionRevoked(ctx context.Context, id string) (bool, error)
+
+	SaveEmailVerification(ctx context.Context, v models.EmailVerification) error
+	FindEmailVerification(ctx context.Context, tokenHash string) (models.EmailVerification, error)
+	VerifyEmail(ctx context.Context, tokenHash string, usedAt time.Time) error
+
+	SavePasswordReset(ctx context.Context, r models.PasswordReset) error
+	FindPasswordReset(ctx context.Context, tokenHash string) (models.PasswordReset, error)
+	ResetPassword(ctx context.Context, tokenHash string, encPassword []byte, usedAt time.Time) error
+
+	GetLoginFailures(ctx context.Context, key string) (models.LoginFailures, error)
+	AddLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (models.LoginFailures, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	DeleteLoginFailures(ctx context.Context, key string) error
+
+	GetMFA(ctx context.Context, userID string) (models.MFA, error)
+	SaveMFA(ctx context.Context, m models.MFA) error
+	EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string, at time.Time) error
+	DeleteMFA(ctx context.Context, userID string) error
+
+	SaveAccessToken(ctx context.Context, t models.AccessToken) error
+	FindAccessToken(ctx context.Context, tokenHash string) (models.AccessToken, error)
+	UserAccessTokens(ctx context.Context, userID string) ([]models.AccessToken, error)
+	TouchAccessToken(ctx context.Context, id string, at time.Time) error
+	RevokeAccessToken(ctx context.Context, userID, id string, at time.Time) error
+	RevokeUserAccessTokens(ctx context.Context, userID string, at time.Time) error
+
+	SaveOAuthClient(ctx context.Context, c models.OAuthClient) error
+	FindOAuthClient(ctx context.Context, id string) (models.OAuthClient, error)
+	SaveOAuthCode(ctx context.Context, c models.OAuthCode) error
+	FindOAuthCode(ctx context.Context, codeHash string) (models.OAuthCode, error)
+	UseOAuthCode(ctx context.Context, codeHash string, at time.Time) error
+	SaveOAuthTokens(ctx context.Context, tokens ...models.OAuthToken) error
+	FindOAuthToken(ctx context.Context, tokenHash string) (models.OAuthToken, error)
+	RotateOAuthRefreshToken(ctx context.Context, oldID string, at time.Time, next ...models.OAuthToken) error
+	RevokeOAuthGrant(ctx context.Context, grantID string, at time.Time) error
+	RevokeUserOAuthTokens(ctx context.Context, userID string, at time.Time) error
+
+	SaveIdentity(ctx context.Context, id models.Identity) error
+	SaveFederatedUser(ctx context.Context, u models.User, id models.Identity) (models.User, error)
+	FindIdentity(ctx context.Context, provider, subject string) (models.Identity, error)
+	UserIdentities(ctx context.Context, userID string) ([]models.Identity, error)
+	DeleteIdentity(ctx context.Context, userID, id string) error
+
+	SaveAuditEvent(ctx context.Context, e models.AuditEvent) error
+	ListAuditEvents(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, error)
+	DeleteAuditEvents(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,797 @@
+// Package storagetest is contract of storage.Storage, every implementation
+// runs the same tests, so services behave the same whatever storage they use.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// Run runs contract tests against s. Tests create their own users with random emails,
+// so s may be shared database with data of other runs.
+func Run(t *testing.T, s storage.Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{"Users", testUsers},
+		{"ListUsers", testListUsers},
+		{"Apps", testApps},
+		{"Roles", testRoles},
+		{"Revocations", testRevocations},
+		{"RefreshTokens", testRefreshTokens},
+		{"Sessions", testSessions},
+		{"EmailVerifications", testEmailVerifications},
+		{"PasswordResets", testPasswordResets},
+		{"LoginFailures", testLoginFailures},
+		{"MFA", testMFA},
+		{"AccessTokens", testAccessTokens},
+		{"OAuth", testOAuth},
+		{"Identities", testIdentities},
+		{"Audit", testAudit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, s)
+		})
+	}
+}
+
+// now is second precision time, it's kept by every storage as is
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func random() string {
+	return uuid.New().String()
+}
+
+func randomEmail() string {
+	return random() + "@example.org"
+}
+
+// newUser saves user with random email
+func newUser(t *testing.T, s storage.Storage) models.User {
+	t.Helper()
+
+	u, err := s.SaveUser(context.Background(), models.User{
+		Email:       randomEmail(),
+		EncPassword: []byte("hash"),
+	})
+	if err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+
+	return u
+}
+
+// wantErr fails test if err isn't target, nil target means no error
+func wantErr(t *testing.T, call string, err, target error) {
+	t.Helper()
+
+	switch {
+	case target == nil && err != nil:
+		t.Fatalf("%s error = %v, want no error", call, err)
+	case target != nil && !errors.Is(err, target):
+		t.Fatalf("%s error = %v, want %v", call, err, target)
+	}
+}
+
+func testUsers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	_, err := s.SaveUser(ctx, models.User{Email: randomEmail()})
+	wantErr(t, "SaveUser() without password", err, storage.ErrEmptyFields)
+
+	u := newUser(t, s)
+	if u.ID == "" {
+		t.Fatal("SaveUser() returned user without id")
+	}
+
+	_, err = s.SaveUser(ctx, models.User{Email: u.Email, EncPassword: []byte("hash")})
+	wantErr(t, "SaveUser() with used email", err, storage.ErrAlreadyExists)
+
+	got, err := s.FindUserByEmail(ctx, u.Email)
+	wantErr(t, "FindUserByEmail()", err, nil)
+	if got.ID != u.ID || string(got.EncPassword) != "hash" || got.EmailVerified || got.CreatedAt.IsZero() {
+		t.Errorf("FindUserByEmail() = %+v, want saved user", got)
+	}
+
+	_, err = s.FindUserByEmail(ctx, randomEmail())
+	wantErr(t, "FindUserByEmail() of unknown email", err, storage.ErrNotFound)
+	_, err = s.FindUserByID(ctx, random())
+	wantErr(t, "FindUserByID() of unknown user", err, storage.ErrNotFound)
+
+	wantErr(t, "UpdatePassword()", s.UpdatePassword(ctx, u.ID, []byte("new hash")), nil)
+	wantErr(t, "UpdateProfile()", s.UpdateProfile(ctx, u.ID, "Name"), nil)
+	got, _ = s.FindUserByID(ctx, u.ID)
+	if string(got.EncPassword) != "new hash" || got.DisplayName != "Name" {
+		t.Errorf("FindUserByID() = %+v, want updated password and display name", got)
+	}
+	wantErr(t, "UpdateProfile() of unknown user", s.UpdateProfile(ctx, random(), "Name"), storage.ErrNotFound)
+
+	other := newUser(t, s)
+	wantErr(t, "ChangeEmail() to used email", s.ChangeEmail(ctx, u.ID, other.Email), storage.ErrAlreadyExists)
+	email := randomEmail()
+	wantErr(t, "ChangeEmail()", s.ChangeEmail(ctx, u.ID, email), nil)
+	got, err = s.FindUserByEmail(ctx, email)
+	wantErr(t, "FindUserByEmail() of changed email", err, nil)
+	if got.ID != u.ID {
+		t.Errorf("FindUserByEmail() = %+v, want user %s", got, u.ID)
+	}
+
+	wantErr(t, "DisableUser()", s.DisableUser(ctx, u.ID, now()), nil)
+	wantErr(t, "DisableUser() of disabled user", s.DisableUser(ctx, u.ID, now().Add(time.Hour)), nil)
+	got, _ = s.FindUserByID(ctx, u.ID)
+	if got.Status() != models.UserStatusDisabled || got.DisabledAt.After(now()) {
+		t.Errorf("FindUserByID() = %+v, want user disabled the first time", got)
+	}
+	wantErr(t, "EnableUser()", s.EnableUser(ctx, u.ID), nil)
+	got, _ = s.FindUserByID(ctx, u.ID)
+	if got.Status() != models.UserStatusActive {
+		t.Errorf("FindUserByID() = %+v, want active user", got)
+	}
+
+	wantErr(t, "DeleteUser()", s.DeleteUser(ctx, u.ID, now()), nil)
+	wantErr(t, "DeleteUser() of deleted user", s.DeleteUser(ctx, u.ID, now()), storage.ErrNotFound)
+	_, err = s.FindUserByID(ctx, u.ID)
+	wantErr(t, "FindUserByID() of deleted user", err, storage.ErrNotFound)
+	wantErr(t, "UpdatePassword() of deleted user", s.UpdatePassword(ctx, u.ID, []byte("hash")), storage.ErrNotFound)
+
+	// email of deleted user can be used again
+	_, err = s.SaveUser(ctx, models.User{Email: email, EncPassword: []byte("hash")})
+	wantErr(t, "SaveUser() with email of deleted user", err, nil)
+}
+
+func testListUsers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	prefix := random()
+	var ids []string
+	for i := 0; i < 3; i++ {
+		u, err := s.SaveUser(ctx, models.User{
+			Email:       prefix + string(rune('a'+i)) + "@example.org",
+			EncPassword: []byte("hash"),
+		})
+		wantErr(t, "SaveUser()", err, nil)
+		ids = append(ids, u.ID)
+	}
+	wantErr(t, "DeleteUser()", s.DeleteUser(ctx, ids[2], now()), nil)
+
+	page, err := s.ListUsers(ctx, models.UserFilter{EmailPrefix: prefix, Limit: 1})
+	wantErr(t, "ListUsers()", err, nil)
+	if len(page) != 1 {
+		t.Fatalf("ListUsers() returned %d users, want 1", len(page))
+	}
+
+	next, err := s.ListUsers(ctx, models.UserFilter{
+		EmailPrefix:    prefix,
+		AfterCreatedAt: page[0].CreatedAt,
+		AfterID:        page[0].ID,
+		Limit:          10,
+	})
+	wantErr(t, "ListUsers() of the next page", err, nil)
+	if len(next) != 1 || next[0].ID == page[0].ID {
+		t.Fatalf("ListUsers() of the next page = %+v, want the other not deleted user", next)
+	}
+
+	deleted, err := s.ListUsers(ctx, models.UserFilter{
+		EmailPrefix: prefix,
+		Status:      models.UserStatusDeleted,
+		Limit:       10,
+	})
+	wantErr(t, "ListUsers() of deleted users", err, nil)
+	if len(deleted) != 1 || deleted[0].ID != ids[2] {
+		t.Errorf("ListUsers() of deleted users = %+v, want user %s", deleted, ids[2])
+	}
+}
+
+func testApps(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	a, err := s.FindAppByID(ctx, 1)
+	wantErr(t, "FindAppByID()", err, nil)
+	if a.Name != "web" {
+		t.Errorf("FindAppByID() = %+v, want web app", a)
+	}
+
+	_, err = s.FindAppByID(ctx, -1)
+	wantErr(t, "FindAppByID() of unknown app", err, storage.ErrNotFound)
+}
+
+func testRoles(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	roles, err := s.GetUserRoles(ctx, u.ID)
+	wantErr(t, "GetUserRoles()", err, nil)
+	if len(roles) != 1 || roles[0] != models.RoleUser {
+		t.Errorf("GetUserRoles() = %v, want default role", roles)
+	}
+
+	ok, err := s.HasPermission(ctx, u.ID, models.PermissionFilesRead)
+	wantErr(t, "HasPermission()", err, nil)
+	if !ok {
+		t.Errorf("HasPermission(%s) = false, want true", models.PermissionFilesRead)
+	}
+	ok, _ = s.HasPermission(ctx, u.ID, models.PermissionRolesManage)
+	if ok {
+		t.Errorf("HasPermission(%s) = true, want false", models.PermissionRolesManage)
+	}
+
+	wantErr(t, "AssignRole()", s.AssignRole(ctx, u.ID, models.RoleAdmin), nil)
+	wantErr(t, "AssignRole() of assigned role", s.AssignRole(ctx, u.ID, models.RoleAdmin), nil)
+	wantErr(t, "AssignRole() of unknown role", s.AssignRole(ctx, u.ID, random()), storage.ErrNotFound)
+	wantErr(t, "AssignRole() to unknown user", s.AssignRole(ctx, random(), models.RoleAdmin), storage.ErrNotFound)
+
+	permissions, err := s.GetUserPermissions(ctx, u.ID)
+	wantErr(t, "GetUserPermissions()", err, nil)
+	seen := make(map[string]bool)
+	for _, p := range permissions {
+		if seen[p.Name] {
+			t.Errorf("GetUserPermissions() returned %s twice", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	if !seen[models.PermissionRolesManage] || !seen[models.PermissionFilesRead] {
+		t.Errorf("GetUserPermissions() = %v, want permissions of both roles", permissions)
+	}
+
+	wantErr(t, "RevokeRole()", s.RevokeRole(ctx, u.ID, models.RoleAdmin), nil)
+	wantErr(t, "RevokeRole() of revoked role", s.RevokeRole(ctx, u.ID, models.RoleAdmin), storage.ErrNotFound)
+
+	err = s.SetUserRoles(ctx, u.ID, []string{models.RoleAdmin, random()})
+	wantErr(t, "SetUserRoles() with unknown role", err, storage.ErrNotFound)
+	roles, _ = s.GetUserRoles(ctx, u.ID)
+	if len(roles) != 1 || roles[0] != models.RoleUser {
+		t.Errorf("GetUserRoles() = %v, want roles unchanged after failed SetUserRoles()", roles)
+	}
+
+	wantErr(t, "SetUserRoles()", s.SetUserRoles(ctx, u.ID, []string{models.RoleAdmin}), nil)
+	roles, _ = s.GetUserRoles(ctx, u.ID)
+	if len(roles) != 1 || roles[0] != models.RoleAdmin {
+		t.Errorf("GetUserRoles() = %v, want only admin", roles)
+	}
+	wantErr(t, "SetUserRoles() of unknown user", s.SetUserRoles(ctx, random(), nil), storage.ErrNotFound)
+
+	list, err := s.ListRoles(ctx)
+	wantErr(t, "ListRoles()", err, nil)
+	found := false
+	for _, r := range list {
+		if r.Name == models.RoleAdmin && len(r.Permissions) > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("ListRoles() = %+v, want admin with permissions", list)
+	}
+}
+
+func testRevocations(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+	issuedAt := now()
+
+	jti := random()
+	wantErr(t, "RevokeToken()", s.RevokeToken(ctx, jti, u.ID, issuedAt.Add(time.Hour)), nil)
+	wantErr(t, "RevokeToken() of revoked token", s.RevokeToken(ctx, jti, u.ID, issuedAt.Add(time.Hour)), nil)
+
+	revoked, err := s.IsTokenRevoked(ctx, jti, u.ID, issuedAt)
+	wantErr(t, "IsTokenRevoked()", err, nil)
+	if !revoked {
+		t.Error("IsTokenRevoked() of revoked token = false")
+	}
+	if revoked, _ = s.IsTokenRevoked(ctx, random(), u.ID, issuedAt); revoked {
+		t.Error("IsTokenRevoked() of other token = true")
+	}
+
+	wantErr(t, "RevokeUserTokens()", s.RevokeUserTokens(ctx, u.ID, issuedAt), nil)
+	if revoked, _ = s.IsTokenRevoked(ctx, random(), u.ID, issuedAt.Add(-time.Second)); !revoked {
+		t.Error("IsTokenRevoked() of token issued before revocation = false")
+	}
+	if revoked, _ = s.IsTokenRevoked(ctx, random(), u.ID, issuedAt); !revoked {
+		t.Error("IsTokenRevoked() of token issued in second of revocation = false")
+	}
+	if revoked, _ = s.IsTokenRevoked(ctx, random(), u.ID, issuedAt.Add(time.Second)); revoked {
+		t.Error("IsTokenRevoked() of token issued after second of revocation = true")
+	}
+
+	// iat of tokens has second precision, so revocation in the middle of second
+	// revokes tokens issued earlier in that second too
+	other := newUser(t, s)
+	before := issuedAt.Add(500 * time.Millisecond)
+	wantErr(t, "RevokeUserTokens() in middle of second", s.RevokeUserTokens(ctx, other.ID, before), nil)
+	if revoked, _ = s.IsTokenRevoked(ctx, random(), other.ID, issuedAt); !revoked {
+		t.Error("IsTokenRevoked() of token issued in second of sub-second revocation = false")
+	}
+	if revoked, _ = s.IsTokenRevoked(ctx, random(), other.ID, issuedAt.Add(time.Second)); revoked {
+		t.Error("IsTokenRevoked() of token issued after second of sub-second revocation = true")
+	}
+}
+
+func testRefreshTokens(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	token := func(familyID string) models.RefreshToken {
+		return models.RefreshToken{
+			ID:        random(),
+			FamilyID:  familyID,
+			UserID:    u.ID,
+			Email:     u.Email,
+			AppID:     1,
+			TokenHash: random(),
+			ExpiresAt: now().Add(time.Hour),
+			CreatedAt: now(),
+		}
+	}
+
+	first := token(random())
+	wantErr(t, "SaveRefreshToken()", s.SaveRefreshToken(ctx, first), nil)
+
+	got, err := s.FindRefreshToken(ctx, first.TokenHash)
+	wantErr(t, "FindRefreshToken()", err, nil)
+	if got.ID != first.ID || got.AppID != 1 || !got.ExpiresAt.Equal(first.ExpiresAt) || got.IsUsed() {
+		t.Errorf("FindRefreshToken() = %+v, want %+v", got, first)
+	}
+	_, err = s.FindRefreshToken(ctx, random())
+	wantErr(t, "FindRefreshToken() of unknown token", err, storage.ErrNotFound)
+
+	second := token(first.FamilyID)
+	wantErr(t, "RotateRefreshToken()", s.RotateRefreshToken(ctx, first.ID, second), nil)
+	err = s.RotateRefreshToken(ctx, first.ID, token(first.FamilyID))
+	wantErr(t, "RotateRefreshToken() of used token", err, storage.ErrAlreadyExists)
+	if got, _ = s.FindRefreshToken(ctx, first.TokenHash); !got.IsUsed() {
+		t.Error("FindRefreshToken() of rotated token isn't used")
+	}
+
+	wantErr(t, "RevokeRefreshTokenFamily()", s.RevokeRefreshTokenFamily(ctx, first.FamilyID), nil)
+	if got, _ = s.FindRefreshToken(ctx, second.TokenHash); !got.IsRevoked() {
+		t.Error("FindRefreshToken() of token of revoked family isn't revoked")
+	}
+	err = s.RotateRefreshToken(ctx, second.ID, token(first.FamilyID))
+	wantErr(t, "RotateRefreshToken() of revoked token", err, storage.ErrAlreadyExists)
+
+	other := token(random())
+	wantErr(t, "SaveRefreshToken()", s.SaveRefreshToken(ctx, other), nil)
+	wantErr(t, "RevokeUserRefreshTokens()", s.RevokeUserRefreshTokens(ctx, u.ID), nil)
+	if got, _ = s.FindRefreshToken(ctx, other.TokenHash); !got.IsRevoked() {
+		t.Error("FindRefreshToken() of token of user isn't revoked")
+	}
+}
+
+func testSessions(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+	at := now()
+
+	older := models.Session{ID: random(), UserID: u.ID, AppID: 1, IP: "203.0.113.1", CreatedAt: at, LastUsedAt: at}
+	newer := models.Session{ID: random(), UserID: u.ID, AppID: 1, IP: "203.0.113.2", CreatedAt: at, LastUsedAt: at}
+	wantErr(t, "SaveSession()", s.SaveSession(ctx, older), nil)
+	wantErr(t, "SaveSession()", s.SaveSession(ctx, newer), nil)
+
+	wantErr(t, "TouchSession()", s.TouchSession(ctx, newer.ID, "203.0.113.3", at.Add(time.Minute)), nil)
+	wantErr(t, "TouchSession() of unknown session", s.TouchSession(ctx, random(), "", at), nil)
+
+	list, err := s.UserSessions(ctx, u.ID, at.Add(-time.Hour))
+	wantErr(t, "UserSessions()", err, nil)
+	if len(list) != 2 || list[0].ID != newer.ID || list[0].IP != "203.0.113.3" {
+		t.Fatalf("UserSessions() = %+v, want touched session first", list)
+	}
+
+	wantErr(t, "RevokeSession() of other user", s.RevokeSession(ctx, random(), older.ID, at), storage.ErrNotFound)
+	wantErr(t, "RevokeSession()", s.RevokeSession(ctx, u.ID, older.ID, at), nil)
+	wantErr(t, "RevokeSession() of revoked session", s.RevokeSession(ctx, u.ID, older.ID, at), storage.ErrNotFound)
+
+	revoked, err := s.IsSessionRevoked(ctx, older.ID)
+	wantErr(t, "IsSessionRevoked()", err, nil)
+	if !revoked {
+		t.Error("IsSessionRevoked() of revoked session = false")
+	}
+	if revoked, _ = s.IsSessionRevoked(ctx, random()); revoked {
+		t.Error("IsSessionRevoked() of unknown session = true")
+	}
+
+	wantErr(t, "RevokeUserSessions()", s.RevokeUserSessions(ctx, u.ID, at), nil)
+	if list, _ = s.UserSessions(ctx, u.ID, at.Add(-time.Hour)); len(list) != 0 {
+		t.Errorf("UserSessions() = %+v, want no sessions after RevokeUserSessions()", list)
+	}
+}
+
+func testEmailVerifications(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	verification := func(email string) models.EmailVerification {
+		return models.EmailVerification{
+			TokenHash: random(),
+			UserID:    u.ID,
+			Email:     email,
+			ExpiresAt: now().Add(time.Hour),
+			CreatedAt: now(),
+		}
+	}
+
+	replaced := verification(u.Email)
+	wantErr(t, "SaveEmailVerification()", s.SaveEmailVerification(ctx, replaced), nil)
+	v := verification(u.Email)
+	wantErr(t, "SaveEmailVerification()", s.SaveEmailVerification(ctx, v), nil)
+
+	_, err := s.FindEmailVerification(ctx, replaced.TokenHash)
+	wantErr(t, "FindEmailVerification() of replaced token", err, storage.ErrNotFound)
+	got, err := s.FindEmailVerification(ctx, v.TokenHash)
+	wantErr(t, "FindEmailVerification()", err, nil)
+	if got.UserID != u.ID || got.Email != u.Email || got.IsUsed() {
+		t.Errorf("FindEmailVerification() = %+v, want %+v", got, v)
+	}
+
+	wantErr(t, "VerifyEmail()", s.VerifyEmail(ctx, v.TokenHash, now()), nil)
+	wantErr(t, "VerifyEmail() with used token", s.VerifyEmail(ctx, v.TokenHash, now()), storage.ErrAlreadyExists)
+	if u, _ := s.FindUserByID(ctx, u.ID); !u.EmailVerified {
+		t.Error("FindUserByID() of verified user isn't verified")
+	}
+
+	// link sent to old email doesn't verify new one
+	old := verification(u.Email)
+	wantErr(t, "SaveEmailVerification()", s.SaveEmailVerification(ctx, old), nil)
+	wantErr(t, "ChangeEmail()", s.ChangeEmail(ctx, u.ID, randomEmail()), nil)
+	wantErr(t, "VerifyEmail() of changed email", s.VerifyEmail(ctx, old.TokenHash, now()), storage.ErrNotFound)
+	if got, _ := s.FindEmailVerification(ctx, old.TokenHash); got.IsUsed() {
+		t.Error("FindEmailVerification() of token of changed email is used")
+	}
+}
+
+func testPasswordResets(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	reset := func() models.PasswordReset {
+		return models.PasswordReset{
+			TokenHash: random(),
+			UserID:    u.ID,
+			ExpiresAt: now().Add(time.Hour),
+			CreatedAt: now(),
+		}
+	}
+
+	replaced := reset()
+	wantErr(t, "SavePasswordReset()", s.SavePasswordReset(ctx, replaced), nil)
+	r := reset()
+	wantErr(t, "SavePasswordReset()", s.SavePasswordReset(ctx, r), nil)
+
+	_, err := s.FindPasswordReset(ctx, replaced.TokenHash)
+	wantErr(t, "FindPasswordReset() of replaced token", err, storage.ErrNotFound)
+	got, err := s.FindPasswordReset(ctx, r.TokenHash)
+	wantErr(t, "FindPasswordReset()", err, nil)
+	if got.UserID != u.ID || !got.ExpiresAt.Equal(r.ExpiresAt) || got.IsUsed() {
+		t.Errorf("FindPasswordReset() = %+v, want %+v", got, r)
+	}
+
+	wantErr(t, "ResetPassword()", s.ResetPassword(ctx, r.TokenHash, []byte("reset hash"), now()), nil)
+	err = s.ResetPassword(ctx, r.TokenHash, []byte("other hash"), now())
+	wantErr(t, "ResetPassword() with used token", err, storage.ErrAlreadyExists)
+	if u, _ := s.FindUserByID(ctx, u.ID); string(u.EncPassword) != "reset hash" {
+		t.Errorf("FindUserByID() has password %q, want password of the first reset", u.EncPassword)
+	}
+}
+
+func testLoginFailures(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	key := "ip:" + random()
+	at := now()
+
+	_, err := s.GetLoginFailures(ctx, key)
+	wantErr(t, "GetLoginFailures() of unknown key", err, storage.ErrNotFound)
+
+	for i := 1; i <= 2; i++ {
+		f, err := s.AddLoginFailure(ctx, key, at, at.Add(-time.Hour))
+		wantErr(t, "AddLoginFailure()", err, nil)
+		if f.Failures != i {
+			t.Fatalf("AddLoginFailure() has %d failures, want %d", f.Failures, i)
+		}
+	}
+
+	wantErr(t, "LockLogin()", s.LockLogin(ctx, key, at.Add(time.Minute)), nil)
+	f, err := s.GetLoginFailures(ctx, key)
+	wantErr(t, "GetLoginFailures()", err, nil)
+	if !f.IsLocked(at) {
+		t.Errorf("GetLoginFailures() = %+v, want locked", f)
+	}
+
+	// failures before resetBefore are forgotten, lock is kept
+	f, err = s.AddLoginFailure(ctx, key, at.Add(time.Hour), at.Add(time.Minute))
+	wantErr(t, "AddLoginFailure()", err, nil)
+	if f.Failures != 1 || !f.IsLocked(at) {
+		t.Errorf("AddLoginFailure() = %+v, want counter reset and lock kept", f)
+	}
+
+	wantErr(t, "DeleteLoginFailures()", s.DeleteLoginFailures(ctx, key), nil)
+	_, err = s.GetLoginFailures(ctx, key)
+	wantErr(t, "GetLoginFailures() of deleted key", err, storage.ErrNotFound)
+}
+
+func testMFA(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	_, err := s.GetMFA(ctx, u.ID)
+	wantErr(t, "GetMFA() of user without MFA", err, storage.ErrNotFound)
+
+	wantErr(t, "SaveMFA()", s.SaveMFA(ctx, models.MFA{UserID: u.ID, Secret: "first", CreatedAt: now()}), nil)
+	wantErr(t, "SaveMFA() of pending MFA", s.SaveMFA(ctx, models.MFA{UserID: u.ID, Secret: "second", CreatedAt: now()}), nil)
+
+	wantErr(t, "EnableMFA()", s.EnableMFA(ctx, u.ID, 10, []string{"code1", "code2"}), nil)
+	wantErr(t, "EnableMFA() of enabled MFA", s.EnableMFA(ctx, u.ID, 11, nil), storage.ErrAlreadyExists)
+	wantErr(t, "SaveMFA() of enabled MFA", s.SaveMFA(ctx, models.MFA{UserID: u.ID, Secret: "third", CreatedAt: now()}), storage.ErrAlreadyExists)
+
+	m, err := s.GetMFA(ctx, u.ID)
+	wantErr(t, "GetMFA()", err, nil)
+	if m.Secret != "second" || !m.Enabled || m.LastUsedStep != 10 {
+		t.Errorf("GetMFA() = %+v, want enabled second secret", m)
+	}
+
+	wantErr(t, "UseTOTPStep() of used step", s.UseTOTPStep(ctx, u.ID, 10), storage.ErrAlreadyExists)
+	wantErr(t, "UseTOTPStep()", s.UseTOTPStep(ctx, u.ID, 11), nil)
+
+	wantErr(t, "UseRecoveryCode()", s.UseRecoveryCode(ctx, u.ID, "code1", now()), nil)
+	wantErr(t, "UseRecoveryCode() of used code", s.UseRecoveryCode(ctx, u.ID, "code1", now()), storage.ErrNotFound)
+	wantErr(t, "UseRecoveryCode() of unknown code", s.UseRecoveryCode(ctx, u.ID, "code3", now()), storage.ErrNotFound)
+
+	wantErr(t, "DeleteMFA()", s.DeleteMFA(ctx, u.ID), nil)
+	_, err = s.GetMFA(ctx, u.ID)
+	wantErr(t, "GetMFA() of deleted MFA", err, storage.ErrNotFound)
+	wantErr(t, "UseRecoveryCode() after DeleteMFA()", s.UseRecoveryCode(ctx, u.ID, "code2", now()), storage.ErrNotFound)
+}
+
+func testAccessTokens(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	older := models.AccessToken{
+		ID:        random(),
+		UserID:    u.ID,
+		Name:      "ci",
+		Scopes:    []string{models.PermissionFilesRead, models.PermissionFilesWrite},
+		TokenHash: random(),
+		Hint:      "abcd",
+		CreatedAt: now().Add(-time.Minute),
+	}
+	newer := older
+	newer.ID, newer.TokenHash, newer.CreatedAt, newer.ExpiresAt = random(), random(), now(), now().Add(time.Hour)
+	wantErr(t, "SaveAccessToken()", s.SaveAccessToken(ctx, older), nil)
+	wantErr(t, "SaveAccessToken()", s.SaveAccessToken(ctx, newer), nil)
+
+	got, err := s.FindAccessToken(ctx, older.TokenHash)
+	wantErr(t, "FindAccessToken()", err, nil)
+	if got.ID != older.ID || len(got.Scopes) != 2 || !got.ExpiresAt.IsZero() || got.IsRevoked() {
+		t.Errorf("FindAccessToken() = %+v, want %+v", got, older)
+	}
+	_, err = s.FindAccessToken(ctx, random())
+	wantErr(t, "FindAccessToken() of unknown token", err, storage.ErrNotFound)
+
+	wantErr(t, "TouchAccessToken()", s.TouchAccessToken(ctx, older.ID, now()), nil)
+	if got, _ = s.FindAccessToken(ctx, older.TokenHash); got.LastUsedAt.IsZero() {
+		t.Error("FindAccessToken() of touched token wasn't used")
+	}
+
+	list, err := s.UserAccessTokens(ctx, u.ID)
+	wantErr(t, "UserAccessTokens()", err, nil)
+	if len(list) != 2 || list[0].ID != newer.ID {
+		t.Fatalf("UserAccessTokens() = %+v, want the newest first", list)
+	}
+
+	wantErr(t, "RevokeAccessToken() of other user", s.RevokeAccessToken(ctx, random(), older.ID, now()), storage.ErrNotFound)
+	wantErr(t, "RevokeAccessToken()", s.RevokeAccessToken(ctx, u.ID, older.ID, now()), nil)
+	wantErr(t, "RevokeAccessToken() of revoked token", s.RevokeAccessToken(ctx, u.ID, older.ID, now()), storage.ErrNotFound)
+	if list, _ = s.UserAccessTokens(ctx, u.ID); len(list) != 1 {
+		t.Errorf("UserAccessTokens() = %+v, want revoked token hidden", list)
+	}
+
+	other := newer
+	other.ID, other.UserID, other.TokenHash = random(), newUser(t, s).ID, random()
+	wantErr(t, "SaveAccessToken()", s.SaveAccessToken(ctx, other), nil)
+
+	wantErr(t, "RevokeUserAccessTokens()", s.RevokeUserAccessTokens(ctx, u.ID, now()), nil)
+	if list, _ = s.UserAccessTokens(ctx, u.ID); len(list) != 0 {
+		t.Errorf("UserAccessTokens() = %+v, want no tokens after RevokeUserAccessTokens()", list)
+	}
+	if got, _ = s.FindAccessToken(ctx, other.TokenHash); got.IsRevoked() {
+		t.Error("RevokeUserAccessTokens() revoked token of other user")
+	}
+}
+
+func testOAuth(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	c := models.OAuthClient{
+		ID:           random(),
+		Name:         "app",
+		OwnerID:      u.ID,
+		RedirectURIs: []string{"https://app.example.org/callback"},
+		CreatedAt:    now(),
+	}
+	wantErr(t, "SaveOAuthClient()", s.SaveOAuthClient(ctx, c), nil)
+	gotClient, err := s.FindOAuthClient(ctx, c.ID)
+	wantErr(t, "FindOAuthClient()", err, nil)
+	if gotClient.OwnerID != u.ID || len(gotClient.RedirectURIs) != 1 || gotClient.IsConfidential() {
+		t.Errorf("FindOAuthClient() = %+v, want %+v", gotClient, c)
+	}
+	_, err = s.FindOAuthClient(ctx, random())
+	wantErr(t, "FindOAuthClient() of unknown client", err, storage.ErrNotFound)
+
+	code := models.OAuthCode{
+		CodeHash:    random(),
+		GrantID:     random(),
+		ClientID:    c.ID,
+		UserID:      u.ID,
+		RedirectURI: c.RedirectURIs[0],
+		Scopes:      []string{models.PermissionFilesRead},
+		ExpiresAt:   now().Add(time.Minute),
+		CreatedAt:   now(),
+	}
+	wantErr(t, "SaveOAuthCode()", s.SaveOAuthCode(ctx, code), nil)
+	gotCode, err := s.FindOAuthCode(ctx, code.CodeHash)
+	wantErr(t, "FindOAuthCode()", err, nil)
+	if gotCode.GrantID != code.GrantID || len(gotCode.Scopes) != 1 || gotCode.IsUsed() {
+		t.Errorf("FindOAuthCode() = %+v, want %+v", gotCode, code)
+	}
+	wantErr(t, "UseOAuthCode()", s.UseOAuthCode(ctx, code.CodeHash, now()), nil)
+	wantErr(t, "UseOAuthCode() of used code", s.UseOAuthCode(ctx, code.CodeHash, now()), storage.ErrAlreadyExists)
+
+	token := func(kind string) models.OAuthToken {
+		return models.OAuthToken{
+			ID:        random(),
+			GrantID:   code.GrantID,
+			Kind:      kind,
+			TokenHash: random(),
+			ClientID:  c.ID,
+			UserID:    u.ID,
+			Scopes:    code.Scopes,
+			ExpiresAt: now().Add(time.Hour),
+			CreatedAt: now(),
+		}
+	}
+
+	refresh := token(models.OAuthTokenRefresh)
+	wantErr(t, "SaveOAuthTokens()", s.SaveOAuthTokens(ctx, token(models.OAuthTokenAccess), refresh), nil)
+	gotToken, err := s.FindOAuthToken(ctx, refresh.TokenHash)
+	wantErr(t, "FindOAuthToken()", err, nil)
+	if gotToken.ID != refresh.ID || gotToken.Kind != models.OAuthTokenRefresh || gotToken.IsUsed() {
+		t.Errorf("FindOAuthToken() = %+v, want %+v", gotToken, refresh)
+	}
+	_, err = s.FindOAuthToken(ctx, random())
+	wantErr(t, "FindOAuthToken() of unknown token", err, storage.ErrNotFound)
+
+	next := token(models.OAuthTokenRefresh)
+	err = s.RotateOAuthRefreshToken(ctx, refresh.ID, now(), token(models.OAuthTokenAccess), next)
+	wantErr(t, "RotateOAuthRefreshToken()", err, nil)
+	err = s.RotateOAuthRefreshToken(ctx, refresh.ID, now(), token(models.OAuthTokenRefresh))
+	wantErr(t, "RotateOAuthRefreshToken() of used token", err, storage.ErrAlreadyExists)
+
+	wantErr(t, "RevokeOAuthGrant()", s.RevokeOAuthGrant(ctx, code.GrantID, now()), nil)
+	if gotToken, _ = s.FindOAuthToken(ctx, next.TokenHash); !gotToken.IsRevoked() {
+		t.Error("FindOAuthToken() of token of revoked grant isn't revoked")
+	}
+
+	// the other grant of user is revoked with all his tokens
+	code.GrantID = random()
+	granted := token(models.OAuthTokenAccess)
+	wantErr(t, "SaveOAuthTokens()", s.SaveOAuthTokens(ctx, granted), nil)
+	wantErr(t, "RevokeUserOAuthTokens()", s.RevokeUserOAuthTokens(ctx, u.ID, now()), nil)
+	if gotToken, _ = s.FindOAuthToken(ctx, granted.TokenHash); !gotToken.IsRevoked() {
+		t.Error("FindOAuthToken() of token of user after RevokeUserOAuthTokens() isn't revoked")
+	}
+}
+
+func testIdentities(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	u := newUser(t, s)
+
+	identity := func(userID string) models.Identity {
+		return models.Identity{
+			UserID:    userID,
+			Provider:  "google",
+			Subject:   random(),
+			Email:     randomEmail(),
+			CreatedAt: now(),
+		}
+	}
+
+	linked := identity(u.ID)
+	wantErr(t, "SaveIdentity()", s.SaveIdentity(ctx, linked), nil)
+	wantErr(t, "SaveIdentity() of linked identity", s.SaveIdentity(ctx, linked), storage.ErrAlreadyExists)
+
+	got, err := s.FindIdentity(ctx, linked.Provider, linked.Subject)
+	wantErr(t, "FindIdentity()", err, nil)
+	if got.ID == "" || got.UserID != u.ID || got.Email != linked.Email {
+		t.Errorf("FindIdentity() = %+v, want %+v", got, linked)
+	}
+	_, err = s.FindIdentity(ctx, "other", linked.Subject)
+	wantErr(t, "FindIdentity() of other provider", err, storage.ErrNotFound)
+
+	_, err = s.SaveFederatedUser(ctx, models.User{Email: u.Email}, identity(""))
+	wantErr(t, "SaveFederatedUser() with used email", err, storage.ErrAlreadyExists)
+	_, err = s.SaveFederatedUser(ctx, models.User{Email: randomEmail()}, linked)
+	wantErr(t, "SaveFederatedUser() with linked identity", err, storage.ErrAlreadyExists)
+
+	federated, err := s.SaveFederatedUser(ctx, models.User{Email: randomEmail(), EmailVerified: true}, identity(""))
+	wantErr(t, "SaveFederatedUser()", err, nil)
+	fu, err := s.FindUserByID(ctx, federated.ID)
+	wantErr(t, "FindUserByID() of federated user", err, nil)
+	if fu.HasPassword() || !fu.EmailVerified {
+		t.Errorf("FindUserByID() = %+v, want verified user without password", fu)
+	}
+	if roles, _ := s.GetUserRoles(ctx, fu.ID); len(roles) != 1 || roles[0] != models.RoleUser {
+		t.Errorf("GetUserRoles() of federated user = %v, want default role", roles)
+	}
+
+	list, err := s.UserIdentities(ctx, federated.ID)
+	wantErr(t, "UserIdentities()", err, nil)
+	if len(list) != 1 || list[0].UserID != federated.ID {
+		t.Fatalf("UserIdentities() = %+v, want identity of federated user", list)
+	}
+
+	wantErr(t, "DeleteIdentity() of other user", s.DeleteIdentity(ctx, u.ID, list[0].ID), storage.ErrNotFound)
+	wantErr(t, "DeleteIdentity()", s.DeleteIdentity(ctx, federated.ID, list[0].ID), nil)
+	wantErr(t, "DeleteIdentity() of deleted identity", s.DeleteIdentity(ctx, federated.ID, list[0].ID), storage.ErrNotFound)
+
+	// identities of deleted user can be linked again
+	wantErr(t, "DeleteUser()", s.DeleteUser(ctx, u.ID, now()), nil)
+	_, err = s.FindIdentity(ctx, linked.Provider, linked.Subject)
+	wantErr(t, "FindIdentity() of deleted user", err, storage.ErrNotFound)
+	linked.UserID = newUser(t, s).ID
+	wantErr(t, "SaveIdentity() of identity of deleted user", s.SaveIdentity(ctx, linked), nil)
+}
+
+func testAudit(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	userID := random()
+
+	// events are so old that deleting them doesn't touch events of other tests
+	at := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, outcome := range []string{models.AuditFailure, models.AuditSuccess, models.AuditFailure} {
+		err := s.SaveAuditEvent(ctx, models.AuditEvent{
+			Type:      models.AuditLogin,
+			Outcome:   outcome,
+			UserID:    userID,
+			Email:     "user@example.org",
+			AppID:     1,
+			IP:        "203.0.113.1",
+			CreatedAt: at,
+		})
+		wantErr(t, "SaveAuditEvent()", err, nil)
+	}
+
+	page, err := s.ListAuditEvents(ctx, models.AuditFilter{UserID: userID, Limit: 2})
+	wantErr(t, "ListAuditEvents()", err, nil)
+	if len(page) != 2 || page[0].ID <= page[1].ID || page[0].Outcome != models.AuditFailure {
+		t.Fatalf("ListAuditEvents() = %+v, want the newest 2 events", page)
+	}
+
+	rest, err := s.ListAuditEvents(ctx, models.AuditFilter{UserID: userID, BeforeID: page[1].ID, Limit: 10})
+	wantErr(t, "ListAuditEvents() of the next page", err, nil)
+	if len(rest) != 1 || rest[0].ID >= page[1].ID {
+		t.Fatalf("ListAuditEvents() of the next page = %+v, want the oldest event", rest)
+	}
+
+	failures, err := s.ListAuditEvents(ctx, models.AuditFilter{UserID: userID, Outcome: models.AuditFailure, Limit: 10})
+	wantErr(t, "ListAuditEvents() of failures", err, nil)
+	if len(failures) != 2 {
+		t.Errorf("ListAuditEvents() of failures returned %d events, want 2", len(failures))
+	}
+
+	n, err := s.DeleteAuditEvents(ctx, at.Add(time.Second))
+	wantErr(t, "DeleteAuditEvents()", err, nil)
+	if n < 3 {
+		t.Errorf("DeleteAuditEvents() = %d, want at least 3", n)
+	}
+	if left, _ := s.ListAuditEvents(ctx, models.AuditFilter{UserID: userID, Limit: 10}); len(left) != 0 {
+		t.Errorf("ListAuditEvents() = %+v, want deleted events gone", left)
+	}
+}
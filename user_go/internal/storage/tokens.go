@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"lib/tokens"
+	"user_service/internal/domain/models"
+)
+
+// Tokens adapts s to storage and apps of tokens.Manager,
+// errors of s are translated to ones manager expects
+func Tokens(s Storage) TokenStorage {
+	return TokenStorage{s}
+}
+
+// TokenStorage implements tokens.Storage and tokens.Apps
+type TokenStorage struct {
+	Storage
+}
+
+func (s TokenStorage) FindRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	t, err := s.Storage.FindRefreshToken(ctx, tokenHash)
+	if errors.Is(err, ErrNotFound) {
+		return models.RefreshToken{}, tokens.ErrNotFound
+	}
+
+	return t, err
+}
+
+func (s TokenStorage) RotateRefreshToken(ctx context.Context, oldID string, next models.RefreshToken) error {
+	err := s.Storage.RotateRefreshToken(ctx, oldID, next)
+	if errors.Is(err, ErrAlreadyExists) {
+		return tokens.ErrAlreadyUsed
+	}
+
+	return err
+}
+
+func (s TokenStorage) FindApp(ctx context.Context, id int32) (tokens.App, error) {
+	a, err := s.FindAppByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return tokens.App{}, tokens.ErrNotFound
+		}
+		return tokens.App{}, err
+	}
+
+	return a.TokenApp(), nil
+}
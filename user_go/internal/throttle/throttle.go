@@ -0,0 +1,167 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+var (
+	ErrAccountLocked   = errors.New("account locked")
+	ErrTooManyAttempts = errors.New("too many login attempts")
+)
+
+// maxBackoffShift limits doubling of lockout, so it can't overflow
+const maxBackoffShift = 30
+
+// LockedError is returned by Check* methods, it tells when login can be tried again
+type LockedError struct {
+	Err        error // ErrAccountLocked or ErrTooManyAttempts
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.RetryAfter)
+}
+
+func (e *LockedError) Unwrap() error {
+	return e.Err
+}
+
+type Storage interface {
+	GetLoginFailures(ctx context.Context, key string) (models.LoginFailures, error)
+	// AddLoginFailure increments failures of key, counter starts from 1 again
+	// if last failure was before resetBefore. Returns updated failures.
+	AddLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (models.LoginFailures, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	DeleteLoginFailures(ctx context.Context, key string) error
+}
+
+// Policy is lockout policy: after MaxFailures failures in a row key is locked
+// for BaseLockout, every next failure doubles lockout up to MaxLockout.
+// Failures are forgotten ResetAfter the last one.
+type Policy struct {
+	MaxFailures int
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	ResetAfter  time.Duration
+}
+
+// lockout returns how long key with given number of failures is locked
+func (p Policy) lockout(failures int) time.Duration {
+	if p.MaxFailures <= 0 || failures < p.MaxFailures {
+		return 0
+	}
+
+	shift := failures - p.MaxFailures
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+
+	d := p.BaseLockout << shift
+	if d <= 0 || d > p.MaxLockout {
+		return p.MaxLockout
+	}
+
+	return d
+}
+
+// Limiter counts failed logins per account and per client IP
+type Limiter struct {
+	storage Storage
+	account Policy
+	ip      Policy
+}
+
+func New(storage Storage, account, ip Policy) *Limiter {
+	return &Limiter{
+		storage: storage,
+		account: account,
+		ip:      ip,
+	}
+}
+
+// CheckIP returns LockedError with ErrTooManyAttempts if IP is locked
+func (l *Limiter) CheckIP(ctx context.Context, ip string) error {
+	if ip == "" {
+		return nil
+	}
+
+	return l.check(ctx, ipKey(ip), ErrTooManyAttempts)
+}
+
+// CheckAccount returns LockedError with ErrAccountLocked if account is locked
+func (l *Limiter) CheckAccount(ctx context.Context, userID string) error {
+	return l.check(ctx, accountKey(userID), ErrAccountLocked)
+}
+
+func (l *Limiter) check(ctx context.Context, key string, lockedErr error) error {
+	f, err := l.storage.GetLoginFailures(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	if f.IsLocked(now) {
+		return &LockedError{Err: lockedErr, RetryAfter: f.LockedUntil.Sub(now)}
+	}
+
+	return nil
+}
+
+// Failure records failed login from IP, userID is empty if there is no such user
+func (l *Limiter) Failure(ctx context.Context, userID, ip string) error {
+	if ip != "" {
+		if err := l.failure(ctx, ipKey(ip), l.ip); err != nil {
+			return err
+		}
+	}
+
+	if userID != "" {
+		if err := l.failure(ctx, accountKey(userID), l.account); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Limiter) failure(ctx context.Context, key string, p Policy) error {
+	now := time.Now()
+
+	f, err := l.storage.AddLoginFailure(ctx, key, now, now.Add(-p.ResetAfter))
+	if err != nil {
+		return err
+	}
+
+	if d := p.lockout(f.Failures); d > 0 {
+		return l.storage.LockLogin(ctx, key, now.Add(d))
+	}
+
+	return nil
+}
+
+// Success forgets failures of account after successful login.
+// Failures of IP are kept, so one valid account doesn't let to guess passwords of others.
+func (l *Limiter) Success(ctx context.Context, userID string) error {
+	return l.storage.DeleteLoginFailures(ctx, accountKey(userID))
+}
+
+// Unlock removes lock and failures of account, it's done by admin
+func (l *Limiter) Unlock(ctx context.Context, userID string) error {
+	return l.storage.DeleteLoginFailures(ctx, accountKey(userID))
+}
+
+func accountKey(userID string) string {
+	return "account:" + userID
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,114 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/storage"
+)
+
+// fakeStorage keeps failures in map
+type fakeStorage struct {
+	failures map[string]models.LoginFailures
+}
+
+func (f *fakeStorage) GetLoginFailures(_ context.Context, key string) (models.LoginFailures, error) {
+	lf, ok := f.failures[key]
+	if !ok {
+		return models.LoginFailures{}, storage.ErrNotFound
+	}
+	return lf, nil
+}
+
+func (f *fakeStorage) AddLoginFailure(_ context.Context, key string, at, resetBefore time.Time) (models.LoginFailures, error) {
+	lf := f.failures[key]
+	lf.Key = key
+	if lf.LastFailureAt.Before(resetBefore) {
+		lf.Failures = 0
+	}
+	lf.Failures++
+	lf.LastFailureAt = at
+	f.failures[key] = lf
+	return lf, nil
+}
+
+func (f *fakeStorage) LockLogin(_ context.Context, key string, until time.Time) error {
+	lf := f.failures[key]
+	lf.LockedUntil = until
+	f.failures[key] = lf
+	return nil
+}
+
+func (f *fakeStorage) DeleteLoginFailures(_ context.Context, key string) error {
+	delete(f.failures, key)
+	return nil
+}
+
+var policy = Policy{MaxFailures: 3, BaseLockout: time.Minute, MaxLockout: 5 * time.Minute, ResetAfter: time.Hour}
+
+func TestPolicy_lockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Minute},
+		{failures: 4, want: 2 * time.Minute},
+		{failures: 5, want: 4 * time.Minute},
+		{failures: 6, want: 5 * time.Minute},
+		{failures: 1000, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.lockout(tt.failures); got != tt.want {
+			t.Errorf("lockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	st := &fakeStorage{failures: map[string]models.LoginFailures{}}
+	l := New(st, policy, Policy{MaxFailures: 5, BaseLockout: time.Minute, MaxLockout: time.Hour, ResetAfter: time.Hour})
+
+	for i := 0; i < policy.MaxFailures; i++ {
+		if err := l.CheckAccount(ctx, "user-id"); err != nil {
+			t.Fatalf("CheckAccount() after %d failures error = %v", i, err)
+		}
+		if err := l.Failure(ctx, "user-id", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := l.CheckAccount(ctx, "user-id")
+	var lErr *LockedError
+	if !errors.As(err, &lErr) || !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("CheckAccount() error = %v, want %v", err, ErrAccountLocked)
+	}
+	if lErr.RetryAfter <= 0 || lErr.RetryAfter > policy.BaseLockout {
+		t.Errorf("CheckAccount() retry after = %v", lErr.RetryAfter)
+	}
+
+	if err := l.CheckIP(ctx, "10.0.0.1"); err != nil {
+		t.Errorf("CheckIP() error = %v, want nil", err)
+	}
+
+	// failures of unknown users are counted per IP
+	for i := 0; i < 2; i++ {
+		if err := l.Failure(ctx, "", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.CheckIP(ctx, "10.0.0.1"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("CheckIP() error = %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	if err := l.Unlock(ctx, "user-id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.CheckAccount(ctx, "user-id"); err != nil {
+		t.Errorf("CheckAccount() after Unlock error = %v, want nil", err)
+	}
+}
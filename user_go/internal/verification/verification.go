@@ -0,0 +1,128 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lib/errs"
+	"lib/securetoken"
+	"strconv"
+	"strings"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/mailer"
+	"user_service/internal/storage"
+)
+
+var (
+	ErrInvalidToken = errs.New(errs.ErrInvalid, "invalid verification token")
+	ErrTokenExpired = errs.New(errs.ErrInvalid, "verification token expired")
+)
+
+type Storage interface {
+	SaveEmailVerification(ctx context.Context, v models.EmailVerification) error
+	FindEmailVerification(ctx context.Context, tokenHash string) (models.EmailVerification, error)
+	VerifyEmail(ctx context.Context, tokenHash string, usedAt time.Time) error
+}
+
+// Manager sends email verification tokens and verifies them.
+// Token is signed, so forged tokens are rejected without storage lookup,
+// and single use, its hash is stored and marked used on verification.
+type Manager struct {
+	storage Storage
+	mailer  mailer.Mailer
+	secret  []byte
+	ttl     time.Duration
+	link    string // fmt format of verification link with token as the only argument
+}
+
+func New(storage Storage, mailer mailer.Mailer, secret string, ttl time.Duration, link string) *Manager {
+	return &Manager{
+		storage: storage,
+		mailer:  mailer,
+		secret:  []byte(secret),
+		ttl:     ttl,
+		link:    link,
+	}
+}
+
+// Send creates verification token for current email of user and mails link with it.
+// Tokens sent before are invalidated.
+func (m *Manager) Send(ctx context.Context, u models.User) error {
+	nonce, _, err := securetoken.New()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	token := securetoken.Sign(m.secret, fmt.Sprintf("%s.%d.%s", u.ID, expiresAt.Unix(), nonce))
+
+	err = m.storage.SaveEmailVerification(ctx, models.EmailVerification{
+		TokenHash: securetoken.Hash(token),
+		UserID:    u.ID,
+		Email:     u.Email,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Follow the link to confirm your email:\n\n%s\n\nThe link expires in %s.\n",
+			fmt.Sprintf(m.link, token), m.ttl,
+		),
+	})
+}
+
+// Verify marks email of token owner as verified and returns his ID
+func (m *Manager) Verify(ctx context.Context, token string) (string, error) {
+	payload, err := securetoken.Verify(m.secret, token)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	now := time.Now()
+	if !now.Before(time.Unix(exp, 0)) {
+		return "", ErrTokenExpired
+	}
+
+	hash := securetoken.Hash(token)
+
+	v, err := m.storage.FindEmailVerification(ctx, hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+
+	if v.IsUsed() {
+		return "", ErrInvalidToken
+	}
+
+	if err := m.storage.VerifyEmail(ctx, hash, now); err != nil {
+		// token was used concurrently or user changed email after it was sent
+		if errors.Is(err, storage.ErrAlreadyExists) || errors.Is(err, storage.ErrNotFound) {
+			return "", ErrInvalidToken
+		}
+		return "", err
+	}
+
+	return v.UserID, nil
+}
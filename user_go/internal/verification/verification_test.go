@@ -0,0 +1,142 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"user_service/internal/domain/models"
+	"user_service/internal/mailer"
+	"user_service/internal/storage"
+)
+
+// fakeStorage keeps verifications by hash and verified users in maps
+type fakeStorage struct {
+	verifications map[string]models.EmailVerification
+	verified      map[string]bool
+}
+
+func (f *fakeStorage) SaveEmailVerification(_ context.Context, v models.EmailVerification) error {
+	for hash, old := range f.verifications {
+		if old.UserID == v.UserID && !old.IsUsed() {
+			delete(f.verifications, hash)
+		}
+	}
+	f.verifications[v.TokenHash] = v
+	return nil
+}
+
+func (f *fakeStorage) FindEmailVerification(_ context.Context, tokenHash string) (models.EmailVerification, error) {
+	v, ok := f.verifications[tokenHash]
+	if !ok {
+		return models.EmailVerification{}, storage.ErrNotFound
+	}
+	return v, nil
+}
+
+func (f *fakeStorage) VerifyEmail(_ context.Context, tokenHash string, usedAt time.Time) error {
+	v := f.verifications[tokenHash]
+	if v.IsUsed() {
+		return storage.ErrAlreadyExists
+	}
+	v.UsedAt = usedAt
+	f.verifications[tokenHash] = v
+	f.verified[v.UserID] = true
+	return nil
+}
+
+var user = models.User{ID: "user-id", Email: "user1@example.org"}
+
+func newManager(ttl time.Duration) (*Manager, *fakeStorage, *mailer.Memory) {
+	st := &fakeStorage{
+		verifications: map[string]models.EmailVerification{},
+		verified:      map[string]bool{},
+	}
+	m := mailer.NewMemory()
+
+	return New(st, m, "test-secret", ttl, "http://localhost/verify?token=%s"), st, m
+}
+
+// sentToken returns token from the last sent email
+func sentToken(t *testing.T, m *mailer.Memory) string {
+	messages := m.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no email sent")
+	}
+
+	_, rest, ok := strings.Cut(messages[len(messages)-1].Body, "token=")
+	if !ok {
+		t.Fatal("no token in email")
+	}
+
+	token, _, _ := strings.Cut(rest, "\n")
+
+	return token
+}
+
+func TestManager_Verify(t *testing.T) {
+	ctx := context.Background()
+	m, st, outbox := newManager(time.Hour)
+
+	if err := m.Send(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	if to := outbox.Messages()[0].To; to != user.Email {
+		t.Errorf("Send() to = %v, want %v", to, user.Email)
+	}
+
+	token := sentToken(t, outbox)
+
+	userID, err := m.Verify(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != user.ID || !st.verified[user.ID] {
+		t.Errorf("Verify() user = %v, verified = %v", userID, st.verified[user.ID])
+	}
+
+	if _, err := m.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() second time error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestManager_VerifyInvalid(t *testing.T) {
+	ctx := context.Background()
+
+	m, _, outbox := newManager(time.Hour)
+	if err := m.Send(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	first := sentToken(t, outbox)
+
+	if err := m.Send(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, _, expiredOutbox := newManager(-time.Hour)
+	if err := expired.Send(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		m     *Manager
+		token string
+		want  error
+	}{
+		{name: "forged", m: m, token: strings.Replace(sentToken(t, outbox), "user-id", "other-id", 1), want: ErrInvalidToken},
+		{name: "garbage", m: m, token: "garbage", want: ErrInvalidToken},
+		{name: "replaced by resend", m: m, token: first, want: ErrInvalidToken},
+		{name: "expired", m: expired, token: sentToken(t, expiredOutbox), want: ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.m.Verify(ctx, tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
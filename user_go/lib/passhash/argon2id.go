@@ -0,0 +1,105 @@
+package passhash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+var b64 = base64.RawStdEncoding
+
+// Argon2id is the scheme recommended by RFC 9106. Hashes are encoded
+// in PHC string format: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+type Argon2id struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLen     uint32
+	KeyLen      uint32
+}
+
+// DefaultArgon2id is the second recommended option of RFC 9106 for memory constrained environments
+var DefaultArgon2id = Argon2id{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLen:     16,
+	KeyLen:      32,
+}
+
+func (a Argon2id) Hash(password string) ([]byte, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLen)
+
+	return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		b64.EncodeToString(salt), b64.EncodeToString(key),
+	)), nil
+}
+
+func (a Argon2id) Match(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(argon2idPrefix))
+}
+
+func (a Argon2id) Verify(hash []byte, password string) bool {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func (a Argon2id) NeedsRehash(hash []byte) bool {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory < a.Memory ||
+		p.Iterations < a.Iterations ||
+		p.Parallelism < a.Parallelism ||
+		uint32(len(salt)) < a.SaltLen ||
+		uint32(len(key)) < a.KeyLen
+}
+
+// decodeArgon2id returns parameters, salt and key of hash
+func decodeArgon2id(hash []byte) (p Argon2id, salt, key []byte, err error) {
+	var version int
+
+	parts := bytes.Split(hash, []byte("$"))
+	if len(parts) != 6 {
+		return Argon2id{}, nil, nil, ErrInvalidHash
+	}
+
+	if _, err := fmt.Sscanf(string(parts[2]), "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2id{}, nil, nil, ErrInvalidHash
+	}
+
+	_, err = fmt.Sscanf(string(parts[3]), "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil || p.Iterations == 0 {
+		return Argon2id{}, nil, nil, ErrInvalidHash
+	}
+
+	if salt, err = b64.DecodeString(string(parts[4])); err != nil {
+		return Argon2id{}, nil, nil, ErrInvalidHash
+	}
+
+	if key, err = b64.DecodeString(string(parts[5])); err != nil || len(key) == 0 {
+		return Argon2id{}, nil, nil, ErrInvalidHash
+	}
+
+	return p, salt, key, nil
+}
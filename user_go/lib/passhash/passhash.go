@@ -0,0 +1,53 @@
+package passhash
+
+import "errors"
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Scheme is a password hashing algorithm. Hashes are self-describing:
+// they contain algorithm identifier and cost parameters, so hashes made
+// with different schemes or parameters can be stored side by side.
+type Scheme interface {
+	// Hash returns encoded hash of password
+	Hash(password string) ([]byte, error)
+	// Match reports whether hash was made by this scheme
+	Match(hash []byte) bool
+	// Verify reports whether password matches hash
+	Verify(hash []byte, password string) bool
+	// NeedsRehash reports whether hash was made with lower cost than scheme's
+	NeedsRehash(hash []byte) bool
+}
+
+// Hasher hashes passwords with the current scheme and verifies
+// hashes of the current and legacy schemes
+type Hasher struct {
+	schemes []Scheme // current one is first
+}
+
+func New(current Scheme, legacy ...Scheme) *Hasher {
+	return &Hasher{schemes: append([]Scheme{current}, legacy...)}
+}
+
+// Hash returns hash of password made with the current scheme
+func (h *Hasher) Hash(password string) ([]byte, error) {
+	return h.schemes[0].Hash(password)
+}
+
+// Verify reports whether password matches hash. If it does, rehash is true
+// when hash was made with legacy scheme or with lower cost than current one,
+// such hash should be replaced with Hash of password.
+func (h *Hasher) Verify(hash []byte, password string) (ok, rehash bool) {
+	for i, s := range h.schemes {
+		if !s.Match(hash) {
+			continue
+		}
+
+		if !s.Verify(hash, password) {
+			return false, false
+		}
+
+		return true, i != 0 || s.NeedsRehash(hash)
+	}
+
+	return false, false
+}
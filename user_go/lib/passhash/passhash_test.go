@@ -0,0 +1,79 @@
+package passhash
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters, tests don't need real cost
+var (
+	weakArgon2id = Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLen: 16, KeyLen: 32}
+	argon2id     = Argon2id{Memory: 2048, Iterations: 2, Parallelism: 1, SaltLen: 16, KeyLen: 32}
+)
+
+func TestHasher_Verify(t *testing.T) {
+	h := New(argon2id, Bcrypt{Cost: bcrypt.MinCost + 1})
+
+	current, err := h.Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weak, err := weakArgon2id.Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		hash       []byte
+		password   string
+		wantOk     bool
+		wantRehash bool
+	}{
+		{name: "current", hash: current, password: "password1", wantOk: true, wantRehash: false},
+		{name: "current wrong password", hash: current, password: "password2", wantOk: false},
+		{name: "legacy bcrypt", hash: legacy, password: "password1", wantOk: true, wantRehash: true},
+		{name: "legacy wrong password", hash: legacy, password: "password2", wantOk: false},
+		{name: "under-cost argon2id", hash: weak, password: "password1", wantOk: true, wantRehash: true},
+		{name: "unknown scheme", hash: []byte("$1$salt$hash"), password: "password1", wantOk: false},
+		{name: "malformed argon2id", hash: []byte("$argon2id$v=19$m=1,t=1$salt"), password: "password1", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash := h.Verify(tt.hash, tt.password)
+			if ok != tt.wantOk || rehash != tt.wantRehash {
+				t.Errorf("Verify() = %v, %v, want %v, %v", ok, rehash, tt.wantOk, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestHasher_Bcrypt(t *testing.T) {
+	// bcrypt can still be the current scheme, hashes with lower cost are upgraded
+	h := New(Bcrypt{Cost: bcrypt.MinCost + 1}, argon2id)
+
+	weak, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, rehash := h.Verify(weak, "password1"); !ok || !rehash {
+		t.Errorf("Verify() = %v, %v, want true, true", ok, rehash)
+	}
+
+	argon, err := argon2id.Hash("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, rehash := h.Verify(argon, "password1"); !ok || !rehash {
+		t.Errorf("Verify() = %v, %v, want true, true", ok, rehash)
+	}
+}
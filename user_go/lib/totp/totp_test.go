@@ -0,0 +1,73 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret of RFC 6238 test vectors for SHA1
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, last 6 digits of 8 digit codes
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	prev, err := Code(secret, Step(now)-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := Validate(secret, prev, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Errorf("Validate() = %v %v, want %v true", step, ok, Step(now)-1)
+	}
+
+	old, err := Code(secret, Step(now)-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Validate(secret, old, now, 1); ok {
+		t.Error("Validate() accepted code out of skew")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Example", "user1@example.org", "SECRET")
+
+	for _, want := range []string{"otpauth://totp/Example:user1@example.org?", "secret=SECRET", "issuer=Example"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("ProvisioningURI() = %v, want it to contain %v", uri, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS roles(
+  name VARCHAR(255) PRIMARY KEY UNIQUE NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS permissions(
+  name VARCHAR(255) PRIMARY KEY UNIQUE NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS role_permissions(
+  role VARCHAR(255) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  permission VARCHAR(255) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+  PRIMARY KEY (role, permission)
+);
+CREATE TABLE IF NOT EXISTS user_roles(
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(255) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles(name, description) VALUES
+  ('user', 'Default role of every registered user'),
+  ('admin', 'Can read all files and manage roles')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO permissions(name, description) VALUES
+  ('files:read', 'Read own files'),
+  ('files:write', 'Upload files'),
+  ('files:read:any', 'Read files of any user'),
+  ('roles:manage', 'Assign and revoke roles')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role, permission) VALUES
+  ('user', 'files:read'),
+  ('user', 'files:write'),
+  ('admin', 'files:read'),
+  ('admin', 'files:write'),
+  ('admin', 'files:read:any'),
+  ('admin', 'roles:manage')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_roles(user_id, role) SELECT id, 'user' FROM users ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS apps
+(
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(255) NOT NULL UNIQUE,
+    secret          TEXT         NOT NULL DEFAULT '',
+    signing_key_id  VARCHAR(255) NOT NULL DEFAULT '',
+    token_ttl       BIGINT       NOT NULL DEFAULT 0, -- seconds, 0 for default
+    allowed_origins TEXT[]       NOT NULL DEFAULT '{}'
+);
+
+INSERT INTO apps (id, name) VALUES (1, 'web'), (2, 'cli'), (3, 'internal')
+ON CONFLICT DO NOTHING;
+
+SELECT setval('apps_id_seq', (SELECT MAX(id) FROM apps));
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS app_id INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS app_id;
+DROP TABLE IF EXISTS apps;
+-- +goose StatementEnd
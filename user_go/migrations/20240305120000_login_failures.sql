@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS login_failures(
+  key VARCHAR(255) PRIMARY KEY UNIQUE NOT NULL,
+  failures INT NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP NOT NULL,
+  locked_until TIMESTAMP
+);
+
+INSERT INTO permissions(name, description) VALUES
+  ('users:unlock', 'Unlock accounts locked after failed logins')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role, permission) VALUES
+  ('admin', 'users:unlock')
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE name = 'users:unlock';
+DROP TABLE IF EXISTS login_failures;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- emails are stored lowercase since password policy normalizes them.
+-- Users whose emails differ only by case couldn't log in after that, so migration fails
+-- and lists them, such accounts must be merged or renamed before running it again.
+DO $$
+DECLARE
+    collisions TEXT;
+BEGIN
+    SELECT string_agg(email, ', ' ORDER BY email) INTO collisions
+    FROM users
+    WHERE LOWER(email) IN (SELECT LOWER(email) FROM users GROUP BY LOWER(email) HAVING COUNT(*) > 1);
+
+    IF collisions IS NOT NULL THEN
+        RAISE EXCEPTION 'emails differ only by case, merge or rename these users: %', collisions;
+    END IF;
+END $$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE users SET email = LOWER(email) WHERE email <> LOWER(email);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'original case of emails is not restored';
+-- +goose StatementEnd
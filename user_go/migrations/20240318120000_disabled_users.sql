@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS users_created_at ON users(created_at, id);
+
+INSERT INTO permissions(name, description) VALUES
+  ('users:read', 'List and search users'),
+  ('users:manage', 'Disable and enable users')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role, permission) VALUES
+  ('admin', 'users:read'),
+  ('admin', 'users:manage')
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE name IN ('users:read', 'users:manage');
+DROP INDEX IF EXISTS users_created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS audit_events(
+  id BIGSERIAL PRIMARY KEY,
+  type VARCHAR(255) NOT NULL,
+  outcome VARCHAR(255) NOT NULL,
+  actor_id VARCHAR(255) NOT NULL DEFAULT '',
+  user_id VARCHAR(255) NOT NULL DEFAULT '',
+  email VARCHAR(255) NOT NULL DEFAULT '',
+  app_id INTEGER NOT NULL DEFAULT 0,
+  ip VARCHAR(255) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  details TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events(user_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events(actor_id, id);
+
+INSERT INTO permissions(name, description) VALUES
+  ('audit:read', 'Read audit log')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role, permission) VALUES
+  ('admin', 'audit:read')
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE name = 'audit:read';
+DROP TABLE IF EXISTS audit_events;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+// Package migrations embeds migrations of storages into binary,
+// they are applied by migrate subcommand of the service
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Postgres are migrations of postgres storage
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite are migrations of sqlite storage, they are in sqlite directory
+var SQLite, _ = fs.Sub(sqliteFS, "sqlite")
@@ -0,0 +1,265 @@
+-- +goose Up
+-- +goose StatementBegin
+-- schema of sqlite storage is the same as of postgres after migrations up to this version,
+-- new migrations are added to both directories with the same version.
+-- times are stored as text in UTC, arrays as json.
+CREATE TABLE IF NOT EXISTS users(
+  id VARCHAR(255) PRIMARY KEY NOT NULL,
+  email VARCHAR(255) NOT NULL,
+  enc_password BLOB NOT NULL,
+  email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+  display_name VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  disabled_at TIMESTAMP,
+  deleted_at TIMESTAMP
+);
+-- email of deleted user can be used again
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_active ON users(email) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS users_created_at ON users(created_at, id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens(
+  id VARCHAR(255) PRIMARY KEY NOT NULL,
+  family_id VARCHAR(255) NOT NULL,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email VARCHAR(255) NOT NULL DEFAULT '',
+  app_id INTEGER NOT NULL DEFAULT 0,
+  token_hash VARCHAR(255) NOT NULL UNIQUE,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens(
+  jti VARCHAR(255) PRIMARY KEY NOT NULL,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_token_revocations(
+  user_id VARCHAR(255) PRIMARY KEY NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  revoked_before TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS roles(
+  name VARCHAR(255) PRIMARY KEY NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions(
+  name VARCHAR(255) PRIMARY KEY NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions(
+  role VARCHAR(255) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  permission VARCHAR(255) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+  PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles(
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(255) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles(name, description) VALUES
+  ('user', 'Default role of every registered user'),
+  ('admin', 'Can read all files and manage roles')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO permissions(name, description) VALUES
+  ('files:read', 'Read own files'),
+  ('files:write', 'Upload files'),
+  ('files:read:any', 'Read files of any user'),
+  ('roles:manage', 'Assign and revoke roles'),
+  ('users:unlock', 'Unlock accounts locked after failed logins'),
+  ('users:read', 'List and search users'),
+  ('users:manage', 'Disable and enable users'),
+  ('audit:read', 'Read audit log')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role, permission) VALUES
+  ('user', 'files:read'),
+  ('user', 'files:write'),
+  ('admin', 'files:read'),
+  ('admin', 'files:write'),
+  ('admin', 'files:read:any'),
+  ('admin', 'roles:manage'),
+  ('admin', 'users:unlock'),
+  ('admin', 'users:read'),
+  ('admin', 'users:manage'),
+  ('admin', 'audit:read')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS apps(
+  id INTEGER PRIMARY KEY,
+  name VARCHAR(255) NOT NULL UNIQUE,
+  secret TEXT NOT NULL DEFAULT '',
+  signing_key_id VARCHAR(255) NOT NULL DEFAULT '',
+  token_ttl BIGINT NOT NULL DEFAULT 0, -- seconds, 0 for default
+  allowed_origins TEXT NOT NULL DEFAULT '[]',
+  require_verified_email BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO apps (id, name) VALUES (1, 'web'), (2, 'cli'), (3, 'internal')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS email_verifications(
+  token_hash VARCHAR(255) PRIMARY KEY NOT NULL,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email VARCHAR(255) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS email_verifications_user_id_idx ON email_verifications(user_id);
+
+CREATE TABLE IF NOT EXISTS password_resets(
+  token_hash VARCHAR(255) PRIMARY KEY NOT NULL,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON password_resets(user_id);
+
+CREATE TABLE IF NOT EXISTS login_failures(
+  key VARCHAR(255) PRIMARY KEY NOT NULL,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP NOT NULL,
+  locked_until TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_mfa(
+  user_id VARCHAR(255) PRIMARY KEY NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  secret VARCHAR(255) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes(
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash VARCHAR(255) NOT NULL,
+  used_at TIMESTAMP,
+  PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS sessions(
+  id VARCHAR(255) PRIMARY KEY NOT NULL,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  app_id INTEGER NOT NULL DEFAULT 0,
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);
+
+CREATE TABLE IF NOT EXISTS access_tokens(
+  id VARCHAR(255) PRIMARY KEY NOT NULL,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  scopes TEXT NOT NULL DEFAULT '[]',
+  token_hash VARCHAR(255) NOT NULL UNIQUE,
+  hint VARCHAR(255) NOT NULL DEFAULT '',
+  expires_at TIMESTAMP,
+  last_used_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS access_tokens_user_id_idx ON access_tokens(user_id);
+
+CREATE TABLE IF NOT EXISTS oauth_clients(
+  id VARCHAR(255) PRIMARY KEY NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  owner_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  secret_hash VARCHAR(255) NOT NULL DEFAULT '',
+  redirect_uris TEXT NOT NULL DEFAULT '[]',
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS oauth_codes(
+  code_hash VARCHAR(255) PRIMARY KEY NOT NULL,
+  grant_id VARCHAR(255) NOT NULL,
+  client_id VARCHAR(255) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  scopes TEXT NOT NULL DEFAULT '[]',
+  code_challenge VARCHAR(255) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS oauth_tokens(
+  id VARCHAR(255) PRIMARY KEY NOT NULL,
+  grant_id VARCHAR(255) NOT NULL,
+  kind VARCHAR(16) NOT NULL,
+  token_hash VARCHAR(255) NOT NULL UNIQUE,
+  client_id VARCHAR(255) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  scopes TEXT NOT NULL DEFAULT '[]',
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS oauth_tokens_grant_id_idx ON oauth_tokens(grant_id);
+
+CREATE TABLE IF NOT EXISTS identities(
+  id VARCHAR(255) PRIMARY KEY NOT NULL,
+  user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider VARCHAR(255) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  UNIQUE (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS identities_user_id_idx ON identities(user_id);
+
+CREATE TABLE IF NOT EXISTS audit_events(
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  type VARCHAR(255) NOT NULL,
+  outcome VARCHAR(255) NOT NULL,
+  actor_id VARCHAR(255) NOT NULL DEFAULT '',
+  user_id VARCHAR(255) NOT NULL DEFAULT '',
+  email VARCHAR(255) NOT NULL DEFAULT '',
+  app_id INTEGER NOT NULL DEFAULT 0,
+  ip VARCHAR(255) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  details TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events(user_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events(actor_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS identities;
+DROP TABLE IF EXISTS oauth_tokens;
+DROP TABLE IF EXISTS oauth_codes;
+DROP TABLE IF EXISTS oauth_clients;
+DROP TABLE IF EXISTS access_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
+DROP TABLE IF EXISTS login_failures;
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS email_verifications;
+DROP TABLE IF EXISTS apps;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS user_token_revocations;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
+-- +goose StatementEnd